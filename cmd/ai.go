@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+	"github.com/junioroyewunmi/kubegpt/pkg/kubegpt"
+	"github.com/spf13/viper"
+)
+
+// aiConfig is the ai section of the config file:
+//
+//	ai:
+//	  provider: openai
+//	  model: gpt-4o-mini
+//	  baseURL: https://api.openai.com/v1
+//	  apiKey: ${OPENAI_API_KEY}
+//	  concurrency: 4
+//	  requestsPerMinute: 30
+//	  timeout: 2m
+//	  retries: 3
+//	  circuitBreaker:
+//	    failures: 5
+//	    cooldown: 1m
+//
+// provider is amazon-q (the default), openai for any OpenAI-compatible API,
+// or ollama. The HTTP providers need a model, and baseURL defaults to the
+// public OpenAI API or a local Ollama server; apiKey defaults to the
+// OPENAI_API_KEY environment variable for openai.
+//
+// concurrency is the number of objects diagnose analyzes at the same time,
+// and requestsPerMinute limits the calls to the model of every command.
+// timeout bounds each call, and calls that time out or are throttled are
+// retried up to retries times. After circuitBreaker.failures failed calls in
+// a row the model is not called for the cooldown, and analyses come from the
+// built-in rules; failures: 0 disables the breaker.
+type aiConfig struct {
+	Provider          string               `mapstructure:"provider"`
+	Model             string               `mapstructure:"model"`
+	BaseURL           string               `mapstructure:"baseURL"`
+	APIKey            string               `mapstructure:"apiKey"`
+	Concurrency       int                  `mapstructure:"concurrency"`
+	RequestsPerMinute int                  `mapstructure:"requestsPerMinute"`
+	Timeout           string               `mapstructure:"timeout"`
+	Retries           *int                 `mapstructure:"retries"`
+	CircuitBreaker    circuitBreakerConfig `mapstructure:"circuitBreaker"`
+}
+
+// circuitBreakerConfig is the ai.circuitBreaker section of the config file
+type circuitBreakerConfig struct {
+	Failures *int   `mapstructure:"failures"`
+	Cooldown string `mapstructure:"cooldown"`
+}
+
+// analysisConcurrency is set by the --concurrency flag of diagnose
+var analysisConcurrency int
+
+// recordFixtures and replayFixtures are the fixture directories of the
+// --record-fixtures and --replay-fixtures flags
+var (
+	recordFixtures string
+	replayFixtures string
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&recordFixtures, "record-fixtures", "", "store each AI prompt and response as a fixture file in this directory")
+	rootCmd.PersistentFlags().StringVar(&replayFixtures, "replay-fixtures", "", "answer AI prompts from the fixture files in this directory instead of calling the model")
+}
+
+// loadAIConfig reads the ai section of the config file
+func loadAIConfig() (aiConfig, error) {
+	var config aiConfig
+	if err := viper.UnmarshalKey("ai", &config); err != nil {
+		return config, fmt.Errorf("reading ai from config: %w", err)
+	}
+	if config.Concurrency < 0 || config.RequestsPerMinute < 0 {
+		return config, fmt.Errorf("ai.concurrency and ai.requestsPerMinute must not be negative")
+	}
+	return config, nil
+}
+
+// provider returns the AI provider of the config file
+func (c aiConfig) provider() (ai.Provider, error) {
+	switch strings.ToLower(c.Provider) {
+	case "", "amazon-q", "amazonq":
+		return ai.NewAmazonQCLI(), nil
+	case "openai":
+		if c.Model == "" {
+			return nil, fmt.Errorf("ai.model is required for the openai provider")
+		}
+		apiKey := os.ExpandEnv(c.APIKey)
+		if apiKey == "" {
+			apiKey = os.Getenv("OPENAI_API_KEY")
+		}
+		return ai.NewOpenAIProvider(c.BaseURL, apiKey, c.Model), nil
+	case "ollama":
+		if c.Model == "" {
+			return nil, fmt.Errorf("ai.model is required for the ollama provider")
+		}
+		return ai.NewOllamaProvider(c.BaseURL, c.Model), nil
+	}
+	return nil, fmt.Errorf("unknown ai.provider %q (expected amazon-q, openai or ollama)", c.Provider)
+}
+
+// retryPolicy returns the retry policy of the config file
+func (c aiConfig) retryPolicy() (ai.RetryPolicy, error) {
+	policy := ai.DefaultRetryPolicy
+	if c.Timeout != "" {
+		timeout, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return policy, fmt.Errorf("invalid ai.timeout %q: %w", c.Timeout, err)
+		}
+		policy.Timeout = timeout
+	}
+	if c.Retries != nil {
+		if *c.Retries < 0 {
+			return policy, fmt.Errorf("ai.retries must not be negative")
+		}
+		policy.MaxRetries = *c.Retries
+	}
+	return policy, nil
+}
+
+// circuitBreaker returns the circuit breaker of the config file
+func (c aiConfig) circuitBreaker() (*ai.CircuitBreaker, error) {
+	failures, cooldown := ai.DefaultBreakerThreshold, ai.DefaultBreakerCooldown
+	if c.CircuitBreaker.Failures != nil {
+		failures = *c.CircuitBreaker.Failures
+	}
+	if c.CircuitBreaker.Cooldown != "" {
+		var err error
+		cooldown, err = time.ParseDuration(c.CircuitBreaker.Cooldown)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ai.circuitBreaker.cooldown %q: %w", c.CircuitBreaker.Cooldown, err)
+		}
+	}
+	return ai.NewCircuitBreaker(failures, cooldown), nil
+}
+
+// concurrency returns the number of objects to analyze at the same time,
+// from --concurrency, the config file or the default
+func concurrency() int {
+	if analysisConcurrency > 0 {
+		return analysisConcurrency
+	}
+	if config, err := loadAIConfig(); err == nil && config.Concurrency > 0 {
+		return config.Concurrency
+	}
+	return kubegpt.DefaultConcurrency
+}
+
+// newAIClient creates the AI client with the provider, prompts, context
+// budget, response cache, rate limit, retries, circuit breaker and redaction
+// of the config file and the selected profile, recording or replaying
+// fixtures if asked to
+func newAIClient() (*ai.AmazonQClient, error) {
+	config, err := loadAIConfig()
+	if err != nil {
+		return nil, err
+	}
+	prompts, _, err := loadPrompts()
+	if err != nil {
+		return nil, err
+	}
+	cache, err := newCache()
+	if err != nil {
+		return nil, err
+	}
+	provider, err := config.provider()
+	if err != nil {
+		return nil, err
+	}
+	retry, err := config.retryPolicy()
+	if err != nil {
+		return nil, err
+	}
+	breaker, err := config.circuitBreaker()
+	if err != nil {
+		return nil, err
+	}
+	redactor, err := newRedactor()
+	if err != nil {
+		return nil, err
+	}
+	client := ai.NewAmazonQClient()
+	client.SetProvider(provider)
+	client.SetPrompts(prompts)
+	client.SetContextBudget(viper.GetInt("prompts.contextBudget"))
+	client.SetCache(cache)
+	client.SetRateLimit(config.RequestsPerMinute)
+	client.SetRetryPolicy(retry)
+	client.SetCircuitBreaker(breaker)
+	client.SetRedactor(redactor)
+
+	// Recording skips the cache so that every prompt reaches the model, and
+	// replaying skips the cache, rate limit and breaker so that every miss is
+	// reported
+	switch {
+	case recordFixtures != "" && replayFixtures != "":
+		return nil, fmt.Errorf("--record-fixtures and --replay-fixtures cannot be used together")
+	case recordFixtures != "" && ai.MockEnabled():
+		return nil, fmt.Errorf("--record-fixtures records the model's responses and cannot be used with KUBEGPT_MOCK_AI")
+	case recordFixtures != "":
+		client.SetProvider(ai.NewRecordingProvider(provider, recordFixtures))
+		client.SetCache(nil)
+	case replayFixtures != "":
+		client.SetProvider(ai.NewReplayProvider(replayFixtures))
+		client.SetCache(nil)
+		client.SetRateLimit(0)
+		client.SetCircuitBreaker(nil)
+	}
+	if verbose {
+		client.SetVerboseOutput(progress)
+	}
+	return client, nil
+}
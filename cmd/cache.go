@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+	"github.com/spf13/viper"
+)
+
+// cacheConfig is the cache section of the config file:
+//
+//	cache:
+//	  dir: ${HOME}/.cache/kubegpt/responses
+//	  ttl: 24h
+//	  maxSizeMB: 50
+//	  disabled: false
+//
+// AI responses are cached by the fingerprint of their prompt, so running
+// diagnose again on an object that has not changed does not call the model.
+type cacheConfig struct {
+	Dir       string `mapstructure:"dir"`
+	TTL       string `mapstructure:"ttl"`
+	MaxSizeMB int64  `mapstructure:"maxSizeMB"`
+	Disabled  bool   `mapstructure:"disabled"`
+}
+
+var noCache bool
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "always call the AI model instead of reusing cached responses")
+}
+
+// newCache creates the response cache of the config file, or returns nil if
+// it is disabled
+func newCache() (*ai.Cache, error) {
+	var config cacheConfig
+	if err := viper.UnmarshalKey("cache", &config); err != nil {
+		return nil, fmt.Errorf("reading cache from config: %w", err)
+	}
+	if noCache || config.Disabled {
+		return nil, nil
+	}
+
+	var ttl time.Duration
+	if config.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(config.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache.ttl %q: %w", config.TTL, err)
+		}
+	}
+
+	dir := os.ExpandEnv(config.Dir)
+	if dir == "" {
+		var err error
+		dir, err = ai.DefaultCacheDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ai.NewCache(dir, ttl, config.MaxSizeMB<<20), nil
+}
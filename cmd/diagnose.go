@@ -3,13 +3,12 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"github.com/junioroyewunmi/kubegpt/pkg/ai"
-	"github.com/junioroyewunmi/kubegpt/pkg/k8s"
-	"github.com/junioroyewunmi/kubegpt/pkg/output"
+	"github.com/junioroyewunmi/kubegpt/pkg/kubegpt"
 )
 
 var (
@@ -40,183 +39,99 @@ Examples:
 
   # Generate YAML patches to fix issues
   kubegpt diagnose --fix
-`,
-	Run: func(cmd *cobra.Command, args []string) {
-		// Print logo
-		printLogo()
 
-		// Create Kubernetes client
-		client, err := k8s.NewClient(kubeconfig)
-		if err != nil {
-			color.Red("Error creating Kubernetes client: %v", err)
-			return
-		}
-
-		// Set namespace if provided
-		if namespace != "" {
-			client.SetNamespace(namespace)
-		}
+  # Fail a CI job on critical findings and publish JUnit results
+  kubegpt diagnose --fail-on critical --output junit --file kubegpt.xml
 
-		// Get current namespace
-		currentNamespace := client.GetCurrentNamespace()
-		color.New(color.FgCyan).Printf("Diagnosing issues in namespace: %s\n\n", currentNamespace)
+  # Render the results with a custom Go template
+  kubegpt diagnose --template team-report.md.tmpl --file report.md
 
-		// Create context with timeout
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-
-		// Initialize results
-		var results output.DiagnosticResults
-		results.Namespace = currentNamespace
-		results.Timestamp = time.Now()
-
-		// If pods-only flag is set, only check pods
-		if podsOnly {
-			includePods = true
-			includeEvents = false
-			includeDeployments = false
-			includeServices = false
+  # Browse the findings in a full-screen terminal UI
+  kubegpt diagnose --tui
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Validate --fail-on before doing any work
+		if _, err := failOnThreshold(); err != nil {
+			return err
 		}
 
-		// Collect unhealthy pods
-		if includePods {
-			fmt.Println("Checking pods...")
-			pods, err := client.GetUnhealthyPods(ctx)
-			if err != nil {
-				color.Red("Error getting unhealthy pods: %v", err)
-			} else {
-				results.UnhealthyPods = pods
-				color.Yellow("Found %d unhealthy pods", len(pods))
-			}
+		var kinds []string
+		if includePods || podsOnly {
+			kinds = append(kinds, "Pod")
 		}
-
-		// Collect failed events
-		if includeEvents {
-			fmt.Println("Checking events...")
-			events, err := client.GetFailedEvents(ctx)
-			if err != nil {
-				color.Red("Error getting failed events: %v", err)
-			} else {
-				results.FailedEvents = events
-				color.Yellow("Found %d failed events", len(events))
+		// If pods-only flag is set, only check pods
+		if !podsOnly {
+			if includeEvents {
+				kinds = append(kinds, "Event")
 			}
-		}
-
-		// Collect misconfigured deployments
-		if includeDeployments {
-			fmt.Println("Checking deployments...")
-			deployments, err := client.GetMisconfiguredDeployments(ctx)
-			if err != nil {
-				color.Red("Error getting misconfigured deployments: %v", err)
-			} else {
-				results.MisconfiguredDeployments = deployments
-				color.Yellow("Found %d misconfigured deployments", len(deployments))
+			if includeDeployments {
+				kinds = append(kinds, "Deployment")
+			}
+			if includeServices {
+				kinds = append(kinds, "Service")
 			}
 		}
+		if len(kinds) == 0 {
+			return fmt.Errorf("no checks selected")
+		}
 
-		// Collect service issues
-		if includeServices {
-			fmt.Println("Checking services...")
-			services, err := client.GetServiceIssues(ctx)
-			if err != nil {
-				color.Red("Error getting service issues: %v", err)
-			} else {
-				results.ServiceIssues = services
-				color.Yellow("Found %d service issues", len(services))
+		// The terminal UI replaces the whole output
+		if tuiFlag {
+			if outputFormat != "terminal" || templatePath != "" {
+				return fmt.Errorf("--tui cannot be combined with --output %s or --template", outputFormat)
 			}
+			return runTUI(kinds)
 		}
 
-		// If no issues found
-		if len(results.UnhealthyPods) == 0 && 
-		   len(results.FailedEvents) == 0 && 
-		   len(results.MisconfiguredDeployments) == 0 &&
-		   len(results.ServiceIssues) == 0 {
-			color.Green("\n✓ No issues found in namespace %s", currentNamespace)
-			return
+		amazonQ, err := newAIClient()
+		if err != nil {
+			return err
 		}
 
-		// Analyze issues with Amazon Q
-		fmt.Println("\nAnalyzing issues with Amazon Q...")
-		amazonQ := ai.NewAmazonQClient()
+		// Print logo
+		printLogo()
 
-		// Analyze unhealthy pods
-		for i, pod := range results.UnhealthyPods {
-			if i >= maxItems {
-				break
-			}
-			fmt.Printf("Analyzing pod %s...\n", pod.Name)
-			analysis, err := amazonQ.AnalyzePodIssue(pod)
-			if err != nil {
-				color.Red("Error analyzing pod %s: %v", pod.Name, err)
-				continue
-			}
-			results.UnhealthyPods[i].Analysis = analysis
-
-			// Generate fix if requested
-			if fix {
-				fixYAML, err := amazonQ.GeneratePodFix(pod)
-				if err != nil {
-					color.Red("Error generating fix for pod %s: %v", pod.Name, err)
-				} else {
-					results.UnhealthyPods[i].Fix = fixYAML
-				}
-			}
+		results, err := kubegpt.Diagnose(context.Background(), kubegpt.Options{
+			Kubeconfig:     kubeconfig,
+			Namespace:      namespace,
+			Kinds:          kinds,
+			CollectTimeout: 30 * time.Second,
+			Analyze:        true,
+			AI:             amazonQ,
+			Fix:            fix,
+			MaxItems:       maxItems,
+			Concurrency:    concurrency(),
+			Progress:       diagnoseProgress(),
+		})
+		if err != nil {
+			return err
 		}
 
-		// Analyze deployment issues
-		for i, deployment := range results.MisconfiguredDeployments {
-			if i >= maxItems {
-				break
-			}
-			fmt.Printf("Analyzing deployment %s...\n", deployment.Name)
-			analysis, err := amazonQ.AnalyzeDeploymentIssue(deployment)
-			if err != nil {
-				color.Red("Error analyzing deployment %s: %v", deployment.Name, err)
-				continue
-			}
-			results.MisconfiguredDeployments[i].Analysis = analysis
-
-			// Generate fix if requested
-			if fix {
-				fixYAML, err := amazonQ.GenerateDeploymentFix(deployment)
-				if err != nil {
-					color.Red("Error generating fix for deployment %s: %v", deployment.Name, err)
-				} else {
-					results.MisconfiguredDeployments[i].Fix = fixYAML
+		// If no issues found
+		if len(results.Findings()) == 0 {
+			color.Green("\n✓ No issues found in namespace %s", results.Namespace)
+			// Document formats are still produced so consumers always get a document
+			if isDocumentFormat() {
+				if err := writeResults(results.DiagnosticResults, results.CollectErrors); err != nil {
+					return err
 				}
 			}
+		} else if err := writeResults(results.DiagnosticResults, results.Errors()); err != nil {
+			// Output results based on format
+			return err
 		}
 
-		// Output results based on format
-		switch outputFormat {
-		case "terminal":
-			output.PrintTerminalOutput(results)
-		case "markdown":
-			markdownContent := output.GenerateMarkdownReport(results)
-			if reportFile != "" {
-				err := output.WriteToFile(reportFile, markdownContent)
-				if err != nil {
-					color.Red("Error writing to file: %v", err)
-				} else {
-					color.Green("Report written to %s", reportFile)
-				}
-			} else {
-				fmt.Println(markdownContent)
-			}
-		case "slack":
-			if slackWebhook != "" {
-				err := output.SendToSlack(slackWebhook, results)
-				if err != nil {
-					color.Red("Error sending to Slack: %v", err)
-				} else {
-					color.Green("Report sent to Slack")
-				}
-			} else {
-				color.Red("Slack webhook URL not provided")
-			}
-		default:
-			color.Red("Unknown output format: %s", outputFormat)
+		if err := syncResultIncidents(context.Background(), results.DiagnosticResults); err != nil {
+			return err
+		}
+		if err := syncResultTickets(context.Background(), results.DiagnosticResults); err != nil {
+			return err
 		}
+		if err := notifyResults(context.Background(), results.DiagnosticResults); err != nil {
+			return err
+		}
+
+		return gate(results.DiagnosticResults, results.CollectErrors)
 	},
 }
 
@@ -228,4 +143,46 @@ func init() {
 	diagnoseCmd.Flags().BoolVar(&includeServices, "services", true, "include service issues in diagnosis")
 	diagnoseCmd.Flags().BoolVar(&podsOnly, "pods-only", false, "only check pods")
 	diagnoseCmd.Flags().IntVar(&maxItems, "max-items", 5, "maximum number of items to analyze per resource type")
-}
\ No newline at end of file
+	diagnoseCmd.Flags().IntVar(&analysisConcurrency, "concurrency", 0, "number of items to analyze at the same time (default ai.concurrency or 4)")
+	diagnoseCmd.Flags().BoolVar(&notifyFlag, "notify", false, "send the results to the notifiers configured in the config file")
+	diagnoseCmd.Flags().StringVar(&failOn, "fail-on", "", "exit with code 2 if any finding has at least this severity (info, warning, critical)")
+	diagnoseCmd.Flags().BoolVar(&tuiFlag, "tui", false, "browse the findings in a full-screen terminal UI and analyze them on demand")
+	diagnoseCmd.Flags().StringVar(&templatePath, "template", "", "render the results with a Go template file instead of --output (html/template for .html files, text/template otherwise)")
+}
+// collectedNouns name what each collector looks for, as printed by diagnose
+var collectedNouns = map[string]string{
+	"Pod":        "unhealthy pods",
+	"Event":      "failed events",
+	"Deployment": "misconfigured deployments",
+	"Service":    "service issues",
+}
+
+// diagnoseProgress prints the progress of a diagnosis in the diagnose
+// command's terminal style
+func diagnoseProgress() func(kubegpt.Event) {
+	analyzing := false
+	return func(event kubegpt.Event) {
+		switch event.Type {
+		case kubegpt.Started:
+			color.New(color.FgCyan).Printf("Diagnosing issues in namespace: %s\n\n", event.Namespace)
+		case kubegpt.Collecting:
+			fmt.Fprintf(progress, "Checking %ss...\n", strings.ToLower(event.Kind))
+		case kubegpt.Collected:
+			if event.Err != nil {
+				color.Red("Error %v", event.Err)
+			} else {
+				color.Yellow("Found %d %s", event.Count, collectedNouns[event.Kind])
+			}
+		case kubegpt.Analyzing:
+			if !analyzing {
+				analyzing = true
+				fmt.Fprintln(progress, "\nAnalyzing issues with Amazon Q...")
+			}
+			fmt.Fprintf(progress, "Analyzing %s %s...\n", strings.ToLower(event.Kind), event.Name)
+		case kubegpt.Analyzed, kubegpt.Fixed:
+			if event.Err != nil {
+				color.Red("Error %v", event.Err)
+			}
+		}
+	}
+}
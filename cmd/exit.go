@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// Exit codes returned by the kubegpt binary
+const (
+	// ExitOK means the command succeeded and no finding reached --fail-on
+	ExitOK = 0
+	// ExitError means the command could not run or a collector failed
+	ExitError = 1
+	// ExitFindings means at least one finding reached the --fail-on severity
+	ExitFindings = 2
+)
+
+var failOn string
+
+// exitError is an error that carries a specific exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// ExitCode returns the process exit code for an error returned by Execute
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+
+	return ExitError
+}
+
+// failOnThreshold parses the --fail-on flag. An empty flag disables gating.
+func failOnThreshold() (output.Severity, error) {
+	if failOn == "" {
+		return "", nil
+	}
+	return output.ParseSeverity(failOn)
+}
+
+// gate turns the outcome of a diagnostic run into the command's error. Findings
+// at or above --fail-on take precedence over collector errors, since they are
+// a definite answer even when part of the namespace could not be checked.
+func gate(results output.DiagnosticResults, collectErrs []error) error {
+	threshold, err := failOnThreshold()
+	if err != nil {
+		return err
+	}
+
+	if threshold != "" {
+		count := 0
+		for _, finding := range results.Findings() {
+			if finding.Severity.Rank() >= threshold.Rank() {
+				count++
+			}
+		}
+		if count > 0 {
+			return &exitError{
+				code: ExitFindings,
+				err:  fmt.Errorf("%d findings at or above severity %s", count, threshold),
+			}
+		}
+	}
+
+	if len(collectErrs) > 0 {
+		return &exitError{code: ExitError, err: errors.Join(collectErrs...)}
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -8,6 +9,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
 	"github.com/junioroyewunmi/kubegpt/pkg/utils"
 )
 
@@ -33,8 +35,8 @@ Examples:
   kubectl logs my-pod | kubegpt explain
 `,
 	Args: cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		runExplain(args)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExplain(args)
 	},
 }
 
@@ -46,7 +48,12 @@ func init() {
 	explainCmd.Flags().StringVarP(&explainFile, "file", "f", "", "file containing error message or YAML to explain")
 }
 
-func runExplain(args []string) {
+func runExplain(args []string) error {
+	// JUnit, SARIF and HTML describe findings, which an explanation does not have
+	if isDocumentFormat() && !output.IsStructuredFormat(outputFormat) {
+		return fmt.Errorf("output format %s is not supported by explain (use json or yaml)", outputFormat)
+	}
+
 	printLogo()
 
 	// Get the content to explain
@@ -59,41 +66,93 @@ func runExplain(args []string) {
 		// Input is from pipe
 		bytes, err := io.ReadAll(os.Stdin)
 		if err != nil {
-			color.New(color.FgRed).Printf("Error reading from stdin: %s\n", utils.FormatError(err))
-			return
+			return fmt.Errorf("reading from stdin: %s", utils.FormatError(err))
 		}
 		content = string(bytes)
 	} else if explainFile != "" {
 		// Input is from file
 		bytes, err := os.ReadFile(explainFile)
 		if err != nil {
-			color.New(color.FgRed).Printf("Error reading file: %s\n", utils.FormatError(err))
-			return
+			return fmt.Errorf("reading file: %s", utils.FormatError(err))
 		}
 		content = string(bytes)
 	} else if len(args) > 0 {
 		// Input is from command line argument
 		content = args[0]
 	} else {
-		color.New(color.FgRed).Println("Error: No input provided. Please provide an error message, YAML, or use --file flag.")
-		return
+		return fmt.Errorf("no input provided; pass an error message or YAML, pipe it in or use --file")
 	}
 
 	// Create Amazon Q client
-	amazonQClient := ai.NewAmazonQClient()
+	amazonQClient, err := newAIClient()
+	if err != nil {
+		return err
+	}
 
 	// Explain the content
-	color.New(color.FgCyan).Println("Analyzing with Amazon Q Developer...")
-	fmt.Println()
+	color.New(color.FgCyan).Fprintf(progress, "Analyzing with %s...\n", amazonQClient.ProviderName())
+	fmt.Fprintln(progress)
+
+	// The response is JSON, so the terminal shows how much of it arrived
+	// and prints the formatted diagnosis once it is complete
+	ctx := context.Background()
+	var live *liveProgress
+	if outputFormat == "terminal" && isTerminal(os.Stdout) {
+		live = &liveProgress{w: progress}
+		ctx = ai.WithStreamOutput(ctx, live)
+	}
 
-	explanation, err := amazonQClient.ExplainError(content)
+	diagnosis, err := amazonQClient.ExplainError(ctx, content)
+	if live != nil {
+		live.clear()
+	}
 	if err != nil {
-		color.New(color.FgRed).Printf("Error getting explanation: %s\n", utils.FormatError(err))
-		return
+		return fmt.Errorf("getting explanation: %s", utils.FormatError(err))
+	}
+
+	// Machine-readable formats get the explanation document on stdout
+	if output.IsStructuredFormat(outputFormat) {
+		document, err := output.Encode(outputFormat, output.NewExplanation(content, diagnosis))
+		if err != nil {
+			return fmt.Errorf("encoding %s output: %w", outputFormat, err)
+		}
+		return writeDocument(document)
 	}
 
-	// Print the explanation
 	color.New(color.FgGreen, color.Bold).Println("Explanation:")
 	fmt.Println()
-	fmt.Println(explanation)
-}
\ No newline at end of file
+	output.PrintDiagnosis(diagnosis, "")
+	return nil
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// liveProgress shows on a single terminal line how much of a streamed
+// response has arrived
+type liveProgress struct {
+	w        io.Writer
+	writes   int
+	received int
+}
+
+// spinnerFrames animate the progress line, one frame per write
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+func (l *liveProgress) Write(p []byte) (int, error) {
+	frame := spinnerFrames[l.writes%len(spinnerFrames)]
+	l.writes++
+	l.received += len(p)
+	color.New(color.Faint).Fprintf(l.w, "\r%s Receiving response... %d bytes", frame, l.received)
+	return len(p), nil
+}
+
+// clear erases the progress line, if anything was written to it
+func (l *liveProgress) clear() {
+	if l.received > 0 {
+		fmt.Fprint(l.w, "\r\033[K")
+	}
+}
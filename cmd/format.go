@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// progress receives the human-oriented chatter of a command (logo, "Checking
+// pods...", per-item errors). For machine-readable formats it is switched to
+// stderr so that stdout carries nothing but the document.
+var progress io.Writer = os.Stdout
+
+// templatePath is the --template file that replaces the --output rendering
+var templatePath string
+
+// isDocumentFormat reports whether the selected output format renders a single
+// document, which stdout must carry without any progress output mixed in
+func isDocumentFormat() bool {
+	return output.IsMachineReadable(outputFormat) || outputFormat == "html" || templatePath != ""
+}
+
+// setupOutputStreams routes progress output for the selected output format
+func setupOutputStreams() {
+	if isDocumentFormat() {
+		progress = os.Stderr
+		color.Output = os.Stderr
+	}
+}
+
+// writeResults renders diagnostic results with the --template file or in the
+// selected output format and writes them to stdout, the --file path or Slack
+func writeResults(results output.DiagnosticResults, errs []error) error {
+	if templatePath != "" {
+		document, err := output.RenderTemplateFile(templatePath, results, errs)
+		if err != nil {
+			return fmt.Errorf("rendering %s: %w", templatePath, err)
+		}
+		return writeDocument(document)
+	}
+
+	switch outputFormat {
+	case "terminal":
+		output.PrintTerminalOutput(results)
+	case "markdown":
+		document, err := output.GenerateMarkdownReport(results)
+		if err != nil {
+			return err
+		}
+		return writeDocument(document)
+	case "json", "yaml":
+		document, err := output.Encode(outputFormat, output.NewReport(results, errs))
+		if err != nil {
+			return fmt.Errorf("encoding %s output: %w", outputFormat, err)
+		}
+		return writeDocument(document)
+	case "junit":
+		threshold, err := failOnThreshold()
+		if err != nil {
+			return err
+		}
+		document, err := output.GenerateJUnitReport(results, threshold, errs)
+		if err != nil {
+			return fmt.Errorf("encoding JUnit output: %w", err)
+		}
+		return writeDocument(document)
+	case "html":
+		document, err := output.GenerateHTMLReport(results, errs)
+		if err != nil {
+			return fmt.Errorf("rendering HTML output: %w", err)
+		}
+		return writeDocument(document)
+	case "sarif":
+		document, err := output.GenerateSARIFReport(results, Version)
+		if err != nil {
+			return fmt.Errorf("encoding SARIF output: %w", err)
+		}
+		return writeDocument(document)
+	case "slack":
+		slack := newSlackClient()
+		if slack == nil {
+			return fmt.Errorf("slack webhook URL or bot token not provided")
+		}
+		if err := slack.PostReport(context.Background(), results); err != nil {
+			return fmt.Errorf("sending to Slack: %w", err)
+		}
+		color.Green("Report sent to Slack")
+	default:
+		return fmt.Errorf("unknown output format: %s", outputFormat)
+	}
+
+	return nil
+}
+
+// writeDocument writes a rendered document to the --file path if one is set,
+// and to stdout otherwise
+func writeDocument(content string) error {
+	if reportFile == "" {
+		fmt.Fprint(os.Stdout, content)
+		return nil
+	}
+
+	if err := output.WriteToFile(reportFile, content); err != nil {
+		return fmt.Errorf("writing to file: %w", err)
+	}
+	color.Green("Report written to %s", reportFile)
+	return nil
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/junioroyewunmi/kubegpt/pkg/incident"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+	"github.com/spf13/viper"
+)
+
+// incidentConfig is the incidents section of the config file:
+//
+//	incidents:
+//	  minSeverity: critical
+//	  pagerduty:
+//	    routingKey: ${PAGERDUTY_ROUTING_KEY}
+//	  opsgenie:
+//	    apiKey: ${OPSGENIE_API_KEY}
+//	    url: https://api.eu.opsgenie.com
+//
+// Environment variables in keys and URLs are expanded.
+type incidentConfig struct {
+	MinSeverity string `mapstructure:"minSeverity"`
+	StateFile   string `mapstructure:"stateFile"`
+	PagerDuty   *struct {
+		RoutingKey string `mapstructure:"routingKey"`
+		URL        string `mapstructure:"url"`
+	} `mapstructure:"pagerduty"`
+	Opsgenie *struct {
+		APIKey string `mapstructure:"apiKey"`
+		URL    string `mapstructure:"url"`
+	} `mapstructure:"opsgenie"`
+}
+
+// loadIncidentManager returns the incident manager configured in the config
+// file, or nil if no incident provider is configured
+func loadIncidentManager() (*incident.Manager, error) {
+	var config incidentConfig
+	if err := viper.UnmarshalKey("incidents", &config); err != nil {
+		return nil, fmt.Errorf("reading incidents from config: %w", err)
+	}
+
+	var providers []incident.Provider
+	if config.PagerDuty != nil {
+		routingKey := os.ExpandEnv(config.PagerDuty.RoutingKey)
+		if routingKey == "" {
+			return nil, fmt.Errorf("incidents.pagerduty.routingKey is required")
+		}
+		providers = append(providers, &incident.PagerDuty{RoutingKey: routingKey, URL: os.ExpandEnv(config.PagerDuty.URL)})
+	}
+	if config.Opsgenie != nil {
+		apiKey := os.ExpandEnv(config.Opsgenie.APIKey)
+		if apiKey == "" {
+			return nil, fmt.Errorf("incidents.opsgenie.apiKey is required")
+		}
+		providers = append(providers, &incident.Opsgenie{APIKey: apiKey, URL: os.ExpandEnv(config.Opsgenie.URL)})
+	}
+	if len(providers) == 0 {
+		return nil, nil
+	}
+
+	var minSeverity output.Severity
+	if config.MinSeverity != "" {
+		severity, err := output.ParseSeverity(config.MinSeverity)
+		if err != nil {
+			return nil, fmt.Errorf("incidents.minSeverity: %w", err)
+		}
+		minSeverity = severity
+	}
+
+	statePath := os.ExpandEnv(config.StateFile)
+	if statePath == "" {
+		path, err := incident.DefaultStatePath()
+		if err != nil {
+			return nil, fmt.Errorf("locating incident state file: %w", err)
+		}
+		statePath = path
+	}
+
+	return incident.NewManager(providers, minSeverity, statePath), nil
+}
+
+// syncIncidents opens incidents for the findings of a run and resolves the
+// ones that are gone, if incident providers are configured. Provider errors
+// are printed; they are retried on the next run.
+func syncIncidents(ctx context.Context, manager *incident.Manager, findings []output.Finding, scope incident.Scope) {
+	if manager == nil {
+		return
+	}
+
+	result, errs := manager.Sync(ctx, findings, scope)
+	for _, err := range errs {
+		color.Red("Error: %v", err)
+	}
+	for _, object := range result.Triggered {
+		color.Red("Incident opened for %s", object)
+	}
+	for _, object := range result.Resolved {
+		color.Green("Incident resolved for %s", object)
+	}
+}
+
+// syncResultIncidents syncs incidents for a diagnose or report run, scoped to
+// the namespace and the checks that completed
+func syncResultIncidents(ctx context.Context, results output.DiagnosticResults) error {
+	manager, err := loadIncidentManager()
+	if err != nil {
+		return err
+	}
+
+	syncIncidents(ctx, manager, results.Findings(), incident.Scope{
+		Namespace: results.Namespace,
+		Kinds:     results.CheckedKinds(),
+	})
+	return nil
+}
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/junioroyewunmi/kubegpt/pkg/incident"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+	"github.com/junioroyewunmi/kubegpt/pkg/route"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// notifierConfig is one entry of the notifiers list in the config file:
+//
+//	notifiers:
+//	  - type: slack
+//	    webhookURL: https://hooks.slack.com/services/...
+//	  - type: teams
+//	    webhookURL: https://example.webhook.office.com/...
+//	  - type: discord
+//	    webhookURL: https://discord.com/api/webhooks/...
+//	  - type: webhook
+//	    url: https://ops.example.com/hooks/kubegpt
+//	    secret: ${KUBEGPT_WEBHOOK_SECRET}
+//	    template: '{"text": {{json .Title}}}'
+//	  - name: payments-pagerduty
+//	    type: pagerduty
+//	    routingKey: ${PAGERDUTY_ROUTING_KEY}
+//
+// Environment variables in URLs, tokens and secrets are expanded, so secrets
+// can stay out of the file. The name, which defaults to the type, is how the
+// routing tree refers to the notifier.
+type notifierConfig struct {
+	Name         string            `mapstructure:"name"`
+	Type         string            `mapstructure:"type"`
+	WebhookURL   string            `mapstructure:"webhookURL"`
+	Token        string            `mapstructure:"token"`
+	Channel      string            `mapstructure:"channel"`
+	URL          string            `mapstructure:"url"`
+	Template     string            `mapstructure:"template"`
+	TemplateFile string            `mapstructure:"templateFile"`
+	Secret       string            `mapstructure:"secret"`
+	Headers      map[string]string `mapstructure:"headers"`
+	RoutingKey   string            `mapstructure:"routingKey"`
+	APIKey       string            `mapstructure:"apiKey"`
+}
+
+var notifyFlag bool
+
+// notifyResults sends the results of a diagnose or report run to the
+// configured notifiers if --notify is set. The Slack flags are skipped when
+// the report already went to Slack through --output slack.
+func notifyResults(ctx context.Context, results output.DiagnosticResults) error {
+	if !notifyFlag {
+		return nil
+	}
+
+	dispatcher, err := loadDispatcher(outputFormat != "slack")
+	if err != nil {
+		return err
+	}
+	if dispatcher == nil {
+		color.Yellow("Warning: --notify is set but no notifiers are configured")
+		return nil
+	}
+
+	sent, errs := dispatcher.Dispatch(ctx, output.NewReportNotification(results))
+	for _, err := range errs {
+		color.Red("Error: %v", err)
+	}
+	if len(errs) == 0 {
+		if sent == 0 {
+			color.Yellow("No notifications sent: the findings were silenced, routed to no receiver or already sent within the repeat interval")
+		} else {
+			color.Green("Report sent in %d notifications", sent)
+		}
+	}
+	return nil
+}
+
+// loadDispatcher returns the dispatcher for the notifiers configured in the
+// config file, or nil if there are none. With a route section, notifications
+// go through the routing tree; otherwise every notifier receives all of them.
+// If withFlags is set, the Slack notifier of the --slack-webhook and
+// --slack-token flags receives every notification regardless of routes.
+func loadDispatcher(withFlags bool) (output.Dispatcher, error) {
+	var dispatchers output.Dispatchers
+	if withFlags {
+		if slack := newSlackClient(); slack != nil {
+			dispatchers = append(dispatchers, output.Broadcast{slack})
+		}
+	}
+
+	var configs []notifierConfig
+	if err := viper.UnmarshalKey("notifiers", &configs); err != nil {
+		return nil, fmt.Errorf("reading notifiers from config: %w", err)
+	}
+
+	receivers := make(map[string]output.Notifier, len(configs))
+	var notifiers output.Broadcast
+	for i, config := range configs {
+		notifier, err := newNotifier(config)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %d (%s): %w", i+1, config.Type, err)
+		}
+
+		name := config.Name
+		if name == "" {
+			name = strings.ToLower(config.Type)
+		}
+		if _, ok := receivers[name]; ok {
+			return nil, fmt.Errorf("notifier %d: duplicate name %q, set a unique name", i+1, name)
+		}
+		receivers[name] = notifier
+		notifiers = append(notifiers, notifier)
+	}
+
+	router, err := loadRouter(receivers)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case router != nil:
+		dispatchers = append(dispatchers, router)
+	case len(notifiers) > 0:
+		dispatchers = append(dispatchers, notifiers)
+	}
+
+	if len(dispatchers) == 0 {
+		return nil, nil
+	}
+	return dispatchers, nil
+}
+
+// loadRouter returns the router of the route and silences sections of the
+// config file, or nil if there is no route section
+func loadRouter(receivers map[string]output.Notifier) (*route.Router, error) {
+	if !viper.IsSet("route") {
+		return nil, nil
+	}
+
+	var config route.Config
+	if err := viper.UnmarshalKey("route", &config); err != nil {
+		return nil, fmt.Errorf("reading route from config: %w", err)
+	}
+	root, err := route.New(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var silenceConfigs []route.SilenceConfig
+	// YAML timestamps decode as time.Time, quoted ones as strings
+	timeHook := viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeHookFunc(time.RFC3339),
+		mapstructure.StringToSliceHookFunc(","),
+	))
+	if err := viper.UnmarshalKey("silences", &silenceConfigs, timeHook); err != nil {
+		return nil, fmt.Errorf("reading silences from config: %w", err)
+	}
+	var silences []*route.Silence
+	for i, silenceConfig := range silenceConfigs {
+		silence, err := route.NewSilence(silenceConfig)
+		if err != nil {
+			return nil, fmt.Errorf("silence %d: %w", i+1, err)
+		}
+		silences = append(silences, silence)
+	}
+
+	statePath, err := route.DefaultStatePath()
+	if err != nil {
+		return nil, fmt.Errorf("locating notification state file: %w", err)
+	}
+	return route.NewRouter(root, silences, receivers, statePath)
+}
+
+// newNotifier creates a notifier from its configuration
+func newNotifier(config notifierConfig) (output.Notifier, error) {
+	webhookURL := os.ExpandEnv(config.WebhookURL)
+
+	switch strings.ToLower(config.Type) {
+	case "slack":
+		if webhookURL == "" && config.Token == "" {
+			return nil, fmt.Errorf("webhookURL or token is required")
+		}
+		return &output.SlackClient{
+			WebhookURL: webhookURL,
+			Token:      os.ExpandEnv(config.Token),
+			Channel:    config.Channel,
+		}, nil
+	case "teams":
+		if webhookURL == "" {
+			return nil, fmt.Errorf("webhookURL is required")
+		}
+		return &output.TeamsNotifier{WebhookURL: webhookURL}, nil
+	case "discord":
+		if webhookURL == "" {
+			return nil, fmt.Errorf("webhookURL is required")
+		}
+		return &output.DiscordNotifier{WebhookURL: webhookURL}, nil
+	case "webhook":
+		body := config.Template
+		if config.TemplateFile != "" {
+			content, err := os.ReadFile(config.TemplateFile)
+			if err != nil {
+				return nil, fmt.Errorf("reading template file: %w", err)
+			}
+			body = string(content)
+		}
+
+		headers := make(map[string]string, len(config.Headers))
+		for key, value := range config.Headers {
+			headers[key] = os.ExpandEnv(value)
+		}
+
+		return output.NewWebhookNotifier(os.ExpandEnv(config.URL), body, os.ExpandEnv(config.Secret), headers)
+	case "pagerduty":
+		routingKey := os.ExpandEnv(config.RoutingKey)
+		if routingKey == "" {
+			return nil, fmt.Errorf("routingKey is required")
+		}
+		return &incident.Notifier{Provider: &incident.PagerDuty{RoutingKey: routingKey, URL: os.ExpandEnv(config.URL)}}, nil
+	case "opsgenie":
+		apiKey := os.ExpandEnv(config.APIKey)
+		if apiKey == "" {
+			return nil, fmt.Errorf("apiKey is required")
+		}
+		return &incident.Notifier{Provider: &incident.Opsgenie{APIKey: apiKey, URL: os.ExpandEnv(config.URL)}}, nil
+	}
+
+	return nil, fmt.Errorf("unknown notifier type %q (expected slack, teams, discord, webhook, pagerduty or opsgenie)", config.Type)
+}
@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+	"github.com/junioroyewunmi/kubegpt/pkg/kubegpt"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// promptConfig is the prompts section of the config file:
+//
+//	prompts:
+//	  dir: ${HOME}/.kubegpt/prompts
+//	  profile: oncall
+//	  contextBudget: 3000
+//	  templates:
+//	    fix: |
+//	      Our runbooks live at https://runbooks.example.com.
+//	      {{.IssueDescription}}
+//	  profiles:
+//	    terse:
+//	      pod: |
+//	        Pod {{.Name}} is {{.Status}}. {{.ContainerIssues}}
+//	        Answer in three bullet points.
+//
+// Overrides are applied in order: files in dir, inline templates, files in
+// the profile's subdirectory of dir, and the profile's inline templates.
+// Files are named after the prompt they replace, such as pod.tmpl.
+// contextBudget is the number of tokens the events, logs and spec of an
+// object may take in a prompt.
+type promptConfig struct {
+	Dir           string                       `mapstructure:"dir"`
+	Profile       string                       `mapstructure:"profile"`
+	ContextBudget int                          `mapstructure:"contextBudget"`
+	Templates     map[string]string            `mapstructure:"templates"`
+	Profiles      map[string]map[string]string `mapstructure:"profiles"`
+}
+
+// promptFileExt is the extension of prompt override files
+const promptFileExt = ".tmpl"
+
+var (
+	promptProfile string
+	promptFix     bool
+)
+
+// promptsCmd represents the prompts command
+var promptsCmd = &cobra.Command{
+	Use:   "prompts",
+	Short: "List, show and preview the AI prompt templates",
+	Long: `List, show and preview the prompt templates sent to Amazon Q.
+
+Any built-in prompt can be overridden with a file in the prompts directory
+(~/.kubegpt/prompts by default) or inline in the config file, and named
+profiles group overrides that are selected with --prompt-profile.
+
+Examples:
+  # List the prompts and where each one comes from
+  kubegpt prompts list --prompt-profile oncall
+
+  # Print the template of the pod prompt
+  kubegpt prompts show pod
+
+  # Preview the exact prompt that diagnose would send for a pod
+  kubegpt prompts render api-7d9f -n payments
+
+  # Preview the fix prompt of a deployment
+  kubegpt prompts render deployment/api --fix
+`,
+}
+
+var promptsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the prompts and the source of their templates",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prompts, profile, err := loadPrompts()
+		if err != nil {
+			return err
+		}
+
+		if profile != "" {
+			fmt.Printf("Profile: %s\n\n", profile)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROMPT\tSOURCE")
+		for _, name := range ai.PromptNames() {
+			fmt.Fprintf(w, "%s\t%s\n", name, prompts.Source(name))
+		}
+		return w.Flush()
+	},
+}
+
+var promptsShowCmd = &cobra.Command{
+	Use:   "show <prompt>",
+	Short: "Print the template of a prompt",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prompts, _, err := loadPrompts()
+		if err != nil {
+			return err
+		}
+
+		text, ok := prompts.Text(args[0])
+		if !ok {
+			return fmt.Errorf("unknown prompt %q (expected one of %s)", args[0], strings.Join(ai.PromptNames(), ", "))
+		}
+		fmt.Println(strings.TrimSpace(text))
+		return nil
+	},
+}
+
+var promptsRenderCmd = &cobra.Command{
+	Use:   "render <finding>",
+	Short: "Print the prompt that would be sent for a finding",
+	Long: `Print the prompt that would be sent for a finding of the namespace.
+
+The finding is given by the name of its object, by kind/name, or by the
+fingerprint shown in the JSON and YAML output.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPromptsRender(args[0])
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&promptProfile, "prompt-profile", "", "prompt profile to use from the config file or prompts directory (default prompts.profile)")
+	promptsRenderCmd.Flags().BoolVar(&promptFix, "fix", false, "render the fix prompt instead of the analysis prompt")
+
+	promptsCmd.AddCommand(promptsListCmd, promptsShowCmd, promptsRenderCmd)
+	rootCmd.AddCommand(promptsCmd)
+}
+
+// runPromptsRender collects the namespace and prints the prompt of one finding
+func runPromptsRender(ref string) error {
+	amazonQ, err := newAIClient()
+	if err != nil {
+		return err
+	}
+
+	diagnosis, err := kubegpt.Diagnose(context.Background(), kubegpt.Options{
+		Kubeconfig:     kubeconfig,
+		Namespace:      namespace,
+		CollectTimeout: 30 * time.Second,
+	})
+	if err != nil {
+		return err
+	}
+	for _, err := range diagnosis.CollectErrors {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	finding, err := findFinding(diagnosis.Findings(), ref)
+	if err != nil {
+		return err
+	}
+
+	prompt, err := kubegpt.FindingPrompt(amazonQ, diagnosis.DiagnosticResults, finding, promptFix)
+	if err != nil {
+		return err
+	}
+	prompt, redactions := amazonQ.Redact(prompt)
+	if len(redactions) > 0 {
+		fmt.Fprintf(os.Stderr, "Redacted %s\n", ai.FormatRedactions(redactions))
+	}
+	fmt.Println(prompt)
+	return nil
+}
+
+// findFinding resolves a finding from its fingerprint, kind/name or name
+func findFinding(findings []output.Finding, ref string) (output.Finding, error) {
+	matchers := []func(output.Finding) bool{
+		func(f output.Finding) bool { return f.Fingerprint == ref },
+		func(f output.Finding) bool { return strings.EqualFold(f.Kind+"/"+f.Name, ref) },
+		func(f output.Finding) bool { return f.Name == ref },
+	}
+
+	for _, matches := range matchers {
+		var found []output.Finding
+		for _, finding := range findings {
+			if matches(finding) {
+				found = append(found, finding)
+			}
+		}
+
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		default:
+			objects := make([]string, len(found))
+			for i, finding := range found {
+				objects[i] = finding.Object()
+			}
+			return output.Finding{}, fmt.Errorf("%q matches several findings (%s); use kind/name or the fingerprint", ref, strings.Join(objects, ", "))
+		}
+	}
+	return output.Finding{}, fmt.Errorf("no finding matches %q", ref)
+}
+
+// loadPrompts builds the prompt set from the prompts directory and config
+// file, and returns it with the selected profile
+func loadPrompts() (*ai.PromptSet, string, error) {
+	var config promptConfig
+	if err := viper.UnmarshalKey("prompts", &config); err != nil {
+		return nil, "", fmt.Errorf("reading prompts from config: %w", err)
+	}
+
+	dir := os.ExpandEnv(config.Dir)
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, "", fmt.Errorf("locating prompts directory: %w", err)
+		}
+		dir = filepath.Join(home, ".kubegpt", "prompts")
+	}
+
+	// Viper lowercases map keys, so profile names are matched in lowercase
+	profile := strings.ToLower(promptProfile)
+	if profile == "" {
+		profile = strings.ToLower(config.Profile)
+	}
+
+	overrides, err := readPromptDir(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	overrides = append(overrides, inlinePrompts(config.Templates, "config prompts.templates")...)
+
+	if profile != "" {
+		profileOverrides, err := readPromptDir(filepath.Join(dir, profile))
+		if err != nil {
+			return nil, "", err
+		}
+		inline, ok := config.Profiles[profile]
+		if len(profileOverrides) == 0 && !ok {
+			return nil, "", fmt.Errorf("unknown prompt profile %q (not in prompts.profiles or %s)", profile, dir)
+		}
+		overrides = append(overrides, profileOverrides...)
+		overrides = append(overrides, inlinePrompts(inline, "config prompts.profiles."+profile)...)
+	}
+
+	prompts, err := ai.NewPromptSet(overrides...)
+	if err != nil {
+		return nil, "", err
+	}
+	return prompts, profile, nil
+}
+
+// readPromptDir reads the prompt files of a directory, which may not exist
+func readPromptDir(dir string) ([]ai.PromptOverride, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading prompts directory: %w", err)
+	}
+
+	var overrides []ai.PromptOverride
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != promptFileExt {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading prompt file: %w", err)
+		}
+		overrides = append(overrides, ai.PromptOverride{
+			Name:   strings.TrimSuffix(entry.Name(), promptFileExt),
+			Text:   string(text),
+			Source: path,
+		})
+	}
+	return overrides, nil
+}
+
+// inlinePrompts turns the templates of the config file into overrides, in a
+// stable order
+func inlinePrompts(templates map[string]string, source string) []ai.PromptOverride {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	overrides := make([]ai.PromptOverride, 0, len(names))
+	for _, name := range names {
+		overrides = append(overrides, ai.PromptOverride{
+			Name:   name,
+			Text:   templates[name],
+			Source: source + "." + name,
+		})
+	}
+	return overrides
+}
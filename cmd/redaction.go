@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+	"github.com/spf13/viper"
+)
+
+// redactionConfig is the redaction section of the config file:
+//
+//	redaction:
+//	  ips: true
+//	  patterns:
+//	    - name: customer-id
+//	      pattern: 'cust-(\d{6})'
+//
+// Every prompt is redacted before it is cached or sent. The built-in
+// detectors are always on; ips adds IPv4 addresses, and patterns adds
+// regular expressions whose first capture group, or whole match, is
+// replaced.
+type redactionConfig struct {
+	IPs      bool               `mapstructure:"ips"`
+	Patterns []redactionPattern `mapstructure:"patterns"`
+}
+
+// redactionPattern is a user-defined detector of the config file
+type redactionPattern struct {
+	Name    string `mapstructure:"name"`
+	Pattern string `mapstructure:"pattern"`
+}
+
+// newRedactor creates the redactor of the config file
+func newRedactor() (*ai.Redactor, error) {
+	var config redactionConfig
+	if err := viper.UnmarshalKey("redaction", &config); err != nil {
+		return nil, fmt.Errorf("reading redaction from config: %w", err)
+	}
+
+	opts := ai.RedactOptions{IPs: config.IPs}
+	for _, p := range config.Patterns {
+		opts.Patterns = append(opts.Patterns, ai.RedactPattern{Name: p.Name, Pattern: p.Pattern})
+	}
+	return ai.NewRedactor(opts)
+}
@@ -8,7 +8,7 @@ import (
 	"time"
 
 	"github.com/fatih/color"
-	"github.com/junioroyewunmi/kubegpt/pkg/k8s"
+	"github.com/junioroyewunmi/kubegpt/pkg/kubegpt"
 	"github.com/junioroyewunmi/kubegpt/pkg/output"
 
 	"github.com/spf13/cobra"
@@ -40,131 +40,145 @@ Examples:
   # Generate a report in markdown format
   kubegpt report --output markdown --file cluster-health.md
 
+  # Fail a CI job when any deployment or pod is critical
+  kubegpt report --fail-on critical --output sarif --file kubegpt.sarif
+
   # Send the report to Slack
   kubegpt report --output slack --slack-webhook https://hooks.slack.com/services/...
 `,
-	Run: func(cmd *cobra.Command, args []string) {
-		runReport()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runReport()
 	},
 }
 
 func init() {
 	reportCmd.Flags().BoolVar(&allNamespacesFlag, "all-namespaces", false, "report on all namespaces")
+	reportCmd.Flags().BoolVar(&notifyFlag, "notify", false, "send the report to the notifiers configured in the config file")
+	reportCmd.Flags().StringVar(&failOn, "fail-on", "", "exit with code 2 if any finding has at least this severity (info, warning, critical)")
+	reportCmd.Flags().StringVar(&templatePath, "template", "", "render the results with a Go template file instead of --output (html/template for .html files, text/template otherwise)")
 }
 
-func runReport() {
-	printLogo()
-
-	// Create Kubernetes client
-	client, err := k8s.NewClient(kubeconfig)
-	if err != nil {
-		color.Red("Error creating Kubernetes client: %v", err)
-		return
+func runReport() error {
+	// Validate --fail-on before doing any work
+	if _, err := failOnThreshold(); err != nil {
+		return err
 	}
 
-	// Set namespace if provided
-	if namespace != "" {
-		client.SetNamespace(namespace)
-	}
-
-	// Get current namespace
-	currentNamespace := client.GetCurrentNamespace()
-
-	// Initialize diagnostic results
-	results := output.DiagnosticResults{
-		Namespace: currentNamespace,
-		Timestamp: time.Now(),
-	}
+	printLogo()
 
 	// Generate report header
 	color.New(color.FgGreen, color.Bold).Println("Kubernetes Cluster Health Report")
 	color.New(color.FgWhite).Printf("Time: %s\n\n", time.Now().Format(time.RFC1123))
 
-	fmt.Printf("Namespace: %s\n\n", currentNamespace)
-
+	// Collector failures are printed in the section of their kind
 	ctx := context.Background()
-
-	// Get pods
-	fmt.Println("Checking pods...")
-	pods, err := client.GetUnhealthyPods(ctx)
+	failures := make(map[string]error)
+	diagnosis, err := kubegpt.Diagnose(ctx, kubegpt.Options{
+		Kubeconfig: kubeconfig,
+		Namespace:  namespace,
+		Kinds:      []string{"Pod", "Deployment", "Event"},
+		Progress: func(event kubegpt.Event) {
+			switch {
+			case event.Type == kubegpt.Started:
+				fmt.Fprintf(progress, "Namespace: %s\n\n", event.Namespace)
+			case event.Type == kubegpt.Collected && event.Err != nil:
+				failures[event.Kind] = event.Err
+			}
+		},
+	})
 	if err != nil {
-		color.Red("Error checking pods: %v", err)
-	} else {
-		results.UnhealthyPods = pods
-		if len(pods) > 0 {
-			color.Red("Found %d unhealthy pods\n", len(pods))
-			for _, pod := range pods {
-				color.White("- %s: %s\n", pod.Name, pod.Status)
-				if pod.Reason != "" {
-					color.White("  Reason: %s\n", pod.Reason)
-				}
+		return err
+	}
+	results, errs := diagnosis.DiagnosticResults, diagnosis.CollectErrors
+
+	// Pods
+	fmt.Fprintln(progress, "Checking pods...")
+	if err := failures["Pod"]; err != nil {
+		color.Red("Error %v", err)
+	} else if len(results.UnhealthyPods) > 0 {
+		color.Red("Found %d unhealthy pods\n", len(results.UnhealthyPods))
+		for _, pod := range results.UnhealthyPods {
+			color.White("- %s: %s\n", pod.Name, pod.Status)
+			if pod.Reason != "" {
+				color.White("  Reason: %s\n", pod.Reason)
 			}
-		} else {
-			color.Green("All pods are healthy")
 		}
+	} else {
+		color.Green("All pods are healthy")
 	}
-	fmt.Println()
-
-	// Get deployments
-	fmt.Println("Checking deployments...")
-	deployments, err := client.GetMisconfiguredDeployments(ctx)
-	if err != nil {
-		color.Red("Error checking deployments: %v", err)
+	fmt.Fprintln(progress)
+
+	// Deployments
+	fmt.Fprintln(progress, "Checking deployments...")
+	if err := failures["Deployment"]; err != nil {
+		color.Red("Error %v", err)
+	} else if len(results.MisconfiguredDeployments) > 0 {
+		color.Red("Found %d unhealthy deployments\n", len(results.MisconfiguredDeployments))
+		for _, deployment := range results.MisconfiguredDeployments {
+			color.White("- %s: %d/%d replicas ready\n", deployment.Name, deployment.ReadyReplicas, deployment.Replicas)
+			if deployment.Reason != "" {
+				color.White("  Reason: %s\n", deployment.Reason)
+			}
+		}
 	} else {
-		results.MisconfiguredDeployments = deployments
-		if len(deployments) > 0 {
-			color.Red("Found %d unhealthy deployments\n", len(deployments))
-			for _, deployment := range deployments {
-				color.White("- %s: %d/%d replicas ready\n", deployment.Name, deployment.ReadyReplicas, deployment.Replicas)
-				if deployment.Reason != "" {
-					color.White("  Reason: %s\n", deployment.Reason)
+		color.Green("All deployments are healthy")
+	}
+	fmt.Fprintln(progress)
+
+	// Events
+	fmt.Fprintln(progress, "Checking events...")
+	if err := failures["Event"]; err != nil {
+		color.Red("Error %v", err)
+	} else if len(results.FailedEvents) > 0 {
+		color.Red("Found %d failed events\n", len(results.FailedEvents))
+		for _, event := range results.FailedEvents {
+			if eventMap, ok := event.(map[string]interface{}); ok {
+				reason := eventMap["reason"]
+				message := eventMap["message"]
+				involvedObject := eventMap["involvedObject"]
+
+				if involvedObjectMap, ok := involvedObject.(map[string]interface{}); ok {
+					kind := involvedObjectMap["kind"]
+					name := involvedObjectMap["name"]
+
+					color.White("- %s %s: %s - %s\n", kind, name, reason, message)
 				}
 			}
-		} else {
-			color.Green("All deployments are healthy")
 		}
+	} else {
+		color.Green("No failed events found")
 	}
-	fmt.Println()
+	fmt.Fprintln(progress)
 
-	// Get events
-	fmt.Println("Checking events...")
-	events, err := client.GetFailedEvents(ctx)
-	if err != nil {
-		color.Red("Error checking events: %v", err)
-	} else {
-		results.FailedEvents = events
-		if len(events) > 0 {
-			color.Red("Found %d failed events\n", len(events))
-			for _, event := range events {
-				if eventMap, ok := event.(map[string]interface{}); ok {
-					reason := eventMap["reason"]
-					message := eventMap["message"]
-					involvedObject := eventMap["involvedObject"]
-
-					if involvedObjectMap, ok := involvedObject.(map[string]interface{}); ok {
-						kind := involvedObjectMap["kind"]
-						name := involvedObjectMap["name"]
-
-						color.White("- %s %s: %s - %s\n", kind, name, reason, message)
-					}
-				}
-			}
-		} else {
-			color.Green("No failed events found")
+	// Document formats go to stdout or --file
+	if isDocumentFormat() {
+		if err := writeResults(results, errs); err != nil {
+			return err
+		}
+		if err := syncResultIncidents(ctx, results); err != nil {
+			return err
 		}
+		if err := syncResultTickets(ctx, results); err != nil {
+			return err
+		}
+		if err := notifyResults(ctx, results); err != nil {
+			return err
+		}
+		return gate(results, errs)
 	}
-	fmt.Println()
 
 	// Save report to file if requested
 	if reportFile != "" && outputFormat == "markdown" {
 		// Generate the markdown report
-		markdownContent := output.GenerateMarkdownReport(results)
+		markdownContent, err := output.GenerateMarkdownReport(results)
+		if err != nil {
+			return err
+		}
 
 		// Get current working directory
 		cwd, err := os.Getwd()
 		if err != nil {
-			color.Red("Error getting current directory: %v", err)
-			return
+			return fmt.Errorf("getting current directory: %w", err)
 		}
 
 		// Create the full path to save the file
@@ -173,26 +187,34 @@ func runReport() {
 		// Create the directory if it doesn't exist
 		dir := filepath.Dir(fullPath)
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			color.Red("Error creating directory: %v", err)
-			return
+			return fmt.Errorf("creating directory: %w", err)
 		}
 
 		// Open the file with write permissions, create if it doesn't exist
 		file, err := os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 		if err != nil {
-			color.Red("Error opening file for writing: %v", err)
-			return
+			return fmt.Errorf("opening file for writing: %w", err)
 		}
 		defer file.Close()
 
 		// Write the content to the file
 		_, err = file.WriteString(markdownContent)
 		if err != nil {
-			color.Red("Error writing to file: %v", err)
-		} else {
-			color.Green("Report saved to %s", fullPath)
-			fmt.Printf("Report content length: %d bytes\n", len(markdownContent))
+			return fmt.Errorf("writing to file: %w", err)
 		}
+		color.Green("Report saved to %s", fullPath)
+		fmt.Fprintf(progress, "Report content length: %d bytes\n", len(markdownContent))
+	}
+
+	if err := syncResultIncidents(ctx, results); err != nil {
+		return err
+	}
+	if err := syncResultTickets(ctx, results); err != nil {
+		return err
+	}
+	if err := notifyResults(ctx, results); err != nil {
+		return err
 	}
 
+	return gate(results, errs)
 }
@@ -44,6 +44,12 @@ Examples:
   # Generate a report of all issues
   kubegpt report --output markdown --file cluster-health.md
 `,
+	// main prints the error and picks the exit code, so cobra stays quiet
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		setupOutputStreams()
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// If no subcommand is provided, print help
 		cmd.Help()
@@ -62,8 +68,10 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.kubegpt.yaml)")
 	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "path to the kubeconfig file")
 	rootCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "", "kubernetes namespace to use")
-	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "terminal", "output format (terminal, markdown, slack)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "terminal", "output format (terminal, markdown, slack, json, yaml, junit, sarif, html)")
 	rootCmd.PersistentFlags().StringVar(&slackWebhook, "slack-webhook", "", "slack webhook URL for notifications")
+	rootCmd.PersistentFlags().StringVar(&slackToken, "slack-token", "", "slack bot token for posting through the Web API with threaded analyses (default $SLACK_BOT_TOKEN)")
+	rootCmd.PersistentFlags().StringVar(&slackChannel, "slack-channel", "", "slack channel to post to when using --slack-token")
 	rootCmd.PersistentFlags().StringVarP(&reportFile, "file", "f", "", "file to write the report to")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose output")
 	rootCmd.PersistentFlags().BoolVar(&fix, "fix", false, "generate YAML patches to fix issues")
@@ -92,6 +100,8 @@ func init() {
 	rootCmd.AddCommand(explainCmd)
 	rootCmd.AddCommand(reportCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(serveCmd)
 }
 
 // initConfig reads in config file and ENV variables if set
@@ -119,13 +129,18 @@ func initConfig() {
 	// If a config file is found, read it in
 	if err := viper.ReadInConfig(); err == nil {
 		if verbose {
-			fmt.Println("Using config file:", viper.ConfigFileUsed())
+			fmt.Fprintln(progress, "Using config file:", viper.ConfigFileUsed())
 		}
 	}
 }
 
 // printLogo prints the KubeGPT ASCII logo
 func printLogo() {
+	// Keep stderr free of decoration when stdout carries a document
+	if isDocumentFormat() {
+		return
+	}
+
 	logo := `
  _    _    _            _____  _____  _______
 | |  / |  | |          / ____||  __ \|__   __|
@@ -138,5 +153,5 @@ func printLogo() {
 	color.New(color.FgCyan, color.Bold).Println(logo)
 	color.New(color.FgWhite).Println("AI-powered Kubernetes troubleshooting assistant")
 	color.New(color.FgWhite).Println("--------------------------------------------")
-	fmt.Println()
+	fmt.Fprintln(progress)
 }
\ No newline at end of file
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+	"github.com/junioroyewunmi/kubegpt/pkg/kubegpt"
+	"github.com/junioroyewunmi/kubegpt/pkg/metrics"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+	"github.com/junioroyewunmi/kubegpt/pkg/server"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr           string
+	serveToken          string
+	serveRequestTimeout time.Duration
+	serveMaxConcurrent  int
+	serveWebhookURL     string
+	serveWebhookSecret  string
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the KubeGPT JSON HTTP API",
+	Long: `Serve diagnose, explain and report over a JSON HTTP API for internal tools and chatops bots.
+
+Endpoints:
+  POST /v1/diagnose   {"namespace": "...", "kinds": ["Pod"], "names": ["api-0"], "analyze": true, "fix": false}
+  POST /v1/explain    {"content": "CrashLoopBackOff: container exited with code 1"}
+  GET  /v1/report     ?namespace=...
+  POST /v1/alertmanager  Alertmanager webhook payload
+  GET  /healthz       unauthenticated health check
+  GET  /metrics       unauthenticated Prometheus metrics
+
+Alerts posted to /v1/alertmanager are scoped to the objects named by their
+namespace, pod, deployment and service labels. The resulting analysis is posted
+to Slack, the notifiers configured in the config file and --webhook-url, keyed
+by the alert fingerprint.
+
+Requests must carry "Authorization: Bearer <token>" when a token is set with
+--token or the KUBEGPT_API_TOKEN environment variable.
+
+/metrics exports the open findings of the last unfiltered diagnose, report or
+alert of each namespace, and the counts and latencies of AI and kubectl calls.
+
+Examples:
+  # Serve on the default port
+  KUBEGPT_API_TOKEN=secret kubegpt serve
+
+  # Diagnose firing alerts and post the analysis to Slack
+  kubegpt serve --slack-webhook https://hooks.slack.com/services/...
+
+  # Allow two concurrent requests of at most one minute each
+  kubegpt serve --addr :9090 --max-concurrent 2 --request-timeout 1m
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runServe()
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "address to listen on")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "bearer token required by the API (default $KUBEGPT_API_TOKEN)")
+	serveCmd.Flags().DurationVar(&serveRequestTimeout, "request-timeout", 2*time.Minute, "maximum duration of a single request")
+	serveCmd.Flags().IntVar(&serveMaxConcurrent, "max-concurrent", 4, "maximum number of requests processed concurrently")
+	serveCmd.Flags().StringVar(&serveWebhookURL, "webhook-url", "", "generic webhook URL that receives alert diagnoses as JSON")
+	serveCmd.Flags().StringVar(&serveWebhookSecret, "webhook-secret", "", "secret that signs --webhook-url bodies with HMAC-SHA256 (default $KUBEGPT_WEBHOOK_SECRET)")
+}
+
+func runServe() error {
+	printLogo()
+
+	token := serveToken
+	if token == "" {
+		token = os.Getenv("KUBEGPT_API_TOKEN")
+	}
+	if token == "" {
+		color.Yellow("Warning: no API token configured, authentication is disabled")
+	}
+
+	dispatcher, err := loadDispatcher(true)
+	if err != nil {
+		return err
+	}
+
+	var webhook output.Notifier
+	if serveWebhookURL != "" {
+		secret := serveWebhookSecret
+		if secret == "" {
+			secret = os.Getenv("KUBEGPT_WEBHOOK_SECRET")
+		}
+		webhook, err = output.NewWebhookNotifier(serveWebhookURL, "", secret, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	amazonQ, err := newAIClient()
+	if err != nil {
+		return err
+	}
+
+	metrics.Register()
+	api := server.New(server.Config{
+		Token:         token,
+		Timeout:       serveRequestTimeout,
+		MaxConcurrent: serveMaxConcurrent,
+		Metrics:       metrics.Handler(),
+	}, &apiBackend{amazonQ: amazonQ, dispatcher: dispatcher, webhook: webhook})
+
+	httpServer := &http.Server{
+		Addr:              serveAddr,
+		Handler:           api,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Shut down gracefully on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	color.New(color.FgCyan).Printf("Serving KubeGPT API on %s\n", serveAddr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serving API: %w", err)
+	}
+	return nil
+}
+
+// apiBackend implements server.Backend with the same collectors and AI
+// client as the CLI commands
+type apiBackend struct {
+	amazonQ    *ai.AmazonQClient
+	dispatcher output.Dispatcher
+	// webhook receives alert diagnoses from --webhook-url
+	webhook output.Notifier
+}
+
+// diagnose runs a diagnosis scoped to the requested namespace, falling back
+// to the --namespace flag and then the kubeconfig context
+func (b *apiBackend) diagnose(ctx context.Context, opts kubegpt.Options) (output.DiagnosticResults, []error, error) {
+	opts.Kubeconfig = kubeconfig
+	opts.AI = b.amazonQ
+	opts.Concurrency = concurrency()
+	if opts.Namespace == "" {
+		opts.Namespace = namespace
+	}
+
+	results, err := kubegpt.Diagnose(ctx, opts)
+	if results == nil {
+		return output.DiagnosticResults{}, nil, err
+	}
+	recordFindings(results.DiagnosticResults, len(opts.Names) > 0 || len(opts.Objects) > 0)
+	return results.DiagnosticResults, results.Errors(), err
+}
+
+// Diagnose implements server.Backend
+func (b *apiBackend) Diagnose(ctx context.Context, req server.DiagnoseRequest) (output.DiagnosticResults, []error, error) {
+	items := req.MaxItems
+	if items <= 0 {
+		items = maxItems
+	}
+
+	return b.diagnose(ctx, kubegpt.Options{
+		Namespace: req.Namespace,
+		Kinds:     req.Kinds,
+		Names:     req.Names,
+		Analyze:   req.Analyze,
+		Fix:       req.Fix,
+		MaxItems:  items,
+	})
+}
+
+// Explain implements server.Backend
+func (b *apiBackend) Explain(ctx context.Context, req server.ExplainRequest) (*ai.Diagnosis, error) {
+	return b.amazonQ.ExplainError(ctx, req.Content)
+}
+
+// Report implements server.Backend
+func (b *apiBackend) Report(ctx context.Context, req server.ReportRequest) (output.DiagnosticResults, []error, error) {
+	return b.diagnose(ctx, kubegpt.Options{
+		Namespace: req.Namespace,
+		Kinds:     []string{"Pod", "Event", "Deployment"},
+	})
+}
+
+// Alert implements server.Backend
+func (b *apiBackend) Alert(ctx context.Context, req server.AlertRequest) (output.DiagnosticResults, []error, error) {
+	var results output.DiagnosticResults
+	var errs []error
+
+	if req.Status == "firing" {
+		var err error
+		results, errs, err = b.diagnose(ctx, kubegpt.Options{
+			Namespace: req.Namespace,
+			Kinds:     req.Kinds,
+			Objects:   req.Names,
+			Analyze:   true,
+			MaxItems:  maxItems,
+		})
+		if err != nil {
+			return results, errs, err
+		}
+	}
+
+	notification := alertNotification(req, results)
+	if b.dispatcher != nil {
+		_, dispatchErrs := b.dispatcher.Dispatch(ctx, notification)
+		errs = append(errs, dispatchErrs...)
+	}
+
+	if b.webhook != nil {
+		if err := b.webhook.Notify(ctx, notification); err != nil {
+			errs = append(errs, fmt.Errorf("sending to %s: %w", b.webhook.Name(), err))
+		}
+	}
+
+	return results, errs, nil
+}
+
+// recordFindings updates the open findings metric of the namespace. Results
+// filtered by name only cover some objects and leave the metric as it is.
+func recordFindings(results output.DiagnosticResults, filtered bool) {
+	if !filtered {
+		metrics.SetFindings(results.Namespace, results.CheckedKinds(), results.Findings(), results.Timestamp)
+	}
+}
+
+// alertNotification builds the notification for the diagnosis of an alert.
+// The alert fingerprint is the thread key, so threaded notifiers post
+// repeats and the resolution of an alert as replies to its first diagnosis.
+func alertNotification(req server.AlertRequest, results output.DiagnosticResults) output.Notification {
+	notification := output.Notification{
+		Type:      output.NotificationAlert,
+		Title:     fmt.Sprintf("[%s] %s", strings.ToUpper(req.Status), req.AlertName),
+		Namespace: req.Namespace,
+		Timestamp: time.Now(),
+		Findings:  []output.Finding{},
+		ThreadKey: req.Fingerprint,
+	}
+	if req.Status == "resolved" {
+		notification.Type = output.NotificationResolved
+	}
+
+	lines := []string{fmt.Sprintf("Alert fingerprint: %s", req.Fingerprint)}
+	if req.Summary != "" {
+		lines = append(lines, req.Summary)
+	}
+
+	if req.Status == "firing" {
+		notification.Findings = results.Findings()
+		if len(notification.Findings) == 0 {
+			lines = append(lines, "No unhealthy objects found for this alert.")
+		}
+	}
+
+	notification.Text = strings.Join(lines, "\n")
+	return notification
+}
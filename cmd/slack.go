@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+var (
+	slackToken   string
+	slackChannel string
+)
+
+// newSlackClient returns a Slack client for the configured webhook or bot
+// token, or nil if Slack is not configured. A bot token takes precedence
+// since it allows analyses to be threaded under their report.
+func newSlackClient() *output.SlackClient {
+	token := slackToken
+	if token == "" {
+		token = os.Getenv("SLACK_BOT_TOKEN")
+	}
+
+	if token == "" && slackWebhook == "" {
+		return nil
+	}
+
+	return &output.SlackClient{
+		WebhookURL: slackWebhook,
+		Token:      token,
+		Channel:    slackChannel,
+		APIURL:     os.Getenv("SLACK_API_URL"),
+	}
+}
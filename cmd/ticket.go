@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+	"github.com/junioroyewunmi/kubegpt/pkg/ticket"
+	"github.com/spf13/viper"
+)
+
+// ticketConfig is the tickets section of the config file:
+//
+//	tickets:
+//	  minSeverity: warning
+//	  minAge: 24h
+//	  github:
+//	    repo: example/platform
+//	    token: ${GITHUB_TOKEN}
+//	    labels: [kubernetes]
+//	  jira:
+//	    url: https://example.atlassian.net
+//	    email: sre@example.com
+//	    token: ${JIRA_API_TOKEN}
+//	    project: OPS
+//	    issueType: Bug
+//
+// Environment variables in tokens and URLs are expanded.
+type ticketConfig struct {
+	MinSeverity string `mapstructure:"minSeverity"`
+	MinAge      string `mapstructure:"minAge"`
+	StateFile   string `mapstructure:"stateFile"`
+	GitHub      *struct {
+		Repo   string   `mapstructure:"repo"`
+		Token  string   `mapstructure:"token"`
+		Labels []string `mapstructure:"labels"`
+		URL    string   `mapstructure:"url"`
+	} `mapstructure:"github"`
+	Jira *struct {
+		URL             string   `mapstructure:"url"`
+		Email           string   `mapstructure:"email"`
+		Token           string   `mapstructure:"token"`
+		Project         string   `mapstructure:"project"`
+		IssueType       string   `mapstructure:"issueType"`
+		Labels          []string `mapstructure:"labels"`
+		CloseTransition string   `mapstructure:"closeTransition"`
+	} `mapstructure:"jira"`
+}
+
+// loadTicketManager returns the ticket manager configured in the config file,
+// or nil if no issue tracker is configured
+func loadTicketManager() (*ticket.Manager, error) {
+	var config ticketConfig
+	if err := viper.UnmarshalKey("tickets", &config); err != nil {
+		return nil, fmt.Errorf("reading tickets from config: %w", err)
+	}
+
+	var providers []ticket.Provider
+	if config.GitHub != nil {
+		token := os.ExpandEnv(config.GitHub.Token)
+		if config.GitHub.Repo == "" || token == "" {
+			return nil, fmt.Errorf("tickets.github.repo and tickets.github.token are required")
+		}
+		providers = append(providers, &ticket.GitHub{
+			Repo:   config.GitHub.Repo,
+			Token:  token,
+			Labels: config.GitHub.Labels,
+			URL:    os.ExpandEnv(config.GitHub.URL),
+		})
+	}
+	if config.Jira != nil {
+		jiraURL := os.ExpandEnv(config.Jira.URL)
+		token := os.ExpandEnv(config.Jira.Token)
+		if jiraURL == "" || token == "" || config.Jira.Project == "" {
+			return nil, fmt.Errorf("tickets.jira.url, tickets.jira.token and tickets.jira.project are required")
+		}
+		providers = append(providers, &ticket.Jira{
+			URL:             jiraURL,
+			Email:           os.ExpandEnv(config.Jira.Email),
+			Token:           token,
+			Project:         config.Jira.Project,
+			IssueType:       config.Jira.IssueType,
+			Labels:          config.Jira.Labels,
+			CloseTransition: config.Jira.CloseTransition,
+		})
+	}
+	if len(providers) == 0 {
+		return nil, nil
+	}
+
+	var options ticket.Options
+	if config.MinSeverity != "" {
+		severity, err := output.ParseSeverity(config.MinSeverity)
+		if err != nil {
+			return nil, fmt.Errorf("tickets.minSeverity: %w", err)
+		}
+		options.MinSeverity = severity
+	}
+	if config.MinAge != "" {
+		minAge, err := time.ParseDuration(config.MinAge)
+		if err != nil {
+			return nil, fmt.Errorf("tickets.minAge: %w", err)
+		}
+		options.MinAge = minAge
+	}
+
+	options.StatePath = os.ExpandEnv(config.StateFile)
+	if options.StatePath == "" {
+		path, err := ticket.DefaultStatePath()
+		if err != nil {
+			return nil, fmt.Errorf("locating ticket state file: %w", err)
+		}
+		options.StatePath = path
+	}
+
+	return ticket.NewManager(providers, options), nil
+}
+
+// syncTickets files, updates and closes tickets for the findings of a run, if
+// an issue tracker is configured. Provider errors are printed; they are
+// retried on the next run.
+func syncTickets(ctx context.Context, manager *ticket.Manager, findings []output.Finding, scope ticket.Scope) {
+	if manager == nil {
+		return
+	}
+
+	result, errs := manager.Sync(ctx, findings, scope)
+	for _, err := range errs {
+		color.Red("Error: %v", err)
+	}
+	for _, object := range result.Created {
+		color.Yellow("Ticket created for %s", object)
+	}
+	for _, object := range result.Updated {
+		color.Yellow("Ticket updated for %s", object)
+	}
+	for _, object := range result.Closed {
+		color.Green("Ticket closed for %s", object)
+	}
+}
+
+// syncResultTickets syncs tickets for a diagnose or report run, scoped to the
+// namespace and the checks that completed
+func syncResultTickets(ctx context.Context, results output.DiagnosticResults) error {
+	manager, err := loadTicketManager()
+	if err != nil {
+		return err
+	}
+
+	syncTickets(ctx, manager, results.Findings(), ticket.Scope{
+		Namespace: results.Namespace,
+		Kinds:     results.CheckedKinds(),
+	})
+	return nil
+}
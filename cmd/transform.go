@@ -1,13 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"github.com/junioroyewunmi/kubegpt/pkg/ai"
 	"github.com/junioroyewunmi/kubegpt/pkg/utils"
 )
 
@@ -127,13 +127,17 @@ func transformToIaC(content, targetLang string) string {
 	fmt.Println()
 
 	// Create Amazon Q client
-	client := ai.NewAmazonQClient()
+	client, err := newAIClient()
+	if err != nil {
+		color.New(color.FgRed).Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Build prompt for transformation
 	prompt := buildTransformationPrompt(content, targetLang)
 
 	// Call Amazon Q
-	result, err := client.CallAmazonQ(prompt)
+	result, err := client.CallAmazonQ(context.Background(), prompt)
 	if err != nil {
 		color.New(color.FgRed).Printf("Error calling Amazon Q: %v\n", err)
 		os.Exit(1)
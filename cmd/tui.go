@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+	"github.com/junioroyewunmi/kubegpt/pkg/k8s"
+	"github.com/junioroyewunmi/kubegpt/pkg/kubegpt"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+	"github.com/junioroyewunmi/kubegpt/pkg/tui"
+)
+
+// tuiFlag is the --tui flag of diagnose
+var tuiFlag bool
+
+// tuiLogLines is the number of log lines shown per container
+const tuiLogLines = 30
+
+// tuiBackend serves the terminal UI with the same diagnosis and AI client as
+// the other commands
+type tuiBackend struct {
+	amazonQ *ai.AmazonQClient
+	kinds   []string
+}
+
+// runTUI runs diagnose --tui on the given kinds until the user quits
+func runTUI(kinds []string) error {
+	amazonQ, err := newAIClient()
+	if err != nil {
+		return err
+	}
+	// Verbose messages would be drawn over the full-screen UI
+	amazonQ.SetVerboseOutput(nil)
+
+	backend := &tuiBackend{amazonQ: amazonQ, kinds: kinds}
+	if err := tui.Run(backend, namespace); err != nil {
+		return fmt.Errorf("running terminal UI: %w", err)
+	}
+	return nil
+}
+
+// Collect runs the selected collectors against a namespace
+func (b *tuiBackend) Collect(ctx context.Context, namespace string) (output.DiagnosticResults, []error) {
+	results, err := kubegpt.Diagnose(ctx, kubegpt.Options{
+		Kubeconfig: kubeconfig,
+		Namespace:  namespace,
+		Kinds:      b.kinds,
+	})
+	if results == nil {
+		return output.DiagnosticResults{Namespace: namespace}, []error{err}
+	}
+	if err != nil {
+		return results.DiagnosticResults, append(results.CollectErrors, err)
+	}
+	return results.DiagnosticResults, results.CollectErrors
+}
+
+// Details fetches the events of a finding's object and, for pods, the recent
+// logs of each container
+func (b *tuiBackend) Details(ctx context.Context, finding output.Finding) (tui.Details, error) {
+	client, err := k8s.NewClient(kubeconfig)
+	if err != nil {
+		return tui.Details{}, fmt.Errorf("creating Kubernetes client: %w", err)
+	}
+
+	// Event findings are named after their object, in kind/name form
+	name := finding.Name
+	if finding.Kind == "Event" {
+		name = name[strings.LastIndex(name, "/")+1:]
+	}
+
+	events, err := client.GetObjectEvents(ctx, finding.Namespace, name)
+	if err != nil {
+		return tui.Details{}, fmt.Errorf("getting events: %w", err)
+	}
+
+	var details tui.Details
+	for _, event := range events {
+		details.Events = append(details.Events, k8s.FormatEvent(event))
+	}
+
+	if finding.Kind == "Pod" {
+		details.Logs = make(map[string]string)
+		for _, container := range finding.Containers {
+			logs, err := client.GetContainerLogs(ctx, finding.Namespace, finding.Name, container.Name, tuiLogLines)
+			if err != nil {
+				logs = fmt.Sprintf("Error getting logs: %v", err)
+			}
+			details.Logs[container.Name] = logs
+		}
+	}
+
+	return details, nil
+}
+
+// Analyze asks Amazon Q about a finding
+func (b *tuiBackend) Analyze(ctx context.Context, results output.DiagnosticResults, finding output.Finding) (string, error) {
+	diagnosis, err := kubegpt.AnalyzeFinding(ctx, b.amazonQ, results, finding)
+	if err != nil {
+		return "", err
+	}
+	return diagnosis.Markdown(), nil
+}
+
+// Fix asks Amazon Q for a fix of a pod or deployment finding
+func (b *tuiBackend) Fix(ctx context.Context, results output.DiagnosticResults, finding output.Finding) (string, error) {
+	diagnosis, err := kubegpt.FixFinding(ctx, b.amazonQ, results, finding)
+	if err != nil {
+		return "", err
+	}
+	return diagnosis.FixText(), nil
+}
@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+	"github.com/junioroyewunmi/kubegpt/pkg/incident"
+	"github.com/junioroyewunmi/kubegpt/pkg/k8s"
+	"github.com/junioroyewunmi/kubegpt/pkg/kubegpt"
+	"github.com/junioroyewunmi/kubegpt/pkg/metrics"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+	"github.com/junioroyewunmi/kubegpt/pkg/ticket"
+	"github.com/junioroyewunmi/kubegpt/pkg/watch"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchInterval   time.Duration
+	watchDebounce   time.Duration
+	watchMaxAICalls int
+	watchAnalyze    bool
+	watchMetrics    string
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Continuously monitor your Kubernetes cluster for issues",
+	Long: `Continuously monitor a namespace and report issues as they open, change and resolve.
+
+Pods, events, deployments and services are polled on an interval. Only state
+transitions are printed, so a steady cluster produces no output. Newly opened
+issues are analyzed with Amazon Q once; the number of AI calls per interval is
+capped so that a flapping resource cannot flood Amazon Q or Slack.
+Transitions and analyses are sent to Slack and to the notifiers configured in
+the config file, through the routing tree of the route section if there is one.
+
+Examples:
+  # Watch the current namespace
+  kubegpt watch
+
+  # Poll every minute and require issues to persist for two minutes
+  kubegpt watch --interval 1m --debounce 2m
+
+  # Post transitions to Slack
+  kubegpt watch --namespace payments --slack-webhook https://hooks.slack.com/services/...
+
+  # Export open issues and AI call metrics for Prometheus on :9090/metrics
+  kubegpt watch --metrics-addr :9090
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runWatch()
+	},
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 30*time.Second, "how often to poll the cluster")
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", time.Minute, "how long an issue must persist before it is opened or resolved")
+	watchCmd.Flags().IntVar(&watchMaxAICalls, "max-ai-calls", 3, "maximum number of AI analyses per interval")
+	watchCmd.Flags().BoolVar(&watchAnalyze, "analyze", true, "analyze newly opened issues with Amazon Q")
+	watchCmd.Flags().StringVar(&watchMetrics, "metrics-addr", "", "address to serve Prometheus metrics on, such as :9090 (disabled if empty)")
+}
+
+func runWatch() error {
+	printLogo()
+
+	dispatcher, err := loadDispatcher(true)
+	if err != nil {
+		return err
+	}
+
+	incidents, err := loadIncidentManager()
+	if err != nil {
+		return err
+	}
+
+	tickets, err := loadTicketManager()
+	if err != nil {
+		return err
+	}
+
+	amazonQ, err := newAIClient()
+	if err != nil {
+		return err
+	}
+
+	// Create Kubernetes client
+	client, err := k8s.NewClient(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("creating Kubernetes client: %w", err)
+	}
+
+	// Set namespace if provided
+	if namespace != "" {
+		client.SetNamespace(namespace)
+	}
+
+	currentNamespace := client.GetCurrentNamespace()
+	color.New(color.FgCyan).Printf("Watching namespace %s every %s (Ctrl+C to stop)\n\n", currentNamespace, watchInterval)
+
+	// Stop cleanly on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if watchMetrics != "" {
+		metrics.Register()
+		go serveMetrics(ctx, watchMetrics)
+	}
+
+	tracker := watch.NewTracker(watch.Options{Debounce: watchDebounce})
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		diagnosis, err := kubegpt.Diagnose(ctx, kubegpt.Options{
+			Kubeconfig:     kubeconfig,
+			Namespace:      currentNamespace,
+			CollectTimeout: 30 * time.Second,
+		})
+		if diagnosis == nil {
+			return err
+		}
+		results := diagnosis.DiagnosticResults
+
+		for _, err := range diagnosis.CollectErrors {
+			color.Red("Error: %v", err)
+		}
+
+		// Skip the update entirely if every collector failed or the poll was
+		// interrupted. The tracker keeps the findings of kinds whose collector
+		// failed, so they are not reported as resolved.
+		if err == nil && len(diagnosis.CollectErrors) < len(kubegpt.Kinds) {
+			for _, transition := range tracker.Update(results.Findings(), results.CheckedKinds(), time.Now()) {
+				printTransition(transition)
+				notifyTransition(ctx, dispatcher, transition)
+			}
+			if watchMetrics != "" {
+				metrics.SetFindings(currentNamespace, results.CheckedKinds(), tracker.Open(), results.Timestamp)
+			}
+
+			// The tracker has already debounced the findings, so incidents
+			// follow its open set rather than the raw snapshot. Kinds whose
+			// collector failed are out of scope, so their incidents stay open.
+			syncIncidents(ctx, incidents, tracker.Open(), incident.Scope{
+				Namespace: currentNamespace,
+				Kinds:     results.CheckedKinds(),
+			})
+
+			if watchAnalyze {
+				analyzePending(ctx, tracker, amazonQ, results, dispatcher)
+			}
+
+			// Tickets come last so that new ones carry the analysis
+			syncTickets(ctx, tickets, tracker.Open(), ticket.Scope{
+				Namespace: currentNamespace,
+				Kinds:     results.CheckedKinds(),
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			color.Cyan("Stopped watching namespace %s (%d open issues)", currentNamespace, tracker.OpenCount())
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// serveMetrics serves /metrics on addr until ctx is done
+func serveMetrics(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	color.New(color.FgCyan).Printf("Serving metrics on %s/metrics\n", addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		color.Red("Error serving metrics: %v", err)
+	}
+}
+
+// analyzePending analyzes open issues that have not been analyzed yet, up to
+// the per-interval AI call budget. Anything left over is picked up on the
+// next poll.
+func analyzePending(ctx context.Context, tracker *watch.Tracker, amazonQ *ai.AmazonQClient, results output.DiagnosticResults, dispatcher output.Dispatcher) {
+	for i, finding := range tracker.PendingAnalysis() {
+		if i >= watchMaxAICalls {
+			break
+		}
+
+		diagnosis, err := kubegpt.AnalyzeFinding(ctx, amazonQ, results, finding)
+		// Mark the finding even on failure so a broken provider is not
+		// retried every interval for the same incident
+		tracker.MarkAnalyzed(finding)
+		if err != nil {
+			color.Red("Error analyzing %s: %v", finding.Object(), err)
+			continue
+		}
+
+		color.New(color.FgWhite, color.Bold).Printf("Analysis for %s:\n", finding.Object())
+		output.PrintDiagnosis(diagnosis, "    ")
+		fmt.Println()
+
+		finding.Analysis = diagnosis.Markdown()
+		finding.Diagnosis = diagnosis
+		tracker.SetAnalysis(finding, finding.Analysis)
+		notify(ctx, dispatcher, output.Notification{
+			Type:      output.NotificationAnalysis,
+			Title:     fmt.Sprintf("Analysis for %s", finding.Object()),
+			Namespace: finding.Namespace,
+			Timestamp: time.Now(),
+			Findings:  []output.Finding{finding},
+		})
+	}
+}
+
+// printTransition prints a single transition to the terminal
+func printTransition(transition watch.Transition) {
+	timestamp := transition.At.Format("15:04:05")
+	finding := transition.Finding
+
+	switch transition.Type {
+	case watch.Opened:
+		color.New(color.FgRed, color.Bold).Printf("[%s] OPENED   ", timestamp)
+	case watch.Changed:
+		color.New(color.FgYellow, color.Bold).Printf("[%s] CHANGED  ", timestamp)
+	case watch.Resolved:
+		color.New(color.FgGreen, color.Bold).Printf("[%s] RESOLVED ", timestamp)
+	}
+
+	fmt.Printf("%s: %s", finding.Object(), finding.Reason)
+	if transition.Type == watch.Changed {
+		if transition.Previous.Reason != finding.Reason {
+			fmt.Printf(" (was %s)", transition.Previous.Reason)
+		} else {
+			fmt.Printf(" (%s, was %s)", finding.Severity, transition.Previous.Severity)
+		}
+	}
+	fmt.Println()
+
+	if finding.Message != "" && transition.Type != watch.Resolved {
+		fmt.Printf("    %s\n", finding.Message)
+	}
+}
+
+// notifyTransition sends a transition to the configured notifiers
+func notifyTransition(ctx context.Context, dispatcher output.Dispatcher, transition watch.Transition) {
+	finding := transition.Finding
+	notification := output.Notification{
+		Type:      output.NotificationType(transition.Type),
+		Title:     fmt.Sprintf("%s %s: %s", strings.ToUpper(string(transition.Type)), finding.Object(), finding.Reason),
+		Namespace: finding.Namespace,
+		Timestamp: transition.At,
+		Findings:  []output.Finding{finding},
+	}
+	if finding.Message != "" && transition.Type != watch.Resolved {
+		notification.Text = finding.Message
+	}
+
+	notify(ctx, dispatcher, notification)
+}
+
+// notify dispatches a notification, if any notifiers are configured, and
+// prints the failures
+func notify(ctx context.Context, dispatcher output.Dispatcher, notification output.Notification) {
+	if dispatcher == nil {
+		return
+	}
+	_, errs := dispatcher.Dispatch(ctx, notification)
+	for _, err := range errs {
+		color.Red("Error: %v", err)
+	}
+}
@@ -2,169 +2,523 @@ package ai
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/junioroyewunmi/kubegpt/pkg/k8s"
 )
 
-// AmazonQClient is a client for interacting with Amazon Q Developer
+// Operations label AI calls by what they were made for
+const (
+	OperationAnalyzePod        = "analyze_pod"
+	OperationAnalyzeDeployment = "analyze_deployment"
+	OperationAnalyzeService    = "analyze_service"
+	OperationClusterReport     = "cluster_report"
+	OperationExplain           = "explain"
+	OperationFixPod            = "fix_pod"
+	OperationFixDeployment     = "fix_deployment"
+	// OperationPrompt is a free-form prompt, such as a transform
+	OperationPrompt = "prompt"
+)
+
+// CallObserver receives the operation, duration and outcome of an AI call,
+// for example to export call metrics
+type CallObserver func(operation string, duration time.Duration, err error)
+
+// callObserver is the installed observer, if any
+var callObserver CallObserver
+
+// SetCallObserver installs an observer of the AI calls made by all clients.
+// It must be called before any call is made.
+func SetCallObserver(observer CallObserver) {
+	callObserver = observer
+}
+
+// AmazonQClient is a client for interacting with Amazon Q Developer, or with
+// another model through SetProvider
 type AmazonQClient struct {
-	cliPath string
+	provider Provider
+	prompts  *PromptSet
+	budget   int
+	cache    *Cache
+	limiter  *RateLimiter
+	retry    RetryPolicy
+	breaker  *CircuitBreaker
+	redactor *Redactor
+	verbose  io.Writer
 }
 
 // NewAmazonQClient creates a new Amazon Q client
 func NewAmazonQClient() *AmazonQClient {
-	// Try to find the Amazon Q CLI
-	cliPath, err := exec.LookPath("amazon-q")
-	if err != nil {
-		// If not found, use the default path
-		cliPath = "amazon-q"
+	return &AmazonQClient{
+		provider: NewAmazonQCLI(),
+		retry:    DefaultRetryPolicy,
+		breaker:  NewCircuitBreaker(DefaultBreakerThreshold, DefaultBreakerCooldown),
+		redactor: DefaultRedactor(),
 	}
+}
 
-	return &AmazonQClient{
-		cliPath: cliPath,
+// SetProvider replaces the model the client calls, which defaults to the
+// Amazon Q CLI
+func (c *AmazonQClient) SetProvider(provider Provider) {
+	c.provider = provider
+}
+
+// ProviderName names the model the client calls, such as "amazon-q" or
+// "openai/gpt-4o-mini", or "mock" in mock mode
+func (c *AmazonQClient) ProviderName() string {
+	if c.mock() {
+		return "mock"
+	}
+	return c.provider.Name()
+}
+
+// SetPrompts replaces the prompt templates of the client, which default to
+// the built-in ones
+func (c *AmazonQClient) SetPrompts(prompts *PromptSet) {
+	c.prompts = prompts
+}
+
+// Prompts returns the prompt templates of the client
+func (c *AmazonQClient) Prompts() *PromptSet {
+	if c.prompts == nil {
+		return defaultPrompts
 	}
+	return c.prompts
 }
 
-// AnalyzePodIssue analyzes a pod issue using Amazon Q
-func (c *AmazonQClient) AnalyzePodIssue(pod k8s.PodIssue) (string, error) {
-	// Build prompt for Amazon Q
-	prompt := fmt.Sprintf(`
-As a Kubernetes expert, please analyze this pod issue:
+// SetContextBudget sets the number of tokens the context of an object, such
+// as its events and logs, may take in a prompt. Zero or less uses
+// DefaultContextBudget.
+func (c *AmazonQClient) SetContextBudget(tokens int) {
+	c.budget = tokens
+}
 
-Pod: %s
-Namespace: %s
-Status: %s
-Message: %s
-Reason: %s
+// contextBudget returns the context budget of the client
+func (c *AmazonQClient) contextBudget() int {
+	if c.budget <= 0 {
+		return DefaultContextBudget
+	}
+	return c.budget
+}
 
-Please provide:
-1. A diagnosis of the issue
-2. Likely root causes
-3. Recommended solutions
-4. Specific kubectl commands to help diagnose or fix the issue
-`, pod.Name, pod.Namespace, pod.Status, pod.Message, pod.Reason)
+// SetCache makes the client serve repeated prompts from cache instead of
+// calling the model again. A nil cache disables caching.
+func (c *AmazonQClient) SetCache(cache *Cache) {
+	c.cache = cache
+}
 
-	// Call Amazon Q
-	return c.CallAmazonQ(prompt)
+// SetRateLimit limits the calls to the model, which cache hits do not count
+// against, to perMinute requests per minute. Zero or less removes the limit.
+func (c *AmazonQClient) SetRateLimit(perMinute int) {
+	c.limiter = NewRateLimiter(perMinute)
 }
 
-// AnalyzeDeploymentIssue analyzes a deployment issue using Amazon Q
-func (c *AmazonQClient) AnalyzeDeploymentIssue(deployment k8s.DeploymentIssue) (string, error) {
-	// Build prompt for Amazon Q
-	prompt := fmt.Sprintf(`
-As a Kubernetes expert, please analyze this deployment issue:
+// SetRetryPolicy replaces the retry policy of the client, which defaults to
+// DefaultRetryPolicy
+func (c *AmazonQClient) SetRetryPolicy(policy RetryPolicy) {
+	c.retry = policy
+}
 
-Deployment: %s
-Namespace: %s
-Replicas: %d/%d ready
-Message: %s
-Reason: %s
+// SetCircuitBreaker replaces the circuit breaker of the client. A nil
+// breaker never stops calls.
+func (c *AmazonQClient) SetCircuitBreaker(breaker *CircuitBreaker) {
+	c.breaker = breaker
+}
 
-Please provide:
-1. A diagnosis of the issue
-2. Likely root causes
-3. Recommended solutions
-4. Specific kubectl commands to help diagnose or fix the issue
-`, deployment.Name, deployment.Namespace, deployment.ReadyReplicas, deployment.Replicas, deployment.Message, deployment.Reason)
+// SetRedactor replaces the redactor that every prompt goes through before
+// it is cached or sent, which defaults to DefaultRedactor. A nil redactor
+// sends prompts as they are.
+func (c *AmazonQClient) SetRedactor(redactor *Redactor) {
+	c.redactor = redactor
+}
 
-	// Call Amazon Q
-	return c.CallAmazonQ(prompt)
+// Redact returns a prompt as the client would send it, with the values its
+// redactor replaced
+func (c *AmazonQClient) Redact(prompt string) (string, []Redaction) {
+	return c.redactor.Redact(prompt)
 }
 
-// ExplainError explains a Kubernetes error using Amazon Q
-func (c *AmazonQClient) ExplainError(errorMsg string) (string, error) {
-	// Build prompt for Amazon Q
-	prompt := fmt.Sprintf(`
-As a Kubernetes expert, please analyze this error message and explain:
-1. What the error means
-2. Likely causes
-3. How to fix it
-4. Specific kubectl commands that might help diagnose or fix the issue
+// SetVerboseOutput makes the client report details of its calls, such as
+// cache hits and redactions, to w. A nil writer disables them.
+func (c *AmazonQClient) SetVerboseOutput(w io.Writer) {
+	c.verbose = w
+}
+
+// logf writes a verbose message if verbose output is enabled
+func (c *AmazonQClient) logf(format string, args ...interface{}) {
+	if c.verbose != nil {
+		fmt.Fprintf(c.verbose, format+"\n", args...)
+	}
+}
+
+// withFormat appends DiagnosisFormatPrompt to a rendered prompt
+func withFormat(prompt string, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(prompt, "\n") + "\n" + DiagnosisFormatPrompt, nil
+}
+
+// PodPrompt returns the prompt AnalyzePodIssue sends for a pod
+func (c *AmazonQClient) PodPrompt(pod k8s.PodIssue) (string, error) {
+	return withFormat(c.Prompts().Render(PromptPod, NewPodPromptData(pod).Pack(c.contextBudget())))
+}
+
+// DeploymentPrompt returns the prompt AnalyzeDeploymentIssue sends for a
+// deployment
+func (c *AmazonQClient) DeploymentPrompt(deployment k8s.DeploymentIssue) (string, error) {
+	return withFormat(c.Prompts().Render(PromptDeployment, NewDeploymentPromptData(deployment).Pack(c.contextBudget())))
+}
+
+// ServicePrompt returns the prompt AnalyzeServiceIssue sends for a service
+func (c *AmazonQClient) ServicePrompt(service map[string]interface{}) (string, error) {
+	return withFormat(c.Prompts().Render(PromptService, NewServicePromptData(service).Pack(c.contextBudget())))
+}
+
+// ErrorPrompt returns the prompt ExplainError sends for an error message
+func (c *AmazonQClient) ErrorPrompt(errorMsg string) (string, error) {
+	return withFormat(c.Prompts().Render(PromptError, ErrorPromptData{ErrorMessage: errorMsg}.Pack(c.contextBudget())))
+}
+
+// PodFixPrompt returns the prompt GeneratePodFix sends for a pod
+func (c *AmazonQClient) PodFixPrompt(pod k8s.PodIssue) (string, error) {
+	return withFormat(c.Prompts().Render(PromptFix, NewPodFixPromptData(NewPodPromptData(pod).Pack(c.contextBudget()))))
+}
+
+// DeploymentFixPrompt returns the prompt GenerateDeploymentFix sends for a
+// deployment
+func (c *AmazonQClient) DeploymentFixPrompt(deployment k8s.DeploymentIssue) (string, error) {
+	return withFormat(c.Prompts().Render(PromptFix, NewDeploymentFixPromptData(NewDeploymentPromptData(deployment).Pack(c.contextBudget()))))
+}
+
+// AnalyzePodIssue analyzes a pod issue using Amazon Q. If Amazon Q cannot be
+// used, the pod is diagnosed by the built-in rules instead, and the diagnosis
+// notes why in its Fallback field. The other analyses and ExplainError do the
+// same; fixes are only generated by the model.
+func (c *AmazonQClient) AnalyzePodIssue(ctx context.Context, pod k8s.PodIssue) (*Diagnosis, error) {
+	prompt, err := c.PodPrompt(pod)
+	if err != nil {
+		return nil, err
+	}
+	diagnosis, err := c.diagnose(ctx, OperationAnalyzePod, prompt)
+	if err != nil {
+		return c.fallback(ctx, OperationAnalyzePod, err, PodRuleDiagnosis(pod))
+	}
+	return diagnosis, nil
+}
+
+// AnalyzeDeploymentIssue analyzes a deployment issue using Amazon Q
+func (c *AmazonQClient) AnalyzeDeploymentIssue(ctx context.Context, deployment k8s.DeploymentIssue) (*Diagnosis, error) {
+	prompt, err := c.DeploymentPrompt(deployment)
+	if err != nil {
+		return nil, err
+	}
+	diagnosis, err := c.diagnose(ctx, OperationAnalyzeDeployment, prompt)
+	if err != nil {
+		return c.fallback(ctx, OperationAnalyzeDeployment, err, DeploymentRuleDiagnosis(deployment))
+	}
+	return diagnosis, nil
+}
 
-Error message:
-%s
-`, errorMsg)
+// AnalyzeServiceIssue analyzes a service finding, as collected by
+// k8s.Client.GetServiceIssues, using Amazon Q
+func (c *AmazonQClient) AnalyzeServiceIssue(ctx context.Context, service map[string]interface{}) (*Diagnosis, error) {
+	prompt, err := c.ServicePrompt(service)
+	if err != nil {
+		return nil, err
+	}
+	diagnosis, err := c.diagnose(ctx, OperationAnalyzeService, prompt)
+	if err != nil {
+		return c.fallback(ctx, OperationAnalyzeService, err, ServiceRuleDiagnosis(service))
+	}
+	return diagnosis, nil
+}
 
-	// Call Amazon Q
-	return c.CallAmazonQ(prompt)
+// AnalyzeClusterReport asks Amazon Q for an assessment of a cluster's health
+func (c *AmazonQClient) AnalyzeClusterReport(ctx context.Context, report ClusterReportPromptData) (string, error) {
+	prompt, err := c.Prompts().Render(PromptClusterReport, report)
+	if err != nil {
+		return "", err
+	}
+	return c.call(ctx, OperationClusterReport, prompt)
+}
+
+// ExplainError explains a Kubernetes error using Amazon Q
+func (c *AmazonQClient) ExplainError(ctx context.Context, errorMsg string) (*Diagnosis, error) {
+	prompt, err := c.ErrorPrompt(errorMsg)
+	if err != nil {
+		return nil, err
+	}
+	diagnosis, err := c.diagnose(ctx, OperationExplain, prompt)
+	if err != nil {
+		return c.fallback(ctx, OperationExplain, err, ErrorRuleDiagnosis(errorMsg))
+	}
+	return diagnosis, nil
 }
 
 // GeneratePodFix generates a fix for a pod issue using Amazon Q
-func (c *AmazonQClient) GeneratePodFix(pod k8s.PodIssue) (string, error) {
-	// Build prompt for Amazon Q
-	prompt := fmt.Sprintf(`
-As a Kubernetes expert, please generate a fix for this pod issue:
-
-Pod: %s
-Status: %s
-Message: %s
-Reason: %s
-
-Please provide:
-1. A brief explanation of the fix
-2. YAML patch or kubectl commands to apply the fix
-3. Any additional steps needed
-`,
-		pod.Name,
-		pod.Status,
-		pod.Message,
-		pod.Reason,
-	)
-
-	// Call Amazon Q
-	return c.CallAmazonQ(prompt)
+func (c *AmazonQClient) GeneratePodFix(ctx context.Context, pod k8s.PodIssue) (*Diagnosis, error) {
+	prompt, err := c.PodFixPrompt(pod)
+	if err != nil {
+		return nil, err
+	}
+	return c.diagnose(ctx, OperationFixPod, prompt)
 }
 
 // GenerateDeploymentFix generates a fix for a deployment issue using Amazon Q
-func (c *AmazonQClient) GenerateDeploymentFix(deployment k8s.DeploymentIssue) (string, error) {
-	// Build prompt for Amazon Q
-	prompt := fmt.Sprintf(`
-As a Kubernetes expert, please generate a fix for this deployment issue:
-
-Deployment: %s
-Replicas: %d/%d ready
-Message: %s
-Reason: %s
-
-Please provide:
-1. A brief explanation of the fix
-2. YAML patch or kubectl commands to apply the fix
-3. Any additional steps needed
-`,
-		deployment.Name,
-		deployment.ReadyReplicas,
-		deployment.Replicas,
-		deployment.Message,
-		deployment.Reason,
-	)
-
-	// Call Amazon Q
-	return c.CallAmazonQ(prompt)
+func (c *AmazonQClient) GenerateDeploymentFix(ctx context.Context, deployment k8s.DeploymentIssue) (*Diagnosis, error) {
+	prompt, err := c.DeploymentFixPrompt(deployment)
+	if err != nil {
+		return nil, err
+	}
+	return c.diagnose(ctx, OperationFixDeployment, prompt)
+}
+
+// diagnose runs a prompt that asks for DiagnosisFormatPrompt and parses the
+// response, falling back to reading it as markdown
+func (c *AmazonQClient) diagnose(ctx context.Context, operation, prompt string) (*Diagnosis, error) {
+	response, err := c.call(ctx, operation, prompt)
+	if err != nil {
+		return nil, err
+	}
+	diagnosis, err := decodeDiagnosis(response)
+	if err != nil {
+		c.logf("The %s response does not follow the response format (%v), reading it as markdown", operation, err)
+		diagnosis = extractDiagnosis(response)
+	}
+	diagnosis.Raw = response
+	return diagnosis, nil
+}
+
+// fallback returns the rule diagnosis of an analysis whose call failed, or
+// the error if there is no rule diagnosis, the caller gave up or a replayed
+// fixture is missing
+func (c *AmazonQClient) fallback(ctx context.Context, operation string, err error, rule *Diagnosis) (*Diagnosis, error) {
+	if rule == nil || ctx.Err() != nil || errors.Is(err, ErrNoFixture) {
+		return nil, err
+	}
+	c.logf("Falling back to built-in rules for %s: %v", operation, err)
+
+	rule.Fallback = fallbackReason(err)
+	// Outputs read the diagnosis back from Raw, so it holds the diagnosis in
+	// the response format
+	raw, jsonErr := json.Marshal(rule)
+	if jsonErr != nil {
+		return nil, err
+	}
+	rule.Raw = string(raw)
+	return rule, nil
+}
+
+// fallbackReason describes why the model could not be used, in a few words
+func fallbackReason(err error) string {
+	var statusErr *StatusError
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return "disabled after repeated failures"
+	case errors.Is(err, errAttemptTimeout):
+		return "timed out"
+	case errors.As(err, &statusErr):
+		return fmt.Sprintf("provider returned %d", statusErr.StatusCode)
+	case IsRetryable(err):
+		return "still failing after retries"
+	}
+	return "call failed"
 }
 
 // GenerateResponse generates a response based on a custom prompt
-func (c *AmazonQClient) GenerateResponse(prompt string) (string, error) {
+func (c *AmazonQClient) GenerateResponse(ctx context.Context, prompt string) (string, error) {
 	// Call Amazon Q with the provided prompt
-	return c.CallAmazonQ(prompt)
+	return c.call(ctx, OperationPrompt, prompt)
+}
+
+// CallAmazonQ calls the model with a prompt. The call is abandoned, and the
+// CLI process killed, if ctx is cancelled before it returns.
+func (c *AmazonQClient) CallAmazonQ(ctx context.Context, prompt string) (string, error) {
+	return c.call(ctx, OperationPrompt, prompt)
+}
+
+// call redacts a prompt and runs it, from cache if it was answered before,
+// and reports calls to the model to the call observer under operation. The
+// response is written to the stream output of ctx, if any.
+func (c *AmazonQClient) call(ctx context.Context, operation, prompt string) (string, error) {
+	prompt, redactions := c.Redact(prompt)
+	if len(redactions) > 0 {
+		c.logf("Redacted %s from the %s prompt", FormatRedactions(redactions), operation)
+	}
+
+	fingerprint := PromptFingerprint(prompt)
+	if c.cache != nil {
+		if response, age, ok := c.cache.Get(c.backend(), fingerprint); ok {
+			c.logf("Cache hit for %s (prompt %s, %s old)", operation, fingerprint[:12], age.Round(time.Second))
+			if stream := streamOutput(ctx); stream != nil {
+				io.WriteString(stream, response)
+			}
+			return response, nil
+		}
+	}
+
+	response, err := c.callWithRetry(ctx, operation, prompt)
+	if err == nil && c.cache != nil {
+		// A failed write only costs a call next time
+		if err := c.cache.Put(c.backend(), fingerprint, operation, response); err != nil {
+			c.logf("Not caching %s response: %v", operation, err)
+		}
+	}
+	return response, err
 }
 
-// CallAmazonQ calls the Amazon Q CLI with a prompt
-func (c *AmazonQClient) CallAmazonQ(prompt string) (string, error) {
-	// Check if mock mode is enabled - default to using real Amazon Q
-	useMock := false
-	if os.Getenv("KUBEGPT_MOCK_AI") == "true" {
-		useMock = true
+// callWithRetry calls the model, retrying retryable failures with
+// exponential backoff. Each attempt waits for the rate limit and is refused
+// while the circuit breaker is open.
+func (c *AmazonQClient) callWithRetry(ctx context.Context, operation, prompt string) (string, error) {
+	if stream := streamOutput(ctx); stream != nil {
+		ctx = WithStreamOutput(ctx, &countingWriter{w: stream})
+	}
+	for retry := 0; ; retry++ {
+		response, err := c.attempt(ctx, operation, prompt)
+		if err == nil || !IsRetryable(err) || retry >= c.retry.MaxRetries || ctx.Err() != nil {
+			return response, err
+		}
+
+		// Retry-After is honored up to the longest backoff, so a provider
+		// cannot stall a run for hours
+		delay := c.retry.delay(retry)
+		if after := retryAfter(err); after > delay {
+			delay = after
+			if c.retry.MaxDelay > 0 && delay > c.retry.MaxDelay {
+				delay = c.retry.MaxDelay
+			}
+		}
+		// A retry that could only start after the deadline is not worth the
+		// wait; the provider's error says more than the deadline's
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return response, err
+		}
+		c.logf("Retrying %s in %s (attempt %d of %d): %v", operation, delay.Round(time.Millisecond), retry+2, c.retry.MaxRetries+1, err)
+		if stream, ok := streamOutput(ctx).(*countingWriter); ok && stream.n > 0 {
+			// The next attempt starts its response over
+			fmt.Fprintf(stream.w, "\n[response interrupted, retrying]\n")
+			stream.n = 0
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return "", err
+		}
 	}
+}
 
-	if useMock {
+// attempt makes a single call to the model, within the timeout of the retry
+// policy, and reports it to the call observer and circuit breaker
+func (c *AmazonQClient) attempt(ctx context.Context, operation, prompt string) (string, error) {
+	waited, err := c.limiter.Wait(ctx)
+	if err != nil {
+		return "", err
+	}
+	if waited > 0 {
+		c.logf("Waited %s for the rate limit before %s", waited.Round(time.Millisecond), operation)
+	}
+
+	if err := c.breaker.Allow(); err != nil {
+		return "", err
+	}
+
+	attemptCtx := ctx
+	if c.retry.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, c.retry.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	response, err := c.run(attemptCtx, prompt)
+	if err != nil && ctx.Err() == nil && attemptCtx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("%w after %s", errAttemptTimeout, c.retry.Timeout)
+	}
+	if callObserver != nil {
+		callObserver(operation, time.Since(start), err)
+	}
+
+	if ctx.Err() != nil {
+		c.breaker.Cancel()
+	} else {
+		c.breaker.Record(err)
+	}
+	return response, err
+}
+
+// transientCLIError matches the error output of the Amazon Q CLI for
+// failures that are worth retrying
+var transientCLIError = regexp.MustCompile(`(?i)throttl|too many requests|rate exceeded|timed? ?out|service unavailable|internal server error|\b(429|5\d\d)\b|connection (reset|refused)`)
+
+// MockEnabled reports whether mock mode is enabled - default to using real Amazon Q
+func MockEnabled() bool {
+	return os.Getenv("KUBEGPT_MOCK_AI") == "true"
+}
+
+// mock reports whether responses are canned. Replayed fixtures take
+// precedence over mock mode, since they were asked for explicitly.
+func (c *AmazonQClient) mock() bool {
+	if _, replay := c.provider.(*ReplayProvider); replay {
+		return false
+	}
+	return MockEnabled()
+}
+
+// backend names where responses come from, to keep their cache entries apart
+func (c *AmazonQClient) backend() string {
+	if c.mock() {
+		return "mock"
+	}
+	return unsafeNameChars.ReplaceAllString(c.provider.Name(), "_")
+}
+
+// run calls the provider, or returns a canned response in mock mode
+func (c *AmazonQClient) run(ctx context.Context, prompt string) (string, error) {
+	stream := streamOutput(ctx)
+	if c.mock() {
 		// Use mock response in development mode
-		return c.mockResponse(prompt), nil
+		response := c.mockResponse(prompt)
+		if stream != nil {
+			io.WriteString(stream, response)
+		}
+		return response, nil
 	}
+	return c.provider.Complete(ctx, prompt, stream)
+}
 
+// AmazonQCLI is the provider that runs the Amazon Q CLI
+type AmazonQCLI struct {
+	path string
+}
+
+// NewAmazonQCLI finds the Amazon Q CLI on the PATH
+func NewAmazonQCLI() *AmazonQCLI {
+	// Try to find the Amazon Q CLI
+	cliPath, err := exec.LookPath("amazon-q")
+	if err != nil {
+		// If not found, use the default path
+		cliPath = "amazon-q"
+	}
+	return &AmazonQCLI{path: cliPath}
+}
+
+// Name returns the name of the CLI
+func (q *AmazonQCLI) Name() string {
+	return "amazon-q"
+}
+
+// Complete runs the CLI with the prompt in a temporary file. Its output is
+// written to stream as the CLI prints it.
+func (q *AmazonQCLI) Complete(ctx context.Context, prompt string, stream io.Writer) (string, error) {
 	// Create a temporary file for the prompt
 	promptFile, err := os.CreateTemp("", "kubegpt-prompt-*.txt")
 	if err != nil {
@@ -179,17 +533,24 @@ func (c *AmazonQClient) CallAmazonQ(prompt string) (string, error) {
 	promptFile.Close()
 
 	// Create a command to call Amazon Q CLI
-	cmd := exec.Command(c.cliPath, "chat", "--prompt-file", promptFile.Name())
+	cmd := exec.CommandContext(ctx, q.path, "chat", "--prompt-file", promptFile.Name())
 
 	// Capture stdout and stderr
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
+	if stream != nil {
+		cmd.Stdout = io.MultiWriter(&stdout, stream)
+	}
 	cmd.Stderr = &stderr
 
 	// Run the command
 	if err := cmd.Run(); err != nil {
 		// If the CLI is not installed or fails, return a helpful message
-		return "", fmt.Errorf("Failed to run Amazon Q CLI: %w. Error output: %s", err, stderr.String())
+		err = fmt.Errorf("Failed to run Amazon Q CLI: %w. Error output: %s", err, strings.TrimSpace(stderr.String()))
+		if transientCLIError.Match(stderr.Bytes()) {
+			err = &temporaryError{err}
+		}
+		return "", err
 	}
 
 	// Return the response
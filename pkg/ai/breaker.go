@@ -0,0 +1,92 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the model while the circuit
+// breaker is open
+var ErrCircuitOpen = errors.New("AI provider disabled after repeated failures")
+
+// CircuitBreaker stops calls to a provider after a number of consecutive
+// failures. Once the cooldown has passed it lets a single trial call
+// through, which closes the circuit if it succeeds and opens it again if it
+// fails. It is safe for concurrent use.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	trial     bool
+	lastErr   error
+}
+
+// Default settings of the circuit breaker of new clients
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = time.Minute
+)
+
+// NewCircuitBreaker creates a breaker that opens after threshold consecutive
+// failures for cooldown, or returns nil, which never opens, if threshold is
+// zero or less
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow returns an error wrapping ErrCircuitOpen if the call must not be
+// made
+func (b *CircuitBreaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+	if b.trial || time.Since(b.openedAt) < b.cooldown {
+		return fmt.Errorf("%w (last error: %v)", ErrCircuitOpen, b.lastErr)
+	}
+	b.trial = true
+	return nil
+}
+
+// Record records the outcome of a call that Allow let through
+func (b *CircuitBreaker) Record(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if err == nil {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	b.lastErr = err
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
+
+// Cancel records that a call Allow let through was given up by its caller,
+// which says nothing about the health of the provider
+func (b *CircuitBreaker) Cancel() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
@@ -0,0 +1,198 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Default limits of the response cache
+const (
+	DefaultCacheTTL     = 24 * time.Hour
+	DefaultCacheMaxSize = 50 << 20
+)
+
+// PromptFingerprint identifies a prompt by the SHA-256 of its text
+func PromptFingerprint(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// DefaultCacheDir returns the cache directory under the user cache directory,
+// such as ~/.cache/kubegpt/responses on Linux
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("locating user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "kubegpt", "responses"), nil
+}
+
+// Cache stores AI responses on disk by the fingerprint of their prompt. Each
+// backend has its own directory, so that responses of one model are never
+// served for another. Entries expire after a TTL, and the oldest entries are
+// removed when the cache grows past its size limit.
+type Cache struct {
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+
+	// size estimates the bytes in the cache, so that the directory is only
+	// walked when it may have outgrown maxSize. It is measured by the first
+	// write and is negative until then.
+	mu   sync.Mutex
+	size int64
+}
+
+// cacheEntry is the file stored for a response
+type cacheEntry struct {
+	Operation string    `json:"operation"`
+	Created   time.Time `json:"created"`
+	Response  string    `json:"response"`
+}
+
+// NewCache creates a cache in dir. A ttl or maxSize of zero or less uses the
+// defaults.
+func NewCache(dir string, ttl time.Duration, maxSize int64) *Cache {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultCacheMaxSize
+	}
+	return &Cache{dir: dir, ttl: ttl, maxSize: maxSize, size: -1}
+}
+
+// path returns the file of a fingerprint, sharded by its first two characters
+func (c *Cache) path(backend, fingerprint string) string {
+	return filepath.Join(c.dir, backend, fingerprint[:2], fingerprint+".json")
+}
+
+// Get returns the response cached for a fingerprint and its age. Expired and
+// unreadable entries are misses.
+func (c *Cache) Get(backend, fingerprint string) (string, time.Duration, bool) {
+	data, err := os.ReadFile(c.path(backend, fingerprint))
+	if err != nil {
+		return "", 0, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return "", 0, false
+	}
+	age := time.Since(entry.Created)
+	if age > c.ttl {
+		os.Remove(c.path(backend, fingerprint))
+		return "", 0, false
+	}
+	return entry.Response, age, true
+}
+
+// Put stores the response of a fingerprint. The cache is trimmed to its
+// limits on the first write and whenever the writes since the last trim may
+// have taken it past its size limit. The entry is written to a temporary file
+// and renamed, so that concurrent runs never read a partial entry.
+func (c *Cache) Put(backend, fingerprint, operation, response string) error {
+	path := c.path(backend, fingerprint)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(cacheEntry{Operation: operation, Created: time.Now(), Response: response})
+	if err != nil {
+		return fmt.Errorf("encoding cache entry: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	var replaced int64
+	if info, err := os.Stat(path); err == nil {
+		replaced = info.Size()
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size >= 0 {
+		c.size += int64(len(data)) - replaced
+		if c.size <= c.maxSize {
+			return nil
+		}
+	}
+	size, err := c.trim()
+	if err != nil {
+		return err
+	}
+	c.size = size
+	return nil
+}
+
+// trim removes expired entries, then the least recently written ones until
+// the cache fits in its size limit, and returns the size left
+func (c *Cache) trim() (int64, error) {
+	type file struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var files []file
+	var total int64
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Entries removed by a concurrent run are not an error
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if time.Since(info.ModTime()) > c.ttl {
+			os.Remove(path)
+			return nil
+		}
+		files = append(files, file{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("trimming cache: %w", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, f := range files {
+		if total <= c.maxSize {
+			break
+		}
+		os.Remove(f.path)
+		total -= f.size
+	}
+	return total, nil
+}
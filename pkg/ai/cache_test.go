@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cacheFiles counts the entries stored in a cache directory
+func cacheFiles(t *testing.T, dir string) int {
+	t.Helper()
+	count := 0
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Ext(path) == ".json" {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+func TestCacheGetPut(t *testing.T) {
+	cache := NewCache(t.TempDir(), time.Hour, 0)
+	fingerprint := PromptFingerprint("explain CrashLoopBackOff")
+
+	if _, _, ok := cache.Get("amazon-q", fingerprint); ok {
+		t.Fatal("Get() on an empty cache hit")
+	}
+	if err := cache.Put("amazon-q", fingerprint, OperationExplain, "answer"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	response, age, ok := cache.Get("amazon-q", fingerprint)
+	if !ok || response != "answer" || age < 0 || age > time.Minute {
+		t.Errorf("Get() = %q, %s, %v, want the stored answer", response, age, ok)
+	}
+	if _, _, ok := cache.Get("openai_gpt-4o", fingerprint); ok {
+		t.Error("Get() for another backend hit, want backends kept apart")
+	}
+	if _, _, ok := cache.Get("amazon-q", PromptFingerprint("explain OOMKilled")); ok {
+		t.Error("Get() for another prompt hit")
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(dir, 20*time.Millisecond, 0)
+	fingerprint := PromptFingerprint("prompt")
+
+	if err := cache.Put("amazon-q", fingerprint, OperationExplain, "answer"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	time.Sleep(40 * time.Millisecond)
+
+	if _, _, ok := cache.Get("amazon-q", fingerprint); ok {
+		t.Error("Get() of an expired entry hit")
+	}
+	if n := cacheFiles(t, dir); n != 0 {
+		t.Errorf("%d entries left after expiry, want the expired one removed", n)
+	}
+}
+
+func TestCacheSizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	response := strings.Repeat("x", 1000)
+	// Room for three entries of a little over 1000 bytes
+	cache := NewCache(dir, time.Hour, 3500)
+
+	var fingerprints []string
+	for i := 0; i < 6; i++ {
+		fingerprint := PromptFingerprint(strings.Repeat("p", i+1))
+		fingerprints = append(fingerprints, fingerprint)
+		if err := cache.Put("amazon-q", fingerprint, OperationExplain, response); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		// Modification times order the entries for trimming
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := cacheFiles(t, dir); n > 3 {
+		t.Errorf("%d entries in the cache, want at most 3", n)
+	}
+	if _, _, ok := cache.Get("amazon-q", fingerprints[5]); !ok {
+		t.Error("the newest entry was trimmed")
+	}
+	if _, _, ok := cache.Get("amazon-q", fingerprints[0]); ok {
+		t.Error("the oldest entry was kept")
+	}
+}
+
+func TestClientCache(t *testing.T) {
+	t.Setenv("KUBEGPT_MOCK_AI", "")
+
+	tests := []struct {
+		name      string
+		cache     bool
+		wantCalls int
+	}{
+		{"repeated prompts are served from cache", true, 1},
+		// --no-cache leaves the client without a cache
+		{"without a cache every prompt calls the model", false, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &scriptedProvider{outcomes: []error{nil}}
+			client := NewAmazonQClient()
+			client.SetProvider(provider)
+			if tt.cache {
+				client.SetCache(NewCache(t.TempDir(), time.Hour, 0))
+			}
+
+			for i := 0; i < 2; i++ {
+				response, err := client.call(context.Background(), OperationExplain, "explain CrashLoopBackOff")
+				if err != nil || response != "answer" {
+					t.Fatalf("call %d = %q, %v, want the answer", i+1, response, err)
+				}
+			}
+			if provider.calls != tt.wantCalls {
+				t.Errorf("provider called %d times, want %d", provider.calls, tt.wantCalls)
+			}
+		})
+	}
+}
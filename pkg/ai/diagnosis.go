@@ -0,0 +1,500 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Diagnosis is the structured answer of an analysis, fix or explanation
+type Diagnosis struct {
+	// Summary says what is wrong in a sentence or two
+	Summary    string      `json:"summary" yaml:"summary"`
+	RootCauses []RootCause `json:"rootCauses,omitempty" yaml:"rootCauses,omitempty"`
+	// DiagnosticCommands are read-only commands that confirm the cause
+	DiagnosticCommands []string `json:"diagnosticCommands,omitempty" yaml:"diagnosticCommands,omitempty"`
+	// RemediationCommands are commands that change the cluster to fix it
+	RemediationCommands []string `json:"remediationCommands,omitempty" yaml:"remediationCommands,omitempty"`
+	Patches             []Patch  `json:"patches,omitempty" yaml:"patches,omitempty"`
+	// Structured is false when the model ignored the response format and
+	// the fields were extracted from its markdown, in which case root causes
+	// have no confidence
+	Structured bool `json:"structured" yaml:"structured"`
+	// Fallback is why the model could not be used when the diagnosis comes
+	// from the built-in rules instead, and is empty otherwise
+	Fallback string `json:"fallback,omitempty" yaml:"fallback,omitempty"`
+	// Raw is the response of the model
+	Raw string `json:"-" yaml:"-"`
+}
+
+// RootCause is a likely cause of an issue
+type RootCause struct {
+	Cause string `json:"cause" yaml:"cause"`
+	// Confidence is between 0 and 1, or 0 when it is unknown
+	Confidence float64 `json:"confidence,omitempty" yaml:"confidence,omitempty"`
+}
+
+// Patch is a YAML patch or manifest that fixes an issue
+type Patch struct {
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Target is the object the patch applies to in kind/name form, if known
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
+	YAML   string `json:"yaml" yaml:"yaml"`
+}
+
+// ParseDiagnosis reads a response to a prompt that asked for
+// DiagnosisFormatPrompt. A response that does not follow the format is read
+// as markdown instead: its commands and YAML blocks are extracted, and its
+// first paragraph becomes the summary.
+func ParseDiagnosis(response string) *Diagnosis {
+	diagnosis, err := decodeDiagnosis(response)
+	if err != nil {
+		diagnosis = extractDiagnosis(response)
+	}
+	diagnosis.Raw = response
+	return diagnosis
+}
+
+// decodeDiagnosis decodes and validates a JSON response. Models often wrap
+// JSON in a code fence or a sentence, so the outermost object is used.
+func decodeDiagnosis(response string) (*Diagnosis, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in response")
+	}
+
+	var diagnosis Diagnosis
+	if err := json.Unmarshal([]byte(response[start:end+1]), &diagnosis); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if err := diagnosis.validate(); err != nil {
+		return nil, err
+	}
+	diagnosis.Structured = true
+	return &diagnosis, nil
+}
+
+// validate checks and normalizes a decoded diagnosis. Diagnostic commands
+// that could change the cluster are moved to the remediation commands, so
+// that the diagnostic ones are always safe to run.
+func (d *Diagnosis) validate() error {
+	d.Summary = strings.TrimSpace(d.Summary)
+	if d.Summary == "" {
+		return fmt.Errorf("response has no summary")
+	}
+
+	var causes []RootCause
+	for _, cause := range d.RootCauses {
+		cause.Cause = strings.TrimSpace(cause.Cause)
+		if cause.Cause == "" {
+			continue
+		}
+		// Some models answer in percent
+		if cause.Confidence > 1 && cause.Confidence <= 100 {
+			cause.Confidence /= 100
+		}
+		if cause.Confidence < 0 || cause.Confidence > 1 || math.IsNaN(cause.Confidence) {
+			return fmt.Errorf("root cause %q has confidence %v, expected 0 to 1", cause.Cause, cause.Confidence)
+		}
+		causes = append(causes, cause)
+	}
+	d.RootCauses = causes
+
+	var diagnostic []string
+	remediation := cleanCommands(d.RemediationCommands)
+	for _, command := range cleanCommands(d.DiagnosticCommands) {
+		if IsReadOnlyCommand(command) {
+			diagnostic = append(diagnostic, command)
+		} else {
+			remediation = append(remediation, command)
+		}
+	}
+	d.DiagnosticCommands, d.RemediationCommands = diagnostic, remediation
+
+	var patches []Patch
+	for i, patch := range d.Patches {
+		patch.YAML = strings.Trim(stripFence(patch.YAML), "\n")
+		if strings.TrimSpace(patch.YAML) == "" {
+			continue
+		}
+		if !isYAMLDocument(patch.YAML) {
+			return fmt.Errorf("patch %d is not a YAML mapping or list", i+1)
+		}
+		patch.Description = strings.TrimSpace(patch.Description)
+		patch.Target = strings.TrimSpace(patch.Target)
+		patches = append(patches, patch)
+	}
+	d.Patches = patches
+	return nil
+}
+
+// cleanCommands trims commands and their shell prompts, dropping empty ones
+func cleanCommands(commands []string) []string {
+	var clean []string
+	for _, command := range commands {
+		command = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(command), "$ "))
+		if command != "" {
+			clean = append(clean, command)
+		}
+	}
+	return clean
+}
+
+// isYAMLDocument reports whether text parses as a YAML mapping or list, as
+// opposed to a scalar such as a sentence
+func isYAMLDocument(text string) bool {
+	var document interface{}
+	if err := yaml.Unmarshal([]byte(text), &document); err != nil {
+		return false
+	}
+	switch document.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+// readOnlyVerbs are the kubectl subcommands that never change the cluster.
+// Subcommands with their own verbs are listed as "command verb".
+var readOnlyVerbs = map[string]bool{
+	"get": true, "describe": true, "logs": true, "top": true, "explain": true,
+	"events": true, "api-resources": true, "api-versions": true, "version": true,
+	"cluster-info": true, "diff": true, "auth can-i": true, "auth whoami": true,
+	"config view": true, "config current-context": true, "config get-contexts": true,
+	"rollout status": true, "rollout history": true,
+}
+
+// kubectlValueFlags are global kubectl flags that take a separate value,
+// which must be skipped to find the subcommand
+var kubectlValueFlags = map[string]bool{
+	"-n": true, "--namespace": true, "--context": true, "--kubeconfig": true,
+	"--cluster": true, "--user": true, "-s": true, "--server": true,
+}
+
+var (
+	// shellOperators are pipes, redirections, command lists and
+	// substitutions, which can run anything after a read-only command
+	shellOperators = regexp.MustCompile("[|;&><`]|\\$\\(")
+	// placeholder matches the <pod-name> style placeholders of examples
+	placeholder = regexp.MustCompile(`<[\w.-]+>`)
+)
+
+// IsReadOnlyCommand reports whether a command only reads from the cluster.
+// It is conservative: only single kubectl commands with a read-only verb
+// qualify, without pipes or other shell operators.
+func IsReadOnlyCommand(command string) bool {
+	if shellOperators.MatchString(placeholder.ReplaceAllString(command, "x")) {
+		return false
+	}
+	fields := strings.Fields(command)
+	if len(fields) < 2 || fields[0] != "kubectl" {
+		return false
+	}
+
+	var args []string
+	for i := 1; i < len(fields); i++ {
+		field := fields[i]
+		if strings.HasPrefix(field, "-") {
+			if kubectlValueFlags[field] {
+				i++
+			}
+			continue
+		}
+		args = append(args, field)
+	}
+	if len(args) == 0 {
+		return false
+	}
+	if readOnlyVerbs[args[0]] {
+		return true
+	}
+	return len(args) > 1 && readOnlyVerbs[args[0]+" "+args[1]]
+}
+
+var (
+	// markdownFence matches a fenced code block
+	markdownFence = regexp.MustCompile("(?s)```([\\w-]*)[ \t]*\n(.*?)```")
+	// yamlKeyLine matches a line that starts a YAML mapping
+	yamlKeyLine = regexp.MustCompile(`^[\w.-]+:(\s|$)`)
+	// listItem matches a numbered or bulleted list item
+	listItem = regexp.MustCompile(`^\s*(?:\d+[.)]|[-*+])\s+(.*)$`)
+	// causeHeading matches the headings of sections listing causes
+	causeHeading = regexp.MustCompile(`(?i)cause`)
+	// summaryHeading matches the headings of sections describing the issue
+	summaryHeading = regexp.MustCompile(`(?i)what('|’)?s happening|summary|diagnosis|what the error means|overview`)
+	// headingLine matches markdown headings and bold or colon-terminated
+	// lines used as headings
+	headingLine = regexp.MustCompile(`^\s*(?:#+\s*(.+?)\s*#*|\*\*(.+?):?\*\*:?|([A-Z][^.:]{0,60}):)\s*$`)
+)
+
+// extractDiagnosis reads the fields of a diagnosis from a markdown answer.
+// Commands are kubectl lines anywhere in the text. Patches are YAML code
+// blocks and indented YAML mappings, described by the line before them.
+func extractDiagnosis(markdown string) *Diagnosis {
+	diagnosis := &Diagnosis{}
+	lines := strings.Split(markdown, "\n")
+
+	// Fenced blocks are replaced by their lines, remembering which were
+	// fenced YAML so that they become patches as a whole
+	var heading, previous, firstParagraph, summary string
+	var block []string
+	var blockIndent int
+	inFence, fenceYAML := false, false
+
+	flushBlock := func() {
+		if len(block) > 0 {
+			text := strings.Join(block, "\n")
+			if isYAMLDocument(text) {
+				diagnosis.Patches = append(diagnosis.Patches, Patch{Description: previous, YAML: dedent(text)})
+			}
+		}
+		block = nil
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") {
+			if inFence {
+				if fenceYAML {
+					flushBlock()
+				}
+				inFence, block = false, nil
+				continue
+			}
+			flushBlock()
+			language := strings.ToLower(strings.TrimPrefix(trimmed, "```"))
+			inFence, fenceYAML = true, language == "yaml" || language == "yml"
+			continue
+		}
+
+		if command := strings.TrimPrefix(trimmed, "$ "); strings.HasPrefix(command, "kubectl ") {
+			flushBlock()
+			if IsReadOnlyCommand(command) {
+				diagnosis.DiagnosticCommands = appendNew(diagnosis.DiagnosticCommands, command)
+			} else {
+				diagnosis.RemediationCommands = appendNew(diagnosis.RemediationCommands, command)
+			}
+			continue
+		}
+
+		if inFence {
+			if fenceYAML {
+				block = append(block, line)
+			}
+			continue
+		}
+
+		// An indented line starting with a key may begin a YAML snippet,
+		// which goes on while lines are indented at least as much
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if trimmed != "" && indent > 0 {
+			switch {
+			case len(block) == 0 && yamlKeyLine.MatchString(trimmed):
+				blockIndent = indent
+				block = append(block, line)
+				continue
+			case len(block) > 0 && (indent > blockIndent || (indent == blockIndent && yamlKeyLine.MatchString(trimmed))):
+				block = append(block, line)
+				continue
+			}
+		}
+		flushBlock()
+
+		if trimmed == "" {
+			continue
+		}
+		if match := headingLine.FindStringSubmatch(line); match != nil && !listItem.MatchString(line) {
+			// A heading such as "Add a pull secret:" also describes the
+			// patch that follows it
+			heading = strings.TrimSpace(match[1] + match[2] + match[3])
+			previous = heading
+			continue
+		}
+
+		item := listItem.FindStringSubmatch(line)
+		switch {
+		case item != nil && causeHeading.MatchString(heading):
+			diagnosis.RootCauses = append(diagnosis.RootCauses, RootCause{Cause: strings.TrimSpace(item[1])})
+		case item == nil && summary == "" && summaryHeading.MatchString(heading):
+			summary = trimmed
+		case item == nil && firstParagraph == "":
+			firstParagraph = trimmed
+		}
+		if item != nil {
+			previous = strings.TrimSuffix(strings.TrimSpace(item[1]), ":")
+		} else {
+			previous = strings.TrimSuffix(trimmed, ":")
+		}
+	}
+	flushBlock()
+
+	diagnosis.Summary = summary
+	if diagnosis.Summary == "" {
+		diagnosis.Summary = firstParagraph
+	}
+	return diagnosis
+}
+
+// appendNew appends a command unless it is already listed
+func appendNew(commands []string, command string) []string {
+	for _, existing := range commands {
+		if existing == command {
+			return commands
+		}
+	}
+	return append(commands, command)
+}
+
+// dedent removes the indentation common to all lines
+func dedent(text string) string {
+	lines := strings.Split(text, "\n")
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if common < 0 || indent < common {
+			common = indent
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= common && common > 0 {
+			lines[i] = line[common:]
+		}
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// stripFence removes a code fence around text, if there is one
+func stripFence(text string) string {
+	if match := markdownFence.FindStringSubmatch(strings.TrimSpace(text)); match != nil {
+		return match[2]
+	}
+	return text
+}
+
+// Merge combines an analysis with the diagnosis of a fix for the same
+// object. The analysis keeps its summary and causes, the commands of both
+// are listed, and the patches of the fix replace those of the analysis.
+func (d Diagnosis) Merge(fix *Diagnosis) Diagnosis {
+	if fix == nil {
+		return d
+	}
+	merged := d
+	if merged.Summary == "" {
+		merged.Summary = fix.Summary
+		merged.Structured = fix.Structured
+	}
+	if len(merged.RootCauses) == 0 {
+		merged.RootCauses = fix.RootCauses
+	}
+	merged.DiagnosticCommands = append([]string(nil), d.DiagnosticCommands...)
+	for _, command := range fix.DiagnosticCommands {
+		merged.DiagnosticCommands = appendNew(merged.DiagnosticCommands, command)
+	}
+	merged.RemediationCommands = append([]string(nil), d.RemediationCommands...)
+	for _, command := range fix.RemediationCommands {
+		merged.RemediationCommands = appendNew(merged.RemediationCommands, command)
+	}
+	if len(fix.Patches) > 0 {
+		merged.Patches = fix.Patches
+	}
+	return merged
+}
+
+// Markdown renders the diagnosis as markdown, for chat, tickets and other
+// text outputs. A diagnosis extracted from markdown returns the response as
+// it is, which reads better than the extracted fields.
+func (d Diagnosis) Markdown() string {
+	if !d.Structured && strings.TrimSpace(d.Raw) != "" {
+		return strings.TrimSpace(d.Raw)
+	}
+
+	var sb strings.Builder
+	if d.Fallback != "" {
+		sb.WriteString("> " + d.FallbackNote() + "\n\n")
+	}
+	if d.Summary != "" {
+		sb.WriteString(d.Summary + "\n")
+	}
+	if len(d.RootCauses) > 0 {
+		sb.WriteString("\n### Root causes\n\n")
+		for _, cause := range d.RootCauses {
+			sb.WriteString("- " + cause.Cause)
+			if cause.Confidence > 0 {
+				sb.WriteString(fmt.Sprintf(" (%.0f%% confidence)", cause.Confidence*100))
+			}
+			sb.WriteString("\n")
+		}
+	}
+	if len(d.DiagnosticCommands) > 0 {
+		sb.WriteString("\n### Diagnostic commands\n\n```bash\n" + strings.Join(d.DiagnosticCommands, "\n") + "\n```\n")
+	}
+	if len(d.RemediationCommands) > 0 {
+		sb.WriteString("\n### Remediation commands\n\n```bash\n" + strings.Join(d.RemediationCommands, "\n") + "\n```\n")
+	}
+	if len(d.Patches) > 0 {
+		sb.WriteString("\n### Patches\n")
+		for _, patch := range d.Patches {
+			sb.WriteString("\n")
+			if heading := patch.Heading(); heading != "" {
+				sb.WriteString("#### " + heading + "\n\n")
+			}
+			sb.WriteString("```yaml\n" + patch.YAML + "\n```\n")
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// FallbackNote says that the diagnosis comes from the built-in rules and
+// why, or is empty if it comes from the model
+func (d Diagnosis) FallbackNote() string {
+	if d.Fallback == "" {
+		return ""
+	}
+	return fmt.Sprintf("AI analysis unavailable (%s); this diagnosis comes from built-in rules.", d.Fallback)
+}
+
+// FixYAML renders the patches as one YAML stream, each document preceded
+// by a comment with its description, or the remediation commands if there
+// are no patches
+func (d Diagnosis) FixYAML() string {
+	if len(d.Patches) == 0 {
+		return strings.Join(d.RemediationCommands, "\n")
+	}
+	documents := make([]string, len(d.Patches))
+	for i, patch := range d.Patches {
+		documents[i] = patch.YAML
+		if heading := patch.Heading(); heading != "" {
+			documents[i] = "# " + heading + "\n" + patch.YAML
+		}
+	}
+	return strings.Join(documents, "\n---\n")
+}
+
+// FixText renders a fix as FixYAML, or as markdown if it has neither
+// patches nor remediation commands
+func (d Diagnosis) FixText() string {
+	if fix := d.FixYAML(); fix != "" {
+		return fix
+	}
+	return d.Markdown()
+}
+
+// Heading describes a patch with its target and description
+func (p Patch) Heading() string {
+	switch {
+	case p.Target != "" && p.Description != "":
+		return p.Target + ": " + p.Description
+	case p.Target != "":
+		return p.Target
+	}
+	return p.Description
+}
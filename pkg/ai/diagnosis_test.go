@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDiagnosis(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     Diagnosis
+	}{
+		{
+			name:     "JSON response",
+			response: `{"summary": "The image tag does not exist.", "rootCauses": [{"cause": "typo in the tag", "confidence": 0.9}], "diagnosticCommands": ["kubectl describe pod web"], "remediationCommands": [], "patches": []}`,
+			want: Diagnosis{
+				Summary:            "The image tag does not exist.",
+				RootCauses:         []RootCause{{Cause: "typo in the tag", Confidence: 0.9}},
+				DiagnosticCommands: []string{"kubectl describe pod web"},
+				Structured:         true,
+			},
+		},
+		{
+			name:     "JSON in a code fence with percent confidence",
+			response: "Here is the diagnosis:\n```json\n{\"summary\": \" OOMKilled \", \"rootCauses\": [{\"cause\": \"memory limit too low\", \"confidence\": 80}]}\n```",
+			want: Diagnosis{
+				Summary:    "OOMKilled",
+				RootCauses: []RootCause{{Cause: "memory limit too low", Confidence: 0.8}},
+				Structured: true,
+			},
+		},
+		{
+			name:     "mutating diagnostic commands move to remediation",
+			response: `{"summary": "Bad config", "diagnosticCommands": ["$ kubectl get pods", "kubectl delete pod web", "kubectl logs web | grep error", ""], "remediationCommands": ["kubectl rollout restart deployment/web"]}`,
+			want: Diagnosis{
+				Summary:             "Bad config",
+				DiagnosticCommands:  []string{"kubectl get pods"},
+				RemediationCommands: []string{"kubectl rollout restart deployment/web", "kubectl delete pod web", "kubectl logs web | grep error"},
+				Structured:          true,
+			},
+		},
+		{
+			name:     "patches are unfenced and empty ones dropped",
+			response: `{"summary": "Raise the limit", "patches": [{"description": " more memory ", "target": "deployment/web", "yaml": "` + "```yaml\\nspec:\\n  replicas: 2\\n```" + `"}, {"yaml": " "}]}`,
+			want: Diagnosis{
+				Summary:    "Raise the limit",
+				Patches:    []Patch{{Description: "more memory", Target: "deployment/web", YAML: "spec:\n  replicas: 2"}},
+				Structured: true,
+			},
+		},
+		{
+			name:     "JSON without a summary is read as markdown",
+			response: `{"rootCauses": []}`,
+			want:     Diagnosis{Summary: `{"rootCauses": []}`},
+		},
+		{
+			name:     "confidence out of range is read as markdown",
+			response: `{"summary": "x", "rootCauses": [{"cause": "y", "confidence": -2}]}`,
+			want:     Diagnosis{Summary: `{"summary": "x", "rootCauses": [{"cause": "y", "confidence": -2}]}`},
+		},
+		{
+			name: "markdown response",
+			response: "## What's happening\n" +
+				"The pod cannot pull its image.\n\n" +
+				"## Likely causes\n" +
+				"1. The tag does not exist\n" +
+				"2. The registry needs credentials\n\n" +
+				"## Fix\n" +
+				"Check the events:\n" +
+				"$ kubectl describe pod web\n" +
+				"kubectl set image deployment/web web=nginx:1.25\n\n" +
+				"Add a pull secret:\n" +
+				"```yaml\n" +
+				"spec:\n" +
+				"  imagePullSecrets:\n" +
+				"    - name: registry\n" +
+				"```\n",
+			want: Diagnosis{
+				Summary:             "The pod cannot pull its image.",
+				RootCauses:          []RootCause{{Cause: "The tag does not exist"}, {Cause: "The registry needs credentials"}},
+				DiagnosticCommands:  []string{"kubectl describe pod web"},
+				RemediationCommands: []string{"kubectl set image deployment/web web=nginx:1.25"},
+				Patches:             []Patch{{Description: "Add a pull secret", YAML: "spec:\n  imagePullSecrets:\n    - name: registry"}},
+			},
+		},
+		{
+			name:     "plain text",
+			response: "The container ran out of memory.\nRaise its limit.",
+			want:     Diagnosis{Summary: "The container ran out of memory."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseDiagnosis(tt.response)
+			if got.Raw != tt.response {
+				t.Errorf("Raw = %q, want the response", got.Raw)
+			}
+			got.Raw = ""
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ParseDiagnosis() =\n%#v\nwant\n%#v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsReadOnlyCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"kubectl get pods", true},
+		{"kubectl -n prod logs web --previous", true},
+		{"kubectl --context staging describe pod <pod-name>", true},
+		{"kubectl rollout status deployment/web", true},
+		{"kubectl auth can-i get pods", true},
+		{"kubectl rollout restart deployment/web", false},
+		{"kubectl delete pod web", false},
+		{"kubectl apply -f fix.yaml", false},
+		{"kubectl get pods | xargs kubectl delete pod", false},
+		{"kubectl get pods; rm -rf /", false},
+		{"kubectl get pods $(whoami)", false},
+		{"kubectl", false},
+		{"helm list", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if got := IsReadOnlyCommand(tt.command); got != tt.want {
+				t.Errorf("IsReadOnlyCommand(%q) = %v, want %v", tt.command, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoFixture is returned by the replay provider for a prompt that has no
+// fixture
+var ErrNoFixture = errors.New("no fixture for prompt")
+
+// Fixture is a recorded prompt and the response of the model. Fixtures are
+// stored as YAML, one file per prompt named after its fingerprint, so that
+// they can be reviewed and edited by hand. Replay finds them by file name;
+// the prompt is kept for reviewers.
+type Fixture struct {
+	Fingerprint string    `yaml:"fingerprint"`
+	Provider    string    `yaml:"provider"`
+	Recorded    time.Time `yaml:"recorded"`
+	Prompt      string    `yaml:"prompt"`
+	Response    string    `yaml:"response"`
+}
+
+// fixturePath returns the file of a fingerprint in dir
+func fixturePath(dir, fingerprint string) string {
+	return filepath.Join(dir, fingerprint+".yaml")
+}
+
+// RecordingProvider calls another provider and stores each prompt and
+// response in a fixture file, for the replay provider
+type RecordingProvider struct {
+	provider Provider
+	dir      string
+}
+
+// NewRecordingProvider records the calls to provider in dir
+func NewRecordingProvider(provider Provider, dir string) *RecordingProvider {
+	return &RecordingProvider{provider: provider, dir: dir}
+}
+
+// Name returns the name of the recorded provider
+func (r *RecordingProvider) Name() string {
+	return r.provider.Name()
+}
+
+// Complete calls the recorded provider and writes the fixture of a
+// successful call, replacing any earlier one for the same prompt
+func (r *RecordingProvider) Complete(ctx context.Context, prompt string, stream io.Writer) (string, error) {
+	response, err := r.provider.Complete(ctx, prompt, stream)
+	if err != nil {
+		return "", err
+	}
+
+	fingerprint := PromptFingerprint(prompt)
+	data, err := yaml.Marshal(Fixture{
+		Fingerprint: fingerprint,
+		Provider:    r.provider.Name(),
+		Recorded:    time.Now().UTC().Truncate(time.Second),
+		Prompt:      prompt,
+		Response:    response,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding fixture: %w", err)
+	}
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return "", fmt.Errorf("creating fixtures directory: %w", err)
+	}
+	if err := os.WriteFile(fixturePath(r.dir, fingerprint), data, 0644); err != nil {
+		return "", fmt.Errorf("writing fixture: %w", err)
+	}
+	return response, nil
+}
+
+// ReplayProvider answers prompts from the fixture files of a
+// RecordingProvider, without calling a model
+type ReplayProvider struct {
+	dir string
+}
+
+// NewReplayProvider replays the fixtures in dir
+func NewReplayProvider(dir string) *ReplayProvider {
+	return &ReplayProvider{dir: dir}
+}
+
+// Name returns "replay"
+func (r *ReplayProvider) Name() string {
+	return "replay"
+}
+
+// Complete returns the recorded response of prompt, or an error wrapping
+// ErrNoFixture that names the missing fixture file
+func (r *ReplayProvider) Complete(ctx context.Context, prompt string, stream io.Writer) (string, error) {
+	fingerprint := PromptFingerprint(prompt)
+	path := fixturePath(r.dir, fingerprint)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("%w %s: %s does not exist; record it with --record-fixtures%s", ErrNoFixture, fingerprint[:12], path, r.closest(prompt))
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading fixture: %w", err)
+	}
+
+	var fixture Fixture
+	if err := yaml.Unmarshal(data, &fixture); err != nil {
+		return "", fmt.Errorf("decoding fixture %s: %w", path, err)
+	}
+	if stream != nil {
+		io.WriteString(stream, fixture.Response)
+	}
+	return fixture.Response, nil
+}
+
+// closest describes where prompt departs from the most similar recorded
+// prompt, as prompts often differ only by an age or a log line, or returns
+// an empty string if there are no fixtures
+func (r *ReplayProvider) closest(prompt string) string {
+	// YAML block scalars do not keep leading blank lines, so prompts are
+	// compared without them
+	paths, _ := filepath.Glob(filepath.Join(r.dir, "*.yaml"))
+	lines := strings.Split(strings.Trim(prompt, "\n"), "\n")
+
+	best, bestLine, bestPath := -1, "", ""
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var fixture Fixture
+		if err := yaml.Unmarshal(data, &fixture); err != nil {
+			continue
+		}
+
+		recorded := strings.Split(strings.Trim(fixture.Prompt, "\n"), "\n")
+		same := 0
+		for same < len(lines) && same < len(recorded) && lines[same] == recorded[same] {
+			same++
+		}
+		if same > best {
+			best, bestPath = same, path
+			bestLine = ""
+			if same < len(recorded) {
+				bestLine = recorded[same]
+			}
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+
+	got := ""
+	if best < len(lines) {
+		got = lines[best]
+	}
+	return fmt.Sprintf("; the closest fixture, %s, differs at line %d: recorded %q, got %q", filepath.Base(bestPath), best+1, bestLine, got)
+}
@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPromptFingerprint(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{"identical prompts", "explain CrashLoopBackOff", "explain CrashLoopBackOff", true},
+		{"one character apart", "Age: 5m", "Age: 6m", false},
+		{"trailing newline", "prompt", "prompt\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := PromptFingerprint(tt.a), PromptFingerprint(tt.b)
+			if len(a) != 64 {
+				t.Fatalf("PromptFingerprint() = %q, want 64 hex characters", a)
+			}
+			if (a == b) != tt.same {
+				t.Errorf("fingerprints %s and %s, want same %v", a, b, tt.same)
+			}
+		})
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	prompts := []string{
+		"explain CrashLoopBackOff",
+		"\nPod web in namespace default\nStatus: Pending\n",
+		"Événements: ポッド is 待機中",
+	}
+
+	dir := t.TempDir()
+	recorder := NewRecordingProvider(&scriptedProvider{outcomes: []error{nil}}, dir)
+	for _, prompt := range prompts {
+		if _, err := recorder.Complete(context.Background(), prompt, nil); err != nil {
+			t.Fatalf("recording %q: %v", prompt, err)
+		}
+	}
+
+	replay := NewReplayProvider(dir)
+	for _, prompt := range prompts {
+		var stream strings.Builder
+		got, err := replay.Complete(context.Background(), prompt, &stream)
+		if err != nil {
+			t.Fatalf("replaying %q: %v", prompt, err)
+		}
+		if got != "answer" || stream.String() != "answer" {
+			t.Errorf("replaying %q = %q, streamed %q, want the recorded answer", prompt, got, stream.String())
+		}
+	}
+}
+
+func TestRecordingSkipsFailedCalls(t *testing.T) {
+	dir := t.TempDir()
+	failure := &StatusError{StatusCode: 500}
+	recorder := NewRecordingProvider(&scriptedProvider{outcomes: []error{failure}}, dir)
+
+	if _, err := recorder.Complete(context.Background(), "prompt", nil); !errors.Is(err, failure) {
+		t.Fatalf("Complete() error = %v, want the provider's", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("recorded %d fixtures for a failed call, want none", len(entries))
+	}
+}
+
+func TestReplayMiss(t *testing.T) {
+	dir := t.TempDir()
+	recorder := NewRecordingProvider(&scriptedProvider{outcomes: []error{nil}}, dir)
+	if _, err := recorder.Complete(context.Background(), "Pod web\nAge: 5m\nStatus: Pending", nil); err != nil {
+		t.Fatalf("recording: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		dir    string
+		prompt string
+		want   []string
+	}{
+		{
+			name:   "names the closest fixture and the differing line",
+			dir:    dir,
+			prompt: "Pod web\nAge: 6m\nStatus: Pending",
+			want:   []string{PromptFingerprint("Pod web\nAge: 6m\nStatus: Pending")[:12], "differs at line 2", `recorded "Age: 5m", got "Age: 6m"`},
+		},
+		{
+			name:   "empty directory",
+			dir:    t.TempDir(),
+			prompt: "Pod web",
+			want:   []string{"does not exist; record it with --record-fixtures"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReplayProvider(tt.dir).Complete(context.Background(), tt.prompt, nil)
+			if !errors.Is(err, ErrNoFixture) {
+				t.Fatalf("Complete() error = %v, want ErrNoFixture", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReplayTakesPrecedenceOverMock(t *testing.T) {
+	t.Setenv("KUBEGPT_MOCK_AI", "true")
+
+	// The example fixture of the README must keep replaying
+	client := NewAmazonQClient()
+	client.SetProvider(NewReplayProvider(filepath.Join("..", "..", "examples", "fixtures")))
+	diagnosis, err := client.ExplainError(context.Background(), "CrashLoopBackOff: container exited with code 1")
+	if err != nil {
+		t.Fatalf("ExplainError: %v", err)
+	}
+	if !diagnosis.Structured || diagnosis.Fallback != "" || !strings.Contains(diagnosis.Summary, "exits with code 1") {
+		t.Errorf("ExplainError() = %+v, want the replayed fixture", diagnosis)
+	}
+
+	_, err = client.ExplainError(context.Background(), "an error without a fixture")
+	if !errors.Is(err, ErrNoFixture) {
+		t.Errorf("ExplainError() without a fixture = %v, want ErrNoFixture instead of a mock answer", err)
+	}
+}
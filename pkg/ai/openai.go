@@ -0,0 +1,188 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default endpoints of the HTTP providers
+const (
+	DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+	DefaultOllamaBaseURL = "http://localhost:11434/v1"
+)
+
+// OpenAIProvider calls a chat completions API compatible with OpenAI's,
+// which many hosted and local model servers provide, and streams the
+// response with server-sent events
+type OpenAIProvider struct {
+	name       string
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOpenAIProvider creates a provider for the API at baseURL, or OpenAI's
+// if it is empty. apiKey may be empty for servers that do not need one.
+func NewOpenAIProvider(baseURL, apiKey, model string) *OpenAIProvider {
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	return &OpenAIProvider{
+		name:       "openai",
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{},
+	}
+}
+
+// NewOllamaProvider creates a provider for the OpenAI-compatible API of an
+// Ollama server at baseURL, or on localhost if it is empty
+func NewOllamaProvider(baseURL, model string) *OpenAIProvider {
+	if baseURL == "" {
+		baseURL = DefaultOllamaBaseURL
+	}
+	provider := NewOpenAIProvider(baseURL, "", model)
+	provider.name = "ollama"
+	return provider
+}
+
+// Name returns the provider and model
+func (p *OpenAIProvider) Name() string {
+	return p.name + "/" + p.model
+}
+
+// chatRequest is the body of a chat completions request
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatChunk is the data of a streamed event
+type chatChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// maxErrorBody caps the response body kept in a StatusError
+const maxErrorBody = 512
+
+// Complete sends prompt as a user message and collects the streamed deltas
+func (p *OpenAIProvider) Complete(ctx context.Context, prompt string, stream io.Writer) (string, error) {
+	if p.model == "" {
+		return "", fmt.Errorf("no model set for the %s provider", p.name)
+	}
+
+	body, err := json.Marshal(chatRequest{
+		Model:    p.model,
+		Messages: []chatMessage{{Role: "user", Content: prompt}},
+		Stream:   true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encoding %s request: %w", p.name, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("creating %s request: %w", p.name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("calling %s: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return "", &StatusError{
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(data)),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	response, err := readEventStream(resp.Body, stream)
+	if err != nil {
+		return "", fmt.Errorf("reading %s response: %w", p.name, err)
+	}
+	return response, nil
+}
+
+// readEventStream collects the content deltas of a server-sent event stream
+// until its [DONE] event, writing each to stream if it is not nil
+func readEventStream(r io.Reader, stream io.Writer) (string, error) {
+	var response strings.Builder
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			// Blank lines end events; comments and other fields are unused
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return response.String(), nil
+		}
+
+		var chunk chatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", fmt.Errorf("decoding event: %w", err)
+		}
+		if chunk.Error != nil {
+			return "", fmt.Errorf("provider error: %s", chunk.Error.Message)
+		}
+		for _, choice := range chunk.Choices {
+			response.WriteString(choice.Delta.Content)
+			if stream != nil && choice.Delta.Content != "" {
+				io.WriteString(stream, choice.Delta.Content)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	// Some servers close the stream without a [DONE] event
+	return response.String(), nil
+}
+
+// parseRetryAfter reads a Retry-After header, in seconds or as a date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
@@ -0,0 +1,183 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadEventStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "collects deltas until DONE",
+			body: "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"The pod \"}}]}\n\n" +
+				"data:{\"choices\":[{\"delta\":{\"content\":\"is crash looping.\"}}]}\n\n" +
+				"data: [DONE]\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"ignored\"}}]}\n\n",
+			want: "The pod is crash looping.",
+		},
+		{
+			name: "skips comments and other fields",
+			body: ": keep-alive\n\nevent: message\nid: 1\ndata: {\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\ndata: [DONE]\n",
+			want: "ok",
+		},
+		{
+			name: "accepts a stream closed without DONE",
+			body: "data: {\"choices\":[{\"delta\":{\"content\":\"partial\"}}]}\n\n",
+			want: "partial",
+		},
+		{
+			name:    "fails on an error event",
+			body:    "data: {\"choices\":[{\"delta\":{\"content\":\"The\"}}]}\n\ndata: {\"error\":{\"message\":\"overloaded\"}}\n\n",
+			wantErr: true,
+		},
+		{
+			name:    "fails on malformed data",
+			body:    "data: {\"choices\":\n\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stream strings.Builder
+			got, err := readEventStream(strings.NewReader(tt.body), &stream)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("readEventStream() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readEventStream(): %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readEventStream() = %q, want %q", got, tt.want)
+			}
+			if stream.String() != tt.want {
+				t.Errorf("streamed %q, want %q", stream.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAIProviderComplete(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		retryAfter     string
+		body           string
+		want           string
+		wantStatus     int
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:   "streams the answer",
+			status: http.StatusOK,
+			body:   "data: {\"choices\":[{\"delta\":{\"content\":\"answer\"}}]}\n\ndata: [DONE]\n\n",
+			want:   "answer",
+		},
+		{
+			name:           "reports throttling with its Retry-After",
+			status:         http.StatusTooManyRequests,
+			retryAfter:     "7",
+			body:           `{"error":{"message":"rate limited"}}`,
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: 7 * time.Second,
+		},
+		{
+			name:       "reports other failures",
+			status:     http.StatusUnauthorized,
+			body:       `{"error":{"message":"invalid api key"}}`,
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request chatRequest
+			var authorization string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get("Authorization")
+				if r.URL.Path != "/v1/chat/completions" {
+					http.NotFound(w, r)
+					return
+				}
+				json.NewDecoder(r.Body).Decode(&request)
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			provider := NewOpenAIProvider(server.URL+"/v1/", "sk-test", "gpt-test")
+			got, err := provider.Complete(context.Background(), "why is web crashing?", nil)
+
+			if request.Model != "gpt-test" || !request.Stream || len(request.Messages) != 1 || request.Messages[0].Content != "why is web crashing?" {
+				t.Errorf("request = %+v, want a streamed user message for gpt-test", request)
+			}
+			if authorization != "Bearer sk-test" {
+				t.Errorf("Authorization = %q, want the API key", authorization)
+			}
+
+			if tt.wantStatus == 0 {
+				if err != nil || got != tt.want {
+					t.Errorf("Complete() = %q, %v, want %q", got, err, tt.want)
+				}
+				return
+			}
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("Complete() error = %v, want a StatusError", err)
+			}
+			if statusErr.StatusCode != tt.wantStatus || statusErr.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("StatusError = %+v, want status %d and Retry-After %s", statusErr, tt.wantStatus, tt.wantRetryAfter)
+			}
+			if !strings.Contains(statusErr.Error(), "message") {
+				t.Errorf("StatusError = %q, want the response body in it", statusErr.Error())
+			}
+		})
+	}
+}
+
+func TestOpenAIProviderRequiresModel(t *testing.T) {
+	if _, err := NewOllamaProvider("", "").Complete(context.Background(), "prompt", nil); err == nil {
+		t.Error("Complete() without a model succeeded, want an error")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"", 0, 0},
+		{"30", 30 * time.Second, 30 * time.Second},
+		{"0", 0, 0},
+		{"soon", 0, 0},
+		{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 58 * time.Second, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value); got < tt.min || got > tt.max {
+				t.Errorf("parseRetryAfter(%q) = %s, want between %s and %s", tt.value, got, tt.min, tt.max)
+			}
+		})
+	}
+}
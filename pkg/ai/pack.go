@@ -0,0 +1,295 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultContextBudget is the number of tokens the variable context of a
+// prompt (container statuses, events, logs and spec) may take when the client
+// has no budget set
+const DefaultContextBudget = 3000
+
+// maxLineChars caps a single log line, so that one huge JSON log record cannot
+// take the whole budget
+const maxLineChars = 400
+
+// SectionKind selects how the packer shortens a section and how much of the
+// budget it gets
+type SectionKind int
+
+const (
+	// SectionStatus is the state of the object, such as its container
+	// statuses or conditions. It is kept whole whenever possible.
+	SectionStatus SectionKind = iota
+	// SectionLogs are container logs. Error and stack-trace lines are kept
+	// first, then the most recent lines.
+	SectionLogs
+	// SectionEvents are events, oldest first. Warnings are kept first, then
+	// the most recent events.
+	SectionEvents
+	// SectionSpec is the configuration of the object. It is cut from the end.
+	SectionSpec
+)
+
+// sectionRules are the share of the budget each kind is guaranteed, by
+// weight, and the order in which kinds get the budget left over by others
+var sectionRules = map[SectionKind]struct {
+	weight   int
+	priority int
+}{
+	SectionStatus: {weight: 2, priority: 0},
+	SectionLogs:   {weight: 4, priority: 1},
+	SectionEvents: {weight: 2, priority: 2},
+	SectionSpec:   {weight: 1, priority: 3},
+}
+
+// Section is a part of a prompt's context that the packer may shorten
+type Section struct {
+	// Name is what the lines of the section are called in the elision
+	// note, such as "log lines"
+	Name string
+	Kind SectionKind
+	Text string
+}
+
+// EstimateTokens estimates the number of tokens of text. Tokenizers differ
+// between models, so it uses the common approximation of four characters per
+// token, which errs on the high side for English and code.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Pack shortens sections so that together they fit in budget tokens. Each
+// kind is guaranteed a weighted share of the budget, and the share that
+// small sections do not use goes to the others in priority order. The
+// shortened sections are returned with a note of what was elided, which is
+// empty when everything fit.
+func Pack(budget int, sections []Section) ([]Section, string) {
+	packed := append([]Section(nil), sections...)
+
+	total := 0
+	needs := make([]int, len(packed))
+	for i, section := range packed {
+		needs[i] = EstimateTokens(section.Text)
+		total += needs[i]
+	}
+	if total <= budget {
+		return packed, ""
+	}
+
+	// Guaranteed shares, capped by what each section needs
+	weights := 0
+	for i, section := range packed {
+		if needs[i] > 0 {
+			weights += sectionRules[section.Kind].weight
+		}
+	}
+	allocs := make([]int, len(packed))
+	left := budget
+	for i, section := range packed {
+		if needs[i] == 0 {
+			continue
+		}
+		share := budget * sectionRules[section.Kind].weight / weights
+		allocs[i] = min(needs[i], share)
+		left -= allocs[i]
+	}
+
+	// Leftovers in priority order
+	order := make([]int, len(packed))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return sectionRules[packed[order[a]].Kind].priority < sectionRules[packed[order[b]].Kind].priority
+	})
+	for _, i := range order {
+		extra := min(needs[i]-allocs[i], left)
+		allocs[i] += extra
+		left -= extra
+	}
+
+	var notes []string
+	for i, section := range packed {
+		if allocs[i] >= needs[i] {
+			continue
+		}
+		lines := strings.Split(section.Text, "\n")
+		text, kept := shorten(section.Kind, lines, allocs[i])
+		packed[i].Text = text
+		notes = append(notes, fmt.Sprintf("kept %d of %d %s (%s)", kept, len(lines), section.Name, keptWhat[section.Kind]))
+	}
+	return packed, "Some context was shortened to fit the prompt: " + strings.Join(notes, "; ") + "."
+}
+
+// keptWhat describes what the packer keeps of each kind, for the elision note
+var keptWhat = map[SectionKind]string{
+	SectionStatus: "the first lines",
+	SectionLogs:   "errors, stack traces and the most recent lines",
+	SectionEvents: "warnings and the most recent events",
+	SectionSpec:   "the first lines",
+}
+
+// shorten keeps the most important lines of a section within budget tokens,
+// in their original order, and marks the gaps. It returns the text and the
+// number of lines kept.
+func shorten(kind SectionKind, lines []string, budget int) (string, int) {
+	if kind == SectionLogs {
+		for i, line := range lines {
+			if utf8.RuneCountInString(line) > maxLineChars {
+				lines[i] = string([]rune(line)[:maxLineChars]) + "...[line truncated]"
+			}
+		}
+	}
+
+	// Lines in the order they are kept
+	var order []int
+	switch kind {
+	case SectionLogs:
+		order = rankLogLines(lines)
+	case SectionEvents:
+		order = rankEvents(lines)
+	default:
+		for i := range lines {
+			order = append(order, i)
+		}
+	}
+
+	// The cost of the kept lines is tracked incrementally, with the markers
+	// of the runs of dropped lines, as sections can have many thousands of
+	// lines
+	keep := make([]bool, len(lines))
+	kept, cost, gaps := 0, 0, 1
+	for _, i := range order {
+		leftDropped := i > 0 && !keep[i-1]
+		rightDropped := i < len(lines)-1 && !keep[i+1]
+		newGaps := gaps - 1
+		if leftDropped {
+			newGaps++
+		}
+		if rightDropped {
+			newGaps++
+		}
+		lineCost := EstimateTokens(lines[i]) + 1
+		if cost+lineCost+newGaps*markerTokens > budget {
+			// Stop at the first line that does not fit rather than
+			// skipping to smaller ones, so that lower-ranked lines are
+			// never kept in place of higher-ranked ones
+			break
+		}
+		keep[i] = true
+		kept, cost, gaps = kept+1, cost+lineCost, newGaps
+	}
+	return joinKept(lines, keep), kept
+}
+
+// markerTokens is the estimated cost of a gap marker written by joinKept
+const markerTokens = 7
+
+// joinKept joins the kept lines, replacing each run of dropped lines with a
+// marker
+func joinKept(lines []string, keep []bool) string {
+	var out []string
+	dropped := 0
+	for i, line := range lines {
+		if !keep[i] {
+			dropped++
+			continue
+		}
+		if dropped > 0 {
+			out = append(out, elisionMarker(dropped))
+			dropped = 0
+		}
+		out = append(out, line)
+	}
+	if dropped > 0 {
+		out = append(out, elisionMarker(dropped))
+	}
+	return strings.Join(out, "\n")
+}
+
+// elisionMarker replaces a run of dropped lines
+func elisionMarker(dropped int) string {
+	if dropped == 1 {
+		return "...[1 line elided]..."
+	}
+	return fmt.Sprintf("...[%d lines elided]...", dropped)
+}
+
+var (
+	// errorLine matches log lines that report a failure
+	errorLine = regexp.MustCompile(`(?i)\b(error|err|exception|panic|fatal|fail(ed|ure)?|critical|traceback|caused by|denied|refused|timeout|timed out|oom|killed)\b`)
+	// stackLine matches the frames of Java, Python, Go and Node.js stack
+	// traces
+	stackLine = regexp.MustCompile(`^\s+at\s|^\s+File ".*", line \d+|^goroutine \d+|\.go:\d+|^\s+\.\.\. \d+ more|^\s*raise\s`)
+	// logHeader matches the container headers written by formatLogs
+	logHeader = regexp.MustCompile(`^--- .* ---$`)
+)
+
+// recentLogLines is the number of trailing log lines ranked above older
+// lines without errors
+const recentLogLines = 20
+
+// rankLogLines orders log lines by importance: container headers, then error
+// and stack-trace lines, then the last lines of each container, then the
+// rest, most recent first within each group. The lines around a stack frame
+// count as part of the trace, as Go prints the function before its file and
+// Python the code after it.
+func rankLogLines(lines []string) []int {
+	frames := make([]bool, len(lines))
+	for i, line := range lines {
+		if stackLine.MatchString(line) {
+			frames[i] = true
+		}
+	}
+	inTrace := func(i int) bool {
+		return frames[i] || (i > 0 && frames[i-1]) || (i < len(lines)-1 && frames[i+1])
+	}
+
+	ranks := make([]int, len(lines))
+	sinceEnd := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+		switch {
+		case logHeader.MatchString(line):
+			ranks[i] = 0
+			sinceEnd = 0
+			continue
+		case errorLine.MatchString(line) || inTrace(i):
+			ranks[i] = 1
+		case sinceEnd < recentLogLines:
+			ranks[i] = 2
+		default:
+			ranks[i] = 3
+		}
+		sinceEnd++
+	}
+	return byRank(ranks)
+}
+
+// rankEvents orders event lines: warnings, then the others, most recent first
+func rankEvents(lines []string) []int {
+	ranks := make([]int, len(lines))
+	for i, line := range lines {
+		if !strings.Contains(line, " Warning ") {
+			ranks[i] = 1
+		}
+	}
+	return byRank(ranks)
+}
+
+// byRank returns the line indexes by rank, and by recency within a rank
+func byRank(ranks []int) []int {
+	order := make([]int, len(ranks))
+	for i := range order {
+		order[i] = len(ranks) - 1 - i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return ranks[order[a]] < ranks[order[b]]
+	})
+	return order
+}
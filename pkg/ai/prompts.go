@@ -10,9 +10,9 @@ Pod: {{.Name}}
 Namespace: {{.Namespace}}
 Status: {{.Status}}
 Age: {{.Age}}
-Node: {{.Node}}
-{{if .Message}}Message: {{.Message}}{{end}}
-{{if .Reason}}Reason: {{.Reason}}{{end}}
+Node: {{.Node}}{{if .Message}}
+Message: {{.Message}}{{end}}{{if .Reason}}
+Reason: {{.Reason}}{{end}}
 
 Container issues:
 {{.ContainerIssues}}
@@ -24,6 +24,13 @@ Events:
 Container logs (most recent):
 {{.Logs}}
 {{end}}
+{{if .Spec}}
+Container spec:
+{{.Spec}}
+{{end}}
+{{if .Elided}}
+Note: {{.Elided}}
+{{end}}
 
 Please provide:
 1. A diagnosis of the issue
@@ -42,9 +49,9 @@ Replicas: {{.ReadyReplicas}}/{{.Replicas}} ready
 Updated replicas: {{.UpdatedReplicas}}/{{.Replicas}}
 Available replicas: {{.AvailableReplicas}}/{{.Replicas}}
 Strategy: {{.Strategy}}
-Age: {{.Age}}
-{{if .Message}}Message: {{.Message}}{{end}}
-{{if .Reason}}Reason: {{.Reason}}{{end}}
+Age: {{.Age}}{{if .Message}}
+Message: {{.Message}}{{end}}{{if .Reason}}
+Reason: {{.Reason}}{{end}}
 
 Conditions:
 {{.Conditions}}
@@ -52,6 +59,13 @@ Conditions:
 Events:
 {{.Events}}
 
+{{if .Spec}}
+Pod template spec:
+{{.Spec}}
+{{end}}
+{{if .Elided}}
+Note: {{.Elided}}
+{{end}}
 Please provide:
 1. A diagnosis of the issue
 2. Likely root causes
@@ -66,17 +80,20 @@ As a Kubernetes expert, please analyze this service issue:
 Service: {{.Name}}
 Namespace: {{.Namespace}}
 Type: {{.Type}}
-ClusterIP: {{.ClusterIP}}
-{{if .ExternalIP}}ExternalIP: {{.ExternalIP}}{{end}}
+ClusterIP: {{.ClusterIP}}{{if .ExternalIP}}
+ExternalIP: {{.ExternalIP}}{{end}}
 Selector: {{.Selector}}
 Endpoints: {{.EndpointCount}}
-Age: {{.Age}}
-{{if .Message}}Message: {{.Message}}{{end}}
-{{if .Reason}}Reason: {{.Reason}}{{end}}
+Age: {{.Age}}{{if .Message}}
+Message: {{.Message}}{{end}}{{if .Reason}}
+Reason: {{.Reason}}{{end}}
 
 Events:
 {{.Events}}
 
+{{if .Elided}}
+Note: {{.Elided}}
+{{end}}
 Please provide:
 1. A diagnosis of the issue
 2. Likely root causes
@@ -94,7 +111,9 @@ As a Kubernetes expert, please analyze this error message and explain:
 
 Error message:
 {{.ErrorMessage}}
-`
+{{if .Elided}}
+Note: {{.Elided}}
+{{end}}`
 
 // LogsPromptTemplate is the template for logs analysis
 const LogsPromptTemplate = `
@@ -106,7 +125,9 @@ As a Kubernetes expert, please analyze these logs and explain:
 
 Logs:
 {{.Logs}}
-`
+{{if .Elided}}
+Note: {{.Elided}}
+{{end}}`
 
 // YAMLPromptTemplate is the template for YAML analysis
 const YAMLPromptTemplate = `
@@ -118,7 +139,9 @@ As a Kubernetes expert, please analyze this YAML configuration and explain:
 
 YAML:
 {{.YAML}}
-`
+{{if .Elided}}
+Note: {{.Elided}}
+{{end}}`
 
 // FixPromptTemplate is the template for generating fixes
 const FixPromptTemplate = `
@@ -152,4 +175,22 @@ Please provide:
 2. Key issues that need attention
 3. Recommended actions to improve cluster health
 4. Prioritization of issues (what to fix first)
-`
\ No newline at end of file
+`
+
+// DiagnosisFormatPrompt is appended to analysis, fix and explanation
+// prompts, whatever their template, so that responses parse as a Diagnosis
+const DiagnosisFormatPrompt = `
+Respond with a single JSON object and nothing else, in this format:
+{
+  "summary": "what is wrong, in one or two sentences",
+  "rootCauses": [
+    {"cause": "a likely root cause", "confidence": 0.8}
+  ],
+  "diagnosticCommands": ["read-only commands that confirm the cause, such as kubectl describe or kubectl logs"],
+  "remediationCommands": ["commands that fix the issue"],
+  "patches": [
+    {"description": "what the patch changes", "target": "kind/name of the patched object", "yaml": "the YAML patch or manifest, as a string"}
+  ]
+}
+Confidence is a number between 0 and 1. Diagnostic commands must not change the cluster. Use empty lists for anything you cannot provide.
+`
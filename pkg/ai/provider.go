@@ -0,0 +1,49 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"regexp"
+)
+
+// Provider sends a prompt to a model and returns its response
+type Provider interface {
+	// Name identifies the provider and model, such as "openai/gpt-4o-mini".
+	// Responses are cached under it.
+	Name() string
+	// Complete returns the response to prompt. If stream is not nil, the
+	// response is also written to it as it arrives.
+	Complete(ctx context.Context, prompt string, stream io.Writer) (string, error)
+}
+
+// streamKey is the context key of the stream output
+type streamKey struct{}
+
+// WithStreamOutput returns a context whose AI calls write their response to
+// w as it arrives, for example to show it live in a terminal. Responses
+// served from cache are written at once.
+func WithStreamOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, streamKey{}, w)
+}
+
+// streamOutput returns the stream output of ctx, or nil
+func streamOutput(ctx context.Context) io.Writer {
+	w, _ := ctx.Value(streamKey{}).(io.Writer)
+	return w
+}
+
+// countingWriter counts the bytes written through it, so that a retry can
+// tell whether part of a failed response was already shown
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// unsafeNameChars are replaced in provider names used as directory names
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
@@ -0,0 +1,224 @@
+package ai
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Redactor removes secrets and personal data from prompts before they leave
+// the machine. Each match is replaced by a marker naming its detector, such
+// as [REDACTED:jwt], so that the model still knows something was there.
+type Redactor struct {
+	detectors []detector
+}
+
+// detector finds one kind of sensitive value
+type detector struct {
+	name    string
+	pattern *regexp.Regexp
+	// group is the submatch that is replaced, so that keys and schemes stay
+	// readable; 0 replaces the whole match
+	group int
+	// valid filters matches, for patterns that over-match
+	valid func(string) bool
+}
+
+// RedactPattern is a user-defined detector. If the pattern has capture
+// groups, only the first one is replaced.
+type RedactPattern struct {
+	Name    string
+	Pattern string
+}
+
+// RedactOptions selects the detectors of a Redactor in addition to the
+// built-in ones
+type RedactOptions struct {
+	// IPs redacts IPv4 addresses, which are often needed to diagnose
+	// networking issues and so are kept by default
+	IPs      bool
+	Patterns []RedactPattern
+}
+
+// Redaction counts the values one detector replaced
+type Redaction struct {
+	Detector string
+	Count    int
+}
+
+// builtinDetectors find credentials and personal data that commonly end up
+// in logs, events and manifests
+var builtinDetectors = []detector{
+	{name: "jwt", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]*`)},
+	{name: "authorization", pattern: regexp.MustCompile(`(?i)\b(?:proxy-)?authorization["']?\s*[:=]\s*["']?((?:bearer|basic|token|digest|negotiate)\s+[^\s"',]+|[^\s"',]+)`), group: 1},
+	{name: "bearer-token", pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]{8,}=*)`), group: 1},
+	{name: "aws-access-key", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[A-Z0-9]{16}\b`)},
+	{name: "aws-secret-key", pattern: regexp.MustCompile(`(?i)(?:aws_?secret_?access_?key|secret_?access_?key)["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`), group: 1},
+	{name: "password", pattern: regexp.MustCompile(`(?i)\b[\w.-]*(?:password|passwd|pwd|client_?secret|api_?key|access_?token)["']?\s*[:=]\s*["']?([^\s"',;&}]+)`), group: 1},
+	{name: "url-credentials", pattern: regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@]+:([^\s/@]+)@`), group: 1},
+	{name: "email", pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+}
+
+// ipDetector finds IPv4 addresses
+var ipDetector = detector{
+	name:    "ip",
+	pattern: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),
+	valid:   func(s string) bool { return net.ParseIP(s) != nil },
+}
+
+// NewRedactor creates a redactor with the built-in detectors and those of
+// opts
+func NewRedactor(opts RedactOptions) (*Redactor, error) {
+	detectors := append([]detector(nil), builtinDetectors...)
+	if opts.IPs {
+		detectors = append(detectors, ipDetector)
+	}
+	for _, p := range opts.Patterns {
+		if p.Name == "" {
+			return nil, fmt.Errorf("redaction pattern %q has no name", p.Pattern)
+		}
+		pattern, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %s: %w", p.Name, err)
+		}
+		group := 0
+		if pattern.NumSubexp() > 0 {
+			group = 1
+		}
+		detectors = append(detectors, detector{name: p.Name, pattern: pattern, group: group})
+	}
+	return &Redactor{detectors: detectors}, nil
+}
+
+// DefaultRedactor redacts with the built-in detectors only
+func DefaultRedactor() *Redactor {
+	return &Redactor{detectors: builtinDetectors}
+}
+
+// Redact returns text with sensitive values replaced, and how many values
+// each detector replaced, by detector name. The data of Kubernetes Secrets
+// is redacted first, then the detectors run in order. A nil Redactor
+// returns text unchanged.
+func (r *Redactor) Redact(text string) (string, []Redaction) {
+	if r == nil {
+		return text, nil
+	}
+
+	counts := map[string]int{}
+	text = redactSecretData(text, counts)
+	for _, d := range r.detectors {
+		text = d.redact(text, counts)
+	}
+
+	var redactions []Redaction
+	for name, count := range counts {
+		redactions = append(redactions, Redaction{Detector: name, Count: count})
+	}
+	sort.Slice(redactions, func(i, j int) bool {
+		return redactions[i].Detector < redactions[j].Detector
+	})
+	return text, redactions
+}
+
+// FormatRedactions describes redactions in a line, such as "2 jwt, 1 email"
+func FormatRedactions(redactions []Redaction) string {
+	parts := make([]string, len(redactions))
+	for i, r := range redactions {
+		parts[i] = fmt.Sprintf("%d %s", r.Count, r.Detector)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// redactedMarker replaces a value found by a detector
+func redactedMarker(name string) string {
+	return "[REDACTED:" + name + "]"
+}
+
+// redact replaces the matches of the detector in text
+func (d detector) redact(text string, counts map[string]int) string {
+	matches := d.pattern.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return text
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[2*d.group], m[2*d.group+1]
+		// An optional group that did not take part in the match
+		if start < 0 {
+			continue
+		}
+		value := text[start:end]
+		if strings.Contains(value, "[REDACTED:") || (d.valid != nil && !d.valid(value)) {
+			continue
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(redactedMarker(d.name))
+		last = end
+		counts[d.name]++
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+var (
+	// secretKind finds Secret objects in YAML and JSON
+	secretKind = regexp.MustCompile(`(?m)^\s*kind:\s*["']?Secret["']?\s*$|"kind"\s*:\s*"Secret"`)
+	// yamlDataKey starts the data of a Secret in YAML
+	yamlDataKey = regexp.MustCompile(`^(\s*)(data|stringData):\s*$`)
+	// yamlDataValue is an entry of the data, whose value is replaced
+	yamlDataValue = regexp.MustCompile(`^(\s*[^\s:#][^:]*:\s*)(\S.*)$`)
+	// jsonData is the data object of a Secret in JSON
+	jsonData = regexp.MustCompile(`"(?:data|stringData)"\s*:\s*\{[^{}]*\}`)
+	// jsonDataValue is an entry of the data object
+	jsonDataValue = regexp.MustCompile(`("[^"]*"\s*:\s*)"[^"]*"`)
+)
+
+// redactSecretData replaces the values of the data and stringData of
+// Secrets, which are base64-encoded or plain credentials. Texts that do not
+// mention a Secret are returned unchanged, as the data of ConfigMaps is
+// useful to the model.
+func redactSecretData(text string, counts map[string]int) string {
+	if !secretKind.MatchString(text) {
+		return text
+	}
+	const name = "secret-data"
+
+	text = jsonData.ReplaceAllStringFunc(text, func(data string) string {
+		return jsonDataValue.ReplaceAllStringFunc(data, func(entry string) string {
+			counts[name]++
+			return jsonDataValue.ReplaceAllString(entry, `${1}"`+redactedMarker(name)+`"`)
+		})
+	})
+
+	// In YAML, the entries of a data block are indented below its key, and
+	// the lines of block scalars such as "tls.crt: |" below their entry
+	var out []string
+	dataIndent, entryIndent := -1, -1
+	for _, line := range strings.Split(text, "\n") {
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		blank := strings.TrimSpace(line) == ""
+		if dataIndent >= 0 && !blank && indent <= dataIndent {
+			dataIndent, entryIndent = -1, -1
+		}
+		if dataIndent >= 0 && !blank {
+			if entryIndent >= 0 && indent > entryIndent {
+				continue
+			}
+			if m := yamlDataValue.FindStringSubmatch(line); m != nil {
+				out = append(out, m[1]+redactedMarker(name))
+				counts[name]++
+				entryIndent = indent
+				continue
+			}
+		}
+		if m := yamlDataKey.FindStringSubmatch(line); m != nil {
+			dataIndent, entryIndent = len(m[1]), -1
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
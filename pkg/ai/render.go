@@ -0,0 +1,608 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/k8s"
+)
+
+// Names of the prompts, as accepted by RenderPrompt
+const (
+	PromptPod           = "pod"
+	PromptDeployment    = "deployment"
+	PromptService       = "service"
+	PromptError         = "error"
+	PromptLogs          = "logs"
+	PromptYAML          = "yaml"
+	PromptFix           = "fix"
+	PromptClusterReport = "cluster-report"
+)
+
+// DefaultPrompts are the built-in prompt templates by name
+var DefaultPrompts = map[string]string{
+	PromptPod:           PodIssuePromptTemplate,
+	PromptDeployment:    DeploymentIssuePromptTemplate,
+	PromptService:       ServiceIssuePromptTemplate,
+	PromptError:         ErrorPromptTemplate,
+	PromptLogs:          LogsPromptTemplate,
+	PromptYAML:          YAMLPromptTemplate,
+	PromptFix:           FixPromptTemplate,
+	PromptClusterReport: ClusterReportPromptTemplate,
+}
+
+// promptData are empty values of the data each prompt is rendered with, used
+// to check overrides for fields that do not exist
+var promptData = map[string]interface{}{
+	PromptPod:           PodPromptData{},
+	PromptDeployment:    DeploymentPromptData{},
+	PromptService:       ServicePromptData{},
+	PromptError:         ErrorPromptData{},
+	PromptLogs:          LogsPromptData{},
+	PromptYAML:          YAMLPromptData{},
+	PromptFix:           FixPromptData{},
+	PromptClusterReport: ClusterReportPromptData{},
+}
+
+// defaultPrompts is the prompt set without overrides
+var defaultPrompts = func() *PromptSet {
+	prompts, err := NewPromptSet()
+	if err != nil {
+		panic(err)
+	}
+	return prompts
+}()
+
+// PromptNames returns the names of all prompts, sorted
+func PromptNames() []string {
+	names := make([]string, 0, len(DefaultPrompts))
+	for name := range DefaultPrompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PromptOverride replaces the template of a built-in prompt
+type PromptOverride struct {
+	// Name is the prompt to replace, one of PromptNames
+	Name string
+	// Text is the text/template source, filled with the same data as the
+	// built-in template
+	Text string
+	// Source describes where the override comes from, such as a file path
+	Source string
+}
+
+// PromptSet is the template of every prompt, built-in unless overridden
+type PromptSet struct {
+	templates map[string]*template.Template
+	texts     map[string]string
+	sources   map[string]string
+}
+
+// NewPromptSet parses overrides on top of the built-in prompts. Later
+// overrides of the same prompt take precedence. Each override is checked
+// against the data of its prompt, so that a misspelled field fails here
+// rather than when a finding is analyzed.
+func NewPromptSet(overrides ...PromptOverride) (*PromptSet, error) {
+	prompts := &PromptSet{
+		templates: make(map[string]*template.Template),
+		texts:     make(map[string]string),
+		sources:   make(map[string]string),
+	}
+
+	for name, text := range DefaultPrompts {
+		if err := prompts.set(PromptOverride{Name: name, Text: text, Source: "built-in"}); err != nil {
+			return nil, err
+		}
+	}
+	for _, override := range overrides {
+		if _, ok := DefaultPrompts[override.Name]; !ok {
+			return nil, fmt.Errorf("%s: unknown prompt %q (expected one of %s)", override.Source, override.Name, strings.Join(PromptNames(), ", "))
+		}
+		if err := prompts.set(override); err != nil {
+			return nil, err
+		}
+	}
+	return prompts, nil
+}
+
+// set parses and checks the template of one prompt
+func (s *PromptSet) set(override PromptOverride) error {
+	tmpl, err := template.New(override.Name).Parse(override.Text)
+	if err != nil {
+		return fmt.Errorf("%s: parsing prompt %s: %w", override.Source, override.Name, err)
+	}
+	if err := tmpl.Execute(io.Discard, promptData[override.Name]); err != nil {
+		return fmt.Errorf("%s: checking prompt %s: %w", override.Source, override.Name, err)
+	}
+
+	s.templates[override.Name] = tmpl
+	s.texts[override.Name] = override.Text
+	s.sources[override.Name] = override.Source
+	return nil
+}
+
+// Render fills the template of prompt name with data
+func (s *PromptSet) Render(name string, data interface{}) (string, error) {
+	tmpl, ok := s.templates[name]
+	if !ok {
+		return "", fmt.Errorf("unknown prompt %q", name)
+	}
+	return execute(tmpl, data)
+}
+
+// Text returns the template source of prompt name
+func (s *PromptSet) Text(name string) (string, bool) {
+	text, ok := s.texts[name]
+	return text, ok
+}
+
+// Source describes where the template of prompt name comes from: "built-in"
+// or the source of its override
+func (s *PromptSet) Source(name string) string {
+	return s.sources[name]
+}
+
+// RenderPrompt fills the built-in prompt template name with data
+func RenderPrompt(name string, data interface{}) (string, error) {
+	return defaultPrompts.Render(name, data)
+}
+
+// execute runs a prompt template, dropping the blank lines left by empty
+// optional sections
+func execute(tmpl *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("rendering prompt %s: %w", tmpl.Name(), err)
+	}
+
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.TrimSpace(line) == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// PodPromptData fills PodIssuePromptTemplate
+type PodPromptData struct {
+	Name            string
+	Namespace       string
+	Status          string
+	Age             string
+	Node            string
+	Message         string
+	Reason          string
+	ContainerIssues string
+	Events          string
+	Logs            string
+	Spec            string
+	// Elided notes what Pack shortened, and is empty when nothing was
+	Elided string
+}
+
+// NewPodPromptData describes a pod issue for the pod prompt
+func NewPodPromptData(pod k8s.PodIssue) PodPromptData {
+	return PodPromptData{
+		Name:            pod.Name,
+		Namespace:       pod.Namespace,
+		Status:          pod.Status,
+		Age:             formatAge(pod.Age),
+		Node:            orUnknown(pod.Node),
+		Message:         pod.Message,
+		Reason:          pod.Reason,
+		ContainerIssues: formatContainers(pod.Containers),
+		Events:          formatEvents(pod.Events),
+		Logs:            formatLogs(pod.Logs),
+		Spec:            pod.Spec,
+	}
+}
+
+// Pack shortens the container statuses, events, logs and spec to fit in
+// budget tokens
+func (d PodPromptData) Pack(budget int) PodPromptData {
+	sections, elided := Pack(budget, []Section{
+		{Name: "container status lines", Kind: SectionStatus, Text: d.ContainerIssues},
+		{Name: "event lines", Kind: SectionEvents, Text: d.Events},
+		{Name: "log lines", Kind: SectionLogs, Text: d.Logs},
+		{Name: "spec lines", Kind: SectionSpec, Text: d.Spec},
+	})
+	d.ContainerIssues, d.Events, d.Logs, d.Spec = sections[0].Text, sections[1].Text, sections[2].Text, sections[3].Text
+	d.Elided = elided
+	return d
+}
+
+// DeploymentPromptData fills DeploymentIssuePromptTemplate
+type DeploymentPromptData struct {
+	Name              string
+	Namespace         string
+	Replicas          int
+	ReadyReplicas     int
+	UpdatedReplicas   int
+	AvailableReplicas int
+	Strategy          string
+	Age               string
+	Message           string
+	Reason            string
+	Conditions        string
+	Events            string
+	Spec              string
+	// Elided notes what Pack shortened, and is empty when nothing was
+	Elided string
+}
+
+// NewDeploymentPromptData describes a deployment issue for the deployment
+// prompt
+func NewDeploymentPromptData(deployment k8s.DeploymentIssue) DeploymentPromptData {
+	return DeploymentPromptData{
+		Name:              deployment.Name,
+		Namespace:         deployment.Namespace,
+		Replicas:          deployment.Replicas,
+		ReadyReplicas:     deployment.ReadyReplicas,
+		UpdatedReplicas:   deployment.UpdatedReplicas,
+		AvailableReplicas: deployment.AvailableReplicas,
+		Strategy:          orUnknown(deployment.Strategy),
+		Age:               formatAge(deployment.Age),
+		Message:           deployment.Message,
+		Reason:            deployment.Reason,
+		Conditions:        formatConditions(deployment.Conditions),
+		Events:            formatEvents(deployment.Events),
+		Spec:              deployment.Spec,
+	}
+}
+
+// Pack shortens the conditions, events and spec to fit in budget tokens
+func (d DeploymentPromptData) Pack(budget int) DeploymentPromptData {
+	sections, elided := Pack(budget, []Section{
+		{Name: "condition lines", Kind: SectionStatus, Text: d.Conditions},
+		{Name: "event lines", Kind: SectionEvents, Text: d.Events},
+		{Name: "spec lines", Kind: SectionSpec, Text: d.Spec},
+	})
+	d.Conditions, d.Events, d.Spec = sections[0].Text, sections[1].Text, sections[2].Text
+	d.Elided = elided
+	return d
+}
+
+// ServicePromptData fills ServiceIssuePromptTemplate
+type ServicePromptData struct {
+	Name          string
+	Namespace     string
+	Type          string
+	ClusterIP     string
+	ExternalIP    string
+	Selector      string
+	EndpointCount int
+	Age           string
+	Message       string
+	Reason        string
+	Events        string
+	// Elided notes what Pack shortened, and is empty when nothing was
+	Elided string
+}
+
+// NewServicePromptData describes a service finding, as collected by
+// k8s.Client.GetServiceIssues, for the service prompt
+func NewServicePromptData(service map[string]interface{}) ServicePromptData {
+	field := func(key string) string {
+		value, _ := service[key].(string)
+		return value
+	}
+
+	data := ServicePromptData{
+		Name:      field("name"),
+		Namespace: field("namespace"),
+		Type:      orUnknown(field("type")),
+		ClusterIP: orUnknown(field("clusterIP")),
+		Selector:  formatSelector(service["selector"]),
+		Age:       "unknown",
+		Message:   field("message"),
+		Reason:    field("issue"),
+		Events:    "No events found",
+	}
+
+	switch ips := service["externalIPs"].(type) {
+	case []string:
+		data.ExternalIP = strings.Join(ips, ", ")
+	case []interface{}:
+		for i, ip := range ips {
+			if i > 0 {
+				data.ExternalIP += ", "
+			}
+			data.ExternalIP += fmt.Sprint(ip)
+		}
+	}
+
+	switch count := service["endpoints"].(type) {
+	case int:
+		data.EndpointCount = count
+	case float64:
+		data.EndpointCount = int(count)
+	}
+
+	if created, err := time.Parse(time.RFC3339, field("creationTimestamp")); err == nil {
+		data.Age = formatAge(time.Since(created))
+	}
+	if events, ok := service["events"].([]interface{}); ok {
+		data.Events = formatEvents(events)
+	}
+
+	return data
+}
+
+// Pack shortens the events to fit in budget tokens
+func (d ServicePromptData) Pack(budget int) ServicePromptData {
+	sections, elided := Pack(budget, []Section{
+		{Name: "event lines", Kind: SectionEvents, Text: d.Events},
+	})
+	d.Events = sections[0].Text
+	d.Elided = elided
+	return d
+}
+
+// FixPromptData fills FixPromptTemplate
+type FixPromptData struct {
+	IssueDescription string
+}
+
+// ErrorPromptData fills ErrorPromptTemplate
+type ErrorPromptData struct {
+	ErrorMessage string
+	// Elided notes what Pack shortened, and is empty when nothing was
+	Elided string
+}
+
+// Pack shortens the error message, which is often a pasted log, to fit in
+// budget tokens
+func (d ErrorPromptData) Pack(budget int) ErrorPromptData {
+	sections, elided := Pack(budget, []Section{
+		{Name: "lines", Kind: SectionLogs, Text: d.ErrorMessage},
+	})
+	d.ErrorMessage = sections[0].Text
+	d.Elided = elided
+	return d
+}
+
+// LogsPromptData fills LogsPromptTemplate
+type LogsPromptData struct {
+	Logs string
+	// Elided notes what Pack shortened, and is empty when nothing was
+	Elided string
+}
+
+// Pack shortens the logs to fit in budget tokens
+func (d LogsPromptData) Pack(budget int) LogsPromptData {
+	sections, elided := Pack(budget, []Section{
+		{Name: "log lines", Kind: SectionLogs, Text: d.Logs},
+	})
+	d.Logs = sections[0].Text
+	d.Elided = elided
+	return d
+}
+
+// YAMLPromptData fills YAMLPromptTemplate
+type YAMLPromptData struct {
+	YAML string
+	// Elided notes what Pack shortened, and is empty when nothing was
+	Elided string
+}
+
+// Pack shortens the YAML to fit in budget tokens
+func (d YAMLPromptData) Pack(budget int) YAMLPromptData {
+	sections, elided := Pack(budget, []Section{
+		{Name: "YAML lines", Kind: SectionSpec, Text: d.YAML},
+	})
+	d.YAML = sections[0].Text
+	d.Elided = elided
+	return d
+}
+
+// ClusterReportPromptData fills ClusterReportPromptTemplate
+type ClusterReportPromptData struct {
+	NamespaceCount           int
+	TotalPods                int
+	HealthyPods              int
+	UnhealthyPods            int
+	TotalDeployments         int
+	HealthyDeployments       int
+	MisconfiguredDeployments int
+	FailedEventCount         int
+	// UnhealthyResources lists the unhealthy objects, one per line
+	UnhealthyResources string
+}
+
+// NewPodFixPromptData describes a pod issue for the fix prompt
+func NewPodFixPromptData(data PodPromptData) FixPromptData {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Pod issue: %s in namespace %s\n", data.Name, data.Namespace)
+	fmt.Fprintf(&sb, "Status: %s\n", data.Status)
+	if data.Reason != "" {
+		fmt.Fprintf(&sb, "Reason: %s\n", data.Reason)
+	}
+	if data.Message != "" {
+		fmt.Fprintf(&sb, "Message: %s\n", data.Message)
+	}
+	fmt.Fprintf(&sb, "\nContainer issues:\n%s\n", data.ContainerIssues)
+	fmt.Fprintf(&sb, "\nEvents:\n%s\n", data.Events)
+	if data.Logs != "" {
+		fmt.Fprintf(&sb, "\nContainer logs (most recent):\n%s\n", data.Logs)
+	}
+	if data.Spec != "" {
+		fmt.Fprintf(&sb, "\nContainer spec:\n%s\n", data.Spec)
+	}
+	if data.Elided != "" {
+		fmt.Fprintf(&sb, "\nNote: %s\n", data.Elided)
+	}
+	return FixPromptData{IssueDescription: sb.String()}
+}
+
+// NewDeploymentFixPromptData describes a deployment issue for the fix prompt
+func NewDeploymentFixPromptData(data DeploymentPromptData) FixPromptData {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Deployment issue: %s in namespace %s\n", data.Name, data.Namespace)
+	fmt.Fprintf(&sb, "Replicas: %d/%d ready, %d updated, %d available\n",
+		data.ReadyReplicas, data.Replicas, data.UpdatedReplicas, data.AvailableReplicas)
+	fmt.Fprintf(&sb, "Strategy: %s\n", data.Strategy)
+	if data.Reason != "" {
+		fmt.Fprintf(&sb, "Reason: %s\n", data.Reason)
+	}
+	if data.Message != "" {
+		fmt.Fprintf(&sb, "Message: %s\n", data.Message)
+	}
+	fmt.Fprintf(&sb, "\nConditions:\n%s\n", data.Conditions)
+	fmt.Fprintf(&sb, "\nEvents:\n%s\n", data.Events)
+	if data.Spec != "" {
+		fmt.Fprintf(&sb, "\nPod template spec:\n%s\n", data.Spec)
+	}
+	if data.Elided != "" {
+		fmt.Fprintf(&sb, "\nNote: %s\n", data.Elided)
+	}
+	return FixPromptData{IssueDescription: sb.String()}
+}
+
+// formatAge formats the age of an object, which is zero when unknown
+func formatAge(age time.Duration) string {
+	if age <= 0 {
+		return "unknown"
+	}
+	days := int(age.Hours()) / 24
+	if days > 0 {
+		return fmt.Sprintf("%dd%dh", days, int(age.Hours())%24)
+	}
+	if age < time.Minute {
+		return age.Truncate(time.Second).String()
+	}
+	return strings.TrimSuffix(age.Truncate(time.Minute).String(), "0s")
+}
+
+// orUnknown replaces an empty value with "unknown"
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
+
+// formatContainers lists the containers of a pod, one per line
+func formatContainers(containers []k8s.ContainerIssue) string {
+	if len(containers) == 0 {
+		return "No container statuses reported"
+	}
+
+	var lines []string
+	for _, container := range containers {
+		line := fmt.Sprintf("- %s", container.Name)
+		if container.Image != "" {
+			line += fmt.Sprintf(" (%s)", container.Image)
+		}
+		line += fmt.Sprintf(": %s, ready=%t, restarts=%d", container.Status, container.Ready, container.Restarts)
+		if container.Reason != "" {
+			line += ", reason=" + container.Reason
+		}
+		if container.ExitCode != 0 {
+			line += fmt.Sprintf(", exit code %d", container.ExitCode)
+		}
+		if container.Message != "" {
+			line += ": " + container.Message
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatEvents lists events, one per line
+func formatEvents(events []interface{}) string {
+	var lines []string
+	for _, event := range events {
+		if eventMap, ok := event.(map[string]interface{}); ok {
+			lines = append(lines, "- "+k8s.FormatEvent(eventMap))
+		}
+	}
+	if len(lines) == 0 {
+		return "No events found"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatLogs joins the logs of each container under a header with its name
+func formatLogs(logs map[string]string) string {
+	containers := make([]string, 0, len(logs))
+	for container, text := range logs {
+		if strings.TrimSpace(text) != "" {
+			containers = append(containers, container)
+		}
+	}
+	sort.Strings(containers)
+
+	var sections []string
+	for _, container := range containers {
+		sections = append(sections, fmt.Sprintf("--- %s ---\n%s", container, strings.TrimRight(logs[container], "\n")))
+	}
+	return strings.Join(sections, "\n")
+}
+
+// formatConditions lists deployment conditions, one per line. Conditions are
+// typed loosely, as they come from the collectors or from decoded JSON, so
+// they are normalized through JSON first.
+func formatConditions(conditions interface{}) string {
+	var list []struct {
+		Type    string `json:"type"`
+		Status  string `json:"status"`
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	}
+	if raw, err := json.Marshal(conditions); err == nil {
+		_ = json.Unmarshal(raw, &list)
+	}
+	if len(list) == 0 {
+		return "No conditions reported"
+	}
+
+	var lines []string
+	for _, condition := range list {
+		line := fmt.Sprintf("- %s=%s", condition.Type, condition.Status)
+		if condition.Reason != "" {
+			line += " " + condition.Reason
+		}
+		if condition.Message != "" {
+			line += ": " + condition.Message
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatSelector formats a label selector as key=value pairs
+func formatSelector(selector interface{}) string {
+	var pairs []string
+	switch labels := selector.(type) {
+	case map[string]string:
+		for key, value := range labels {
+			pairs = append(pairs, key+"="+value)
+		}
+	case map[string]interface{}:
+		for key, value := range labels {
+			pairs = append(pairs, fmt.Sprintf("%s=%v", key, value))
+		}
+	}
+	if len(pairs) == 0 {
+		return "none"
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how failed calls to the model are retried. Only
+// retryable failures are retried: timeouts, throttling and server errors.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+	// BaseDelay is the delay before the first retry, doubled for each
+	// further retry up to MaxDelay. Each delay is jittered between half and
+	// all of its value, so that concurrent callers do not retry in step.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Timeout bounds a single attempt; zero leaves it to the caller's context
+	Timeout time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of new clients
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  time.Second,
+	MaxDelay:   30 * time.Second,
+	Timeout:    2 * time.Minute,
+}
+
+// delay returns the jittered delay before retry number retry, counted from
+// zero
+func (p RetryPolicy) delay(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 0; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// StatusError is returned by HTTP providers for a response that is not 2xx
+type StatusError struct {
+	StatusCode int
+	// Body is the start of the response body, for the error message
+	Body string
+	// RetryAfter is the delay asked for by a Retry-After header, if any
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("provider returned %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("provider returned %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// temporaryError marks a failure that is worth retrying, such as a
+// throttling message from the Amazon Q CLI
+type temporaryError struct {
+	err error
+}
+
+func (e *temporaryError) Error() string { return e.err.Error() }
+func (e *temporaryError) Unwrap() error { return e.err }
+
+// errAttemptTimeout is returned when a single attempt exceeds
+// RetryPolicy.Timeout
+var errAttemptTimeout = errors.New("call to the model timed out")
+
+// IsRetryable reports whether a failed call may succeed if it is retried:
+// timeouts, 408, 429 and 5xx responses, and failures marked temporary
+func IsRetryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		code := statusErr.StatusCode
+		return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+	}
+
+	var temporary *temporaryError
+	if errors.As(err, &temporary) || errors.Is(err, errAttemptTimeout) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryAfter returns the delay a provider asked for, if any
+func retryAfter(err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+	return 0
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
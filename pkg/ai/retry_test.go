@@ -0,0 +1,296 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// scriptedProvider answers each call with the next of its outcomes, writing
+// partial to the stream first
+type scriptedProvider struct {
+	outcomes []error
+	partial  string
+	calls    int
+}
+
+func (p *scriptedProvider) Name() string {
+	return "scripted"
+}
+
+func (p *scriptedProvider) Complete(ctx context.Context, prompt string, stream io.Writer) (string, error) {
+	outcome := p.outcomes[len(p.outcomes)-1]
+	if p.calls < len(p.outcomes) {
+		outcome = p.outcomes[p.calls]
+	}
+	p.calls++
+
+	if stream != nil && p.partial != "" {
+		io.WriteString(stream, p.partial)
+	}
+	if outcome != nil {
+		return "", outcome
+	}
+	return "answer", nil
+}
+
+// timeoutError is a net.Error that timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"throttled", &StatusError{StatusCode: 429}, true},
+		{"request timeout", &StatusError{StatusCode: 408}, true},
+		{"server error", fmt.Errorf("calling model: %w", &StatusError{StatusCode: 503}), true},
+		{"bad request", &StatusError{StatusCode: 400}, false},
+		{"unauthorized", &StatusError{StatusCode: 401}, false},
+		{"temporary CLI failure", &temporaryError{errors.New("ThrottlingException")}, true},
+		{"attempt timeout", fmt.Errorf("%w after 2m", errAttemptTimeout), true},
+		{"network timeout", timeoutError{}, true},
+		{"canceled", context.Canceled, false},
+		{"other", errors.New("amazon-q not found"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	tests := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{3, 5 * time.Second},
+		{10, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.retry), func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				if got := policy.delay(tt.retry); got < tt.max/2 || got > tt.max {
+					t.Fatalf("delay(%d) = %s, want between %s and %s", tt.retry, got, tt.max/2, tt.max)
+				}
+			}
+		})
+	}
+
+	if got := (RetryPolicy{}).delay(3); got != 0 {
+		t.Errorf("delay without a base delay = %s, want 0", got)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	failure := errors.New("provider returned 503")
+
+	// step is either an outcome to record or a check of Allow, optionally
+	// after waiting
+	type step struct {
+		wait    time.Duration
+		record  error
+		cancel  bool
+		allowed *bool
+	}
+	allowed, refused := true, false
+	allow := func(want *bool) step { return step{allowed: want} }
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name:  "stays closed below the threshold",
+			steps: []step{{record: failure}, allow(&allowed), {record: nil}, {record: failure}, allow(&allowed)},
+		},
+		{
+			name:  "opens after consecutive failures",
+			steps: []step{{record: failure}, {record: failure}, allow(&refused)},
+		},
+		{
+			name: "a successful trial closes it",
+			steps: []step{
+				{record: failure}, {record: failure}, allow(&refused),
+				{wait: 30 * time.Millisecond, allowed: &allowed}, allow(&refused),
+				{record: nil}, allow(&allowed), allow(&allowed),
+			},
+		},
+		{
+			name: "a failed trial opens it again",
+			steps: []step{
+				{record: failure}, {record: failure},
+				{wait: 30 * time.Millisecond, allowed: &allowed}, {record: failure}, allow(&refused),
+			},
+		},
+		{
+			name: "a cancelled trial lets the next call try",
+			steps: []step{
+				{record: failure}, {record: failure},
+				{wait: 30 * time.Millisecond, allowed: &allowed}, {cancel: true}, allow(&allowed),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := NewCircuitBreaker(2, 20*time.Millisecond)
+			for i, s := range tt.steps {
+				time.Sleep(s.wait)
+				switch {
+				case s.allowed != nil:
+					err := breaker.Allow()
+					if (err == nil) != *s.allowed {
+						t.Fatalf("step %d: Allow() = %v, want allowed %v", i+1, err, *s.allowed)
+					}
+					if err != nil && !errors.Is(err, ErrCircuitOpen) {
+						t.Fatalf("step %d: Allow() = %v, want ErrCircuitOpen", i+1, err)
+					}
+				case s.cancel:
+					breaker.Cancel()
+				default:
+					breaker.Record(s.record)
+				}
+			}
+		})
+	}
+
+	disabled := NewCircuitBreaker(0, time.Minute)
+	disabled.Record(failure)
+	if err := disabled.Allow(); err != nil {
+		t.Errorf("disabled breaker Allow() = %v, want nil", err)
+	}
+}
+
+func TestCallWithRetry(t *testing.T) {
+	t.Setenv("KUBEGPT_MOCK_AI", "")
+	throttled := &StatusError{StatusCode: 429}
+	invalid := &StatusError{StatusCode: 400}
+
+	tests := []struct {
+		name       string
+		outcomes   []error
+		partial    string
+		maxRetries int
+		threshold  int
+		wantErr    error
+		wantCalls  int
+		wantStream string
+	}{
+		{name: "succeeds at once", outcomes: []error{nil}, maxRetries: 3, wantCalls: 1},
+		{name: "retries retryable failures", outcomes: []error{throttled, throttled, nil}, maxRetries: 3, wantCalls: 3},
+		{name: "gives up after the retries", outcomes: []error{throttled}, maxRetries: 2, wantErr: throttled, wantCalls: 3},
+		{name: "does not retry other failures", outcomes: []error{invalid, nil}, maxRetries: 3, wantErr: invalid, wantCalls: 1},
+		{name: "stops once the breaker opens", outcomes: []error{throttled}, maxRetries: 5, threshold: 2, wantErr: ErrCircuitOpen, wantCalls: 2},
+		{
+			name:       "marks a stream that is started over",
+			outcomes:   []error{throttled, nil},
+			partial:    "The pod",
+			maxRetries: 1,
+			wantCalls:  2,
+			wantStream: "The pod\n[response interrupted, retrying]\nThe pod",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &scriptedProvider{outcomes: tt.outcomes, partial: tt.partial}
+			client := NewAmazonQClient()
+			client.SetProvider(provider)
+			client.SetRetryPolicy(RetryPolicy{MaxRetries: tt.maxRetries, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+			client.SetCircuitBreaker(NewCircuitBreaker(tt.threshold, time.Minute))
+
+			var stream bytes.Buffer
+			ctx := WithStreamOutput(context.Background(), &stream)
+			response, err := client.callWithRetry(ctx, "analysis", "prompt")
+
+			if tt.wantErr == nil && (err != nil || response != "answer") {
+				t.Errorf("callWithRetry() = %q, %v, want the answer", response, err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("callWithRetry() error = %v, want %v", err, tt.wantErr)
+			}
+			if provider.calls != tt.wantCalls {
+				t.Errorf("provider called %d times, want %d", provider.calls, tt.wantCalls)
+			}
+			if tt.wantStream != "" && stream.String() != tt.wantStream {
+				t.Errorf("stream = %q, want %q", stream.String(), tt.wantStream)
+			}
+		})
+	}
+}
+
+func TestCallWithRetryAfter(t *testing.T) {
+	t.Setenv("KUBEGPT_MOCK_AI", "")
+	unavailable := &StatusError{StatusCode: 503, RetryAfter: time.Hour}
+
+	tests := []struct {
+		name      string
+		maxDelay  time.Duration
+		timeout   time.Duration
+		wantCalls int
+	}{
+		{
+			name:      "waits at most the longest backoff",
+			maxDelay:  10 * time.Millisecond,
+			wantCalls: 3,
+		},
+		{
+			name:      "gives up when the wait would pass the deadline",
+			maxDelay:  2 * time.Second,
+			timeout:   time.Second,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &scriptedProvider{outcomes: []error{unavailable}}
+			client := NewAmazonQClient()
+			client.SetProvider(provider)
+			client.SetRetryPolicy(RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: tt.maxDelay})
+			client.SetCircuitBreaker(NewCircuitBreaker(0, time.Minute))
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			_, err := client.callWithRetry(ctx, "analysis", "prompt")
+			if !errors.Is(err, unavailable) {
+				t.Errorf("callWithRetry() error = %v, want the provider's", err)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("callWithRetry() took %s, want it not to wait for Retry-After", elapsed)
+			}
+			if provider.calls != tt.wantCalls {
+				t.Errorf("provider called %d times, want %d", provider.calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/k8s"
+)
+
+// The rule diagnoses below are used in place of the model when it cannot be
+// reached. They only know the common failure reasons, but they are always
+// available and their commands are always read-only.
+
+// reasonRule describes a failure reason of a container or pod
+type reasonRule struct {
+	// summary is formatted with the container or pod it is about
+	summary string
+	// pod is set for reasons of the pod rather than of a container
+	pod    bool
+	causes []string
+	// commands are extra diagnostic commands, formatted with the pod, its
+	// namespace and the container. Commands of a container are skipped when
+	// the reason is the pod's.
+	commands []string
+}
+
+// podRules are the rules of known container and pod reasons
+var podRules = map[string]reasonRule{
+	"CrashLoopBackOff": {
+		summary: "%s keeps exiting shortly after it starts, and Kubernetes is backing off before restarting it again.",
+		causes: []string{
+			"The application fails at startup, for example because of missing configuration or an unreachable dependency",
+			"The container command or arguments are wrong",
+			"A liveness probe kills the container before it is ready",
+		},
+		commands: []string{"kubectl logs %[1]s -n %[2]s -c %[3]s --previous"},
+	},
+	"Error": {
+		summary: "%s exited with an error.",
+		causes: []string{
+			"The application failed, see the logs of the previous run",
+			"The container command or arguments are wrong",
+		},
+		commands: []string{"kubectl logs %[1]s -n %[2]s -c %[3]s --previous"},
+	},
+	"OOMKilled": {
+		summary: "%s was killed because it used more memory than its limit.",
+		causes: []string{
+			"The memory limit is too low for the workload",
+			"The application leaks memory or its heap is sized above the limit",
+		},
+		commands: []string{"kubectl top pod %[1]s -n %[2]s --containers"},
+	},
+	"ImagePullBackOff": {
+		summary: "%s cannot pull its image, and Kubernetes is backing off before trying again.",
+		causes: []string{
+			"The image name or tag does not exist",
+			"The registry needs credentials that the pod has no imagePullSecret for",
+			"The node cannot reach the registry",
+		},
+	},
+	"ErrImagePull": {
+		summary: "%s cannot pull its image.",
+		causes: []string{
+			"The image name or tag does not exist",
+			"The registry needs credentials that the pod has no imagePullSecret for",
+			"The node cannot reach the registry",
+		},
+	},
+	"InvalidImageName": {
+		summary: "%s has an image name that is not a valid image reference.",
+		causes:  []string{"The image field has a typo or an unexpanded variable"},
+	},
+	"CreateContainerConfigError": {
+		summary: "%s cannot be created because its configuration refers to something that does not exist.",
+		causes: []string{
+			"A ConfigMap or Secret used for environment variables is missing",
+			"A key referenced in a ConfigMap or Secret is missing",
+		},
+		commands: []string{
+			"kubectl get configmaps -n %[2]s",
+			"kubectl get secrets -n %[2]s",
+		},
+	},
+	"CreateContainerError": {
+		summary: "%s could not be created by the container runtime.",
+		causes: []string{
+			"The command of the container does not exist in the image",
+			"A volume mount cannot be set up",
+		},
+	},
+	"ContainerCreating": {
+		summary: "%s has not started yet.",
+		causes: []string{
+			"A volume cannot be mounted, for example a missing PersistentVolumeClaim, ConfigMap or Secret",
+			"The image is still being pulled",
+		},
+	},
+	"Pending": {
+		pod:     true,
+		summary: "%s has not been scheduled or started.",
+		causes: []string{
+			"No node has enough CPU or memory for the pod's requests",
+			"Node selectors, affinity or taints exclude every node",
+			"A PersistentVolumeClaim of the pod is not bound",
+		},
+		commands: []string{"kubectl get nodes", "kubectl get pvc -n %[2]s"},
+	},
+	"Unschedulable": {
+		pod:     true,
+		summary: "%s cannot be scheduled on any node.",
+		causes: []string{
+			"No node has enough CPU or memory for the pod's requests",
+			"Node selectors, affinity or taints exclude every node",
+		},
+		commands: []string{"kubectl get nodes", "kubectl describe nodes"},
+	},
+	"Evicted": {
+		pod:     true,
+		summary: "%s was evicted from its node.",
+		causes: []string{
+			"The node ran low on memory or disk",
+			"The pod used more ephemeral storage than its limit",
+		},
+		commands: []string{"kubectl get nodes"},
+	},
+}
+
+// PodRuleDiagnosis diagnoses a pod from the reason of its first failing
+// container, or of the pod itself, without the model
+func PodRuleDiagnosis(pod k8s.PodIssue) *Diagnosis {
+	container, reason, message := "", pod.Reason, pod.Message
+	for _, c := range pod.Containers {
+		if c.Reason != "" {
+			container, reason, message = c.Name, c.Reason, c.Message
+			break
+		}
+	}
+	if reason == "" {
+		reason = pod.Status
+	}
+
+	diagnosis := &Diagnosis{
+		DiagnosticCommands: []string{
+			fmt.Sprintf("kubectl describe pod %s -n %s", pod.Name, pod.Namespace),
+			fmt.Sprintf("kubectl get events -n %s --field-selector involvedObject.name=%s", pod.Namespace, pod.Name),
+		},
+		Structured: true,
+	}
+
+	rule, ok := podRules[reason]
+	subject := "Pod " + pod.Name
+	if container != "" && !rule.pod {
+		subject = "Container " + container
+	}
+	switch {
+	case ok:
+		diagnosis.Summary = fmt.Sprintf(rule.summary, subject)
+		for _, cause := range rule.causes {
+			diagnosis.RootCauses = append(diagnosis.RootCauses, RootCause{Cause: cause})
+		}
+		for _, command := range rule.commands {
+			if container == "" && strings.Contains(command, "%[3]s") {
+				continue
+			}
+			diagnosis.DiagnosticCommands = appendNew(diagnosis.DiagnosticCommands, fmt.Sprintf(command, pod.Name, pod.Namespace, container))
+		}
+	case reason != "":
+		diagnosis.Summary = fmt.Sprintf("Pod %s is %s.", pod.Name, reason)
+	default:
+		diagnosis.Summary = fmt.Sprintf("Pod %s is not healthy.", pod.Name)
+	}
+	if message != "" {
+		diagnosis.Summary += " Kubernetes reports: " + message
+	}
+	if container != "" && len(pod.Logs) > 0 {
+		diagnosis.DiagnosticCommands = appendNew(diagnosis.DiagnosticCommands, fmt.Sprintf("kubectl logs %s -n %s -c %s", pod.Name, pod.Namespace, container))
+	}
+	return diagnosis
+}
+
+// DeploymentRuleDiagnosis diagnoses a deployment from its replica counts
+// and conditions, without the model
+func DeploymentRuleDiagnosis(deployment k8s.DeploymentIssue) *Diagnosis {
+	selector := fmt.Sprintf("kubectl get pods -n %s", deployment.Namespace)
+	if app, ok := deployment.Labels["app"]; ok {
+		selector += " -l app=" + app
+	}
+	diagnosis := &Diagnosis{
+		Summary: fmt.Sprintf("Deployment %s has %d of %d replicas ready.", deployment.Name, deployment.ReadyReplicas, deployment.Replicas),
+		DiagnosticCommands: []string{
+			fmt.Sprintf("kubectl describe deployment %s -n %s", deployment.Name, deployment.Namespace),
+			fmt.Sprintf("kubectl rollout status deployment/%s -n %s", deployment.Name, deployment.Namespace),
+			selector,
+		},
+		Structured: true,
+	}
+	if deployment.Message != "" {
+		diagnosis.Summary += " Kubernetes reports: " + deployment.Message
+	}
+
+	switch {
+	case deployment.Reason == "ProgressDeadlineExceeded":
+		diagnosis.RootCauses = []RootCause{
+			{Cause: "The new pods of the rollout do not become ready, see the diagnosis of its pods"},
+			{Cause: "The readiness probe fails for the new version"},
+		}
+	case strings.Contains(deployment.Message, "exceeded quota") || strings.Contains(deployment.Message, "forbidden"):
+		diagnosis.RootCauses = []RootCause{{Cause: "A resource quota or admission policy rejects the pods of the deployment"}}
+		diagnosis.DiagnosticCommands = append(diagnosis.DiagnosticCommands, fmt.Sprintf("kubectl describe resourcequota -n %s", deployment.Namespace))
+	case deployment.UpdatedReplicas < deployment.Replicas:
+		diagnosis.RootCauses = []RootCause{{Cause: "A rollout is in progress or stuck, with pods of the new version not ready"}}
+	default:
+		diagnosis.RootCauses = []RootCause{
+			{Cause: "Pods of the deployment are failing, see the diagnosis of its pods"},
+			{Cause: "Pods cannot be scheduled for lack of resources"},
+		}
+	}
+	return diagnosis
+}
+
+// ServiceRuleDiagnosis diagnoses a service finding, as collected by
+// k8s.Client.GetServiceIssues, without the model
+func ServiceRuleDiagnosis(service map[string]interface{}) *Diagnosis {
+	data := NewServicePromptData(service)
+	diagnosis := &Diagnosis{
+		Summary: fmt.Sprintf("Service %s: %s.", data.Name, orUnknown(data.Reason)),
+		DiagnosticCommands: []string{
+			fmt.Sprintf("kubectl describe service %s -n %s", data.Name, data.Namespace),
+			fmt.Sprintf("kubectl get endpoints %s -n %s", data.Name, data.Namespace),
+		},
+		Structured: true,
+	}
+	if data.Reason == "No endpoints available" {
+		diagnosis.RootCauses = []RootCause{
+			{Cause: "No pod matches the selector of the service"},
+			{Cause: "The matching pods are not ready"},
+		}
+		if selector, ok := service["selector"].(map[string]string); ok && len(selector) > 0 {
+			var labels []string
+			for key, value := range selector {
+				labels = append(labels, key+"="+value)
+			}
+			sort.Strings(labels)
+			diagnosis.DiagnosticCommands = append(diagnosis.DiagnosticCommands,
+				fmt.Sprintf("kubectl get pods -n %s -l %s", data.Namespace, strings.Join(labels, ",")))
+		}
+	}
+	return diagnosis
+}
+
+// errorRules match error messages that the rules can explain
+var errorRules = []struct {
+	pattern *regexp.Regexp
+	reason  string
+}{
+	{regexp.MustCompile(`\bCrashLoopBackOff\b`), "CrashLoopBackOff"},
+	{regexp.MustCompile(`\bOOMKilled\b`), "OOMKilled"},
+	{regexp.MustCompile(`\b(ImagePullBackOff|ErrImagePull)\b`), "ImagePullBackOff"},
+	{regexp.MustCompile(`\bInvalidImageName\b`), "InvalidImageName"},
+	{regexp.MustCompile(`\bCreateContainerConfigError\b`), "CreateContainerConfigError"},
+	{regexp.MustCompile(`\bFailedScheduling\b|didn't match|Insufficient (cpu|memory)`), "Unschedulable"},
+	{regexp.MustCompile(`\bEvicted\b`), "Evicted"},
+}
+
+// ErrorRuleDiagnosis explains an error message without the model, or
+// returns nil if no rule matches it
+func ErrorRuleDiagnosis(errorMsg string) *Diagnosis {
+	for _, rule := range errorRules {
+		if !rule.pattern.MatchString(errorMsg) {
+			continue
+		}
+		reason := podRules[rule.reason]
+		subject := "The container"
+		if reason.pod {
+			subject = "The pod"
+		}
+		diagnosis := &Diagnosis{
+			Summary:    fmt.Sprintf(reason.summary, subject),
+			Structured: true,
+		}
+		for _, cause := range reason.causes {
+			diagnosis.RootCauses = append(diagnosis.RootCauses, RootCause{Cause: cause})
+		}
+		diagnosis.DiagnosticCommands = []string{"kubectl get events -A --field-selector type=Warning"}
+		return diagnosis
+	}
+	return nil
+}
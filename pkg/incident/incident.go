@@ -0,0 +1,267 @@
+package incident
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// Incident is a finding as it is reported to an incident tool
+type Incident struct {
+	// DedupKey identifies the incident across runs, so that triggering it
+	// again updates the existing incident instead of opening a new one
+	DedupKey  string
+	Summary   string
+	Severity  output.Severity
+	Namespace string
+	Object    string
+	Reason    string
+	Finding   output.Finding
+}
+
+// Provider opens and resolves incidents in an incident tool
+type Provider interface {
+	// Name identifies the provider in the state file and in error messages
+	Name() string
+	// Trigger opens an incident, or updates it if one with the same dedup key is open
+	Trigger(ctx context.Context, incident Incident) error
+	// Resolve resolves the incident with the given dedup key
+	Resolve(ctx context.Context, dedupKey string) error
+}
+
+// DedupKey derives the dedup key of a finding from its fingerprint
+func DedupKey(finding output.Finding) string {
+	return "kubegpt-" + finding.Fingerprint
+}
+
+// NewIncident builds the incident for a finding
+func NewIncident(finding output.Finding) Incident {
+	summary := fmt.Sprintf("%s: %s", finding.Object(), finding.Reason)
+	if finding.Message != "" {
+		summary += " - " + finding.Message
+	}
+
+	return Incident{
+		DedupKey:  DedupKey(finding),
+		Summary:   summary,
+		Severity:  finding.Severity,
+		Namespace: finding.Namespace,
+		Object:    finding.Object(),
+		Reason:    finding.Reason,
+		Finding:   finding,
+	}
+}
+
+// Record is an open incident in the state file
+type Record struct {
+	DedupKey  string    `json:"dedupKey"`
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace"`
+	Object    string    `json:"object"`
+	Reason    string    `json:"reason"`
+	OpenedAt  time.Time `json:"openedAt"`
+	// Providers lists the providers that accepted the trigger, so a failed
+	// provider is retried on the next run and only these are resolved
+	Providers []string `json:"providers"`
+}
+
+// state is the content of the state file
+type state struct {
+	Incidents map[string]*Record `json:"incidents"`
+}
+
+// Scope describes what a run looked at. Open incidents are only resolved if
+// the run covered their namespace and kind; otherwise their absence says
+// nothing about the cluster.
+type Scope struct {
+	Namespace string
+	Kinds     map[string]bool
+}
+
+// Covers reports whether the scope covers an open incident
+func (s Scope) Covers(record *Record) bool {
+	return record.Namespace == s.Namespace && s.Kinds[record.Kind]
+}
+
+// Result summarizes a sync
+type Result struct {
+	Triggered []string
+	Resolved  []string
+}
+
+// Manager triggers incidents for findings at or above a minimum severity and
+// resolves them once a later run no longer sees the finding. Open incidents
+// are kept in a state file so that resolution works across separate runs.
+type Manager struct {
+	providers   []Provider
+	minSeverity output.Severity
+	statePath   string
+	now         func() time.Time
+}
+
+// NewManager creates a manager. minSeverity defaults to critical.
+func NewManager(providers []Provider, minSeverity output.Severity, statePath string) *Manager {
+	if minSeverity == "" {
+		minSeverity = output.SeverityCritical
+	}
+	return &Manager{
+		providers:   providers,
+		minSeverity: minSeverity,
+		statePath:   statePath,
+		now:         time.Now,
+	}
+}
+
+// DefaultStatePath returns the default location of the state file
+func DefaultStatePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kubegpt", "incidents.json"), nil
+}
+
+// Sync triggers incidents for new findings at or above the minimum severity
+// and resolves open incidents within scope whose finding is gone. Provider
+// failures do not stop the sync; they are returned and retried next time.
+func (m *Manager) Sync(ctx context.Context, findings []output.Finding, scope Scope) (Result, []error) {
+	var result Result
+	var errs []error
+
+	current, err := m.load()
+	if err != nil {
+		return result, []error{err}
+	}
+
+	seen := make(map[string]bool)
+	for _, finding := range findings {
+		if finding.Severity.Rank() < m.minSeverity.Rank() {
+			continue
+		}
+
+		incident := NewIncident(finding)
+		seen[incident.DedupKey] = true
+
+		record, ok := current.Incidents[incident.DedupKey]
+		if !ok {
+			record = &Record{
+				DedupKey:  incident.DedupKey,
+				Kind:      finding.Kind,
+				Namespace: finding.Namespace,
+				Object:    incident.Object,
+				Reason:    finding.Reason,
+				OpenedAt:  m.now(),
+			}
+		}
+
+		triggered := false
+		for _, provider := range m.providers {
+			if contains(record.Providers, provider.Name()) {
+				continue
+			}
+			if err := provider.Trigger(ctx, incident); err != nil {
+				errs = append(errs, fmt.Errorf("triggering %s incident for %s: %w", provider.Name(), incident.Object, err))
+				continue
+			}
+			record.Providers = append(record.Providers, provider.Name())
+			triggered = true
+		}
+
+		if len(record.Providers) > 0 {
+			current.Incidents[incident.DedupKey] = record
+		}
+		if triggered {
+			result.Triggered = append(result.Triggered, incident.Object)
+		}
+	}
+
+	for dedupKey, record := range current.Incidents {
+		if seen[dedupKey] || !scope.Covers(record) {
+			continue
+		}
+
+		var remaining []string
+		for _, provider := range m.providers {
+			if !contains(record.Providers, provider.Name()) {
+				continue
+			}
+			if err := provider.Resolve(ctx, dedupKey); err != nil {
+				errs = append(errs, fmt.Errorf("resolving %s incident for %s: %w", provider.Name(), record.Object, err))
+				remaining = append(remaining, provider.Name())
+			}
+		}
+
+		if len(remaining) == 0 {
+			delete(current.Incidents, dedupKey)
+			result.Resolved = append(result.Resolved, record.Object)
+		} else {
+			record.Providers = remaining
+		}
+	}
+
+	sort.Strings(result.Triggered)
+	sort.Strings(result.Resolved)
+
+	if err := m.save(current); err != nil {
+		errs = append(errs, err)
+	}
+	return result, errs
+}
+
+// load reads the state file. A missing file is an empty state.
+func (m *Manager) load() (*state, error) {
+	current := &state{Incidents: make(map[string]*Record)}
+
+	data, err := os.ReadFile(m.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return current, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading incident state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, current); err != nil {
+		return nil, fmt.Errorf("parsing incident state %s: %w", m.statePath, err)
+	}
+	if current.Incidents == nil {
+		current.Incidents = make(map[string]*Record)
+	}
+	return current, nil
+}
+
+// save writes the state file atomically
+func (m *Manager) save(current *state) error {
+	data, err := json.MarshalIndent(current, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.statePath), 0700); err != nil {
+		return fmt.Errorf("writing incident state: %w", err)
+	}
+	tmp := m.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing incident state: %w", err)
+	}
+	if err := os.Rename(tmp, m.statePath); err != nil {
+		return fmt.Errorf("writing incident state: %w", err)
+	}
+	return nil
+}
+
+// contains reports whether list contains value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
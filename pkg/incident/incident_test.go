@@ -0,0 +1,187 @@
+package incident
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// fakeProvider records the calls it gets and fails while err is set
+type fakeProvider struct {
+	name  string
+	calls []string
+	err   error
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) Trigger(ctx context.Context, incident Incident) error {
+	p.calls = append(p.calls, "trigger "+incident.DedupKey)
+	return p.err
+}
+
+func (p *fakeProvider) Resolve(ctx context.Context, dedupKey string) error {
+	p.calls = append(p.calls, "resolve "+dedupKey)
+	return p.err
+}
+
+func finding(kind, name string, severity output.Severity) output.Finding {
+	return output.Finding{
+		Fingerprint: strings.ToLower(kind) + "-" + name,
+		Kind:        kind,
+		Namespace:   "default",
+		Name:        name,
+		Severity:    severity,
+		Reason:      "CrashLoopBackOff",
+	}
+}
+
+func TestManagerSync(t *testing.T) {
+	web := finding("Pod", "web", output.SeverityCritical)
+	worker := finding("Pod", "worker", output.SeverityWarning)
+	api := finding("Deployment", "api", output.SeverityCritical)
+	all := Scope{Namespace: "default", Kinds: map[string]bool{"Pod": true, "Deployment": true}}
+	podsOnly := Scope{Namespace: "default", Kinds: map[string]bool{"Pod": true}}
+	otherNamespace := Scope{Namespace: "prod", Kinds: map[string]bool{"Pod": true, "Deployment": true}}
+
+	// run is one sync and the provider calls it must make
+	type run struct {
+		findings []output.Finding
+		scope    Scope
+		fail     bool
+		want     []string
+	}
+
+	tests := []struct {
+		name string
+		runs []run
+	}{
+		{
+			name: "triggers critical findings once",
+			runs: []run{
+				{findings: []output.Finding{web, worker}, scope: all, want: []string{"trigger kubegpt-pod-web"}},
+				{findings: []output.Finding{web, worker}, scope: all},
+			},
+		},
+		{
+			name: "resolves findings that are gone",
+			runs: []run{
+				{findings: []output.Finding{web}, scope: all, want: []string{"trigger kubegpt-pod-web"}},
+				{scope: all, want: []string{"resolve kubegpt-pod-web"}},
+				{scope: all},
+			},
+		},
+		{
+			name: "keeps incidents outside the scope open",
+			runs: []run{
+				{findings: []output.Finding{web, api}, scope: all, want: []string{"trigger kubegpt-pod-web", "trigger kubegpt-deployment-api"}},
+				{scope: podsOnly, want: []string{"resolve kubegpt-pod-web"}},
+				{scope: otherNamespace},
+				{scope: all, want: []string{"resolve kubegpt-deployment-api"}},
+			},
+		},
+		{
+			name: "retries failed triggers and resolves",
+			runs: []run{
+				{findings: []output.Finding{web}, scope: all, fail: true, want: []string{"trigger kubegpt-pod-web"}},
+				{findings: []output.Finding{web}, scope: all, want: []string{"trigger kubegpt-pod-web"}},
+				{scope: all, fail: true, want: []string{"resolve kubegpt-pod-web"}},
+				{scope: all, want: []string{"resolve kubegpt-pod-web"}},
+				{scope: all},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{name: "fake"}
+			manager := NewManager([]Provider{provider}, "", filepath.Join(t.TempDir(), "incidents.json"))
+
+			for i, r := range tt.runs {
+				provider.calls = nil
+				provider.err = nil
+				if r.fail {
+					provider.err = errors.New("unavailable")
+				}
+
+				_, errs := manager.Sync(context.Background(), r.findings, r.scope)
+				if r.fail != (len(errs) > 0) {
+					t.Errorf("run %d: errors = %v, want failures %v", i+1, errs, r.fail)
+				}
+				if !reflect.DeepEqual(provider.calls, r.want) {
+					t.Errorf("run %d: calls = %q, want %q", i+1, provider.calls, r.want)
+				}
+			}
+		})
+	}
+}
+
+func TestManagerSyncMinSeverity(t *testing.T) {
+	provider := &fakeProvider{name: "fake"}
+	manager := NewManager([]Provider{provider}, output.SeverityWarning, filepath.Join(t.TempDir(), "incidents.json"))
+
+	findings := []output.Finding{
+		finding("Pod", "web", output.SeverityCritical),
+		finding("Pod", "worker", output.SeverityWarning),
+		finding("Pod", "job", output.SeverityInfo),
+	}
+	result, errs := manager.Sync(context.Background(), findings, Scope{Namespace: "default", Kinds: map[string]bool{"Pod": true}})
+	if len(errs) > 0 {
+		t.Fatalf("Sync: %v", errs)
+	}
+	if want := []string{"Pod default/web", "Pod default/worker"}; !reflect.DeepEqual(result.Triggered, want) {
+		t.Errorf("triggered %q, want %q", result.Triggered, want)
+	}
+}
+
+func TestPagerDutyTrigger(t *testing.T) {
+	var event pagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	web := finding("Pod", "web", output.SeverityCritical)
+	web.Message = strings.Repeat("コンテナが再起動を繰り返しています。", 100)
+
+	provider := &PagerDuty{RoutingKey: "routing-key", URL: server.URL}
+	if err := provider.Trigger(context.Background(), NewIncident(web)); err != nil {
+		t.Fatalf("Trigger: %v", err)
+	}
+
+	if event.RoutingKey != "routing-key" || event.EventAction != "trigger" || event.DedupKey != "kubegpt-pod-web" {
+		t.Errorf("event = %+v, want a trigger for kubegpt-pod-web", event)
+	}
+	summary := event.Payload.Summary
+	if utf8.RuneCountInString(summary) != 1024 || !utf8.ValidString(summary) || !strings.HasSuffix(summary, "...") {
+		t.Errorf("summary has %d characters (valid UTF-8 %v), want 1024 ending in an ellipsis", utf8.RuneCountInString(summary), utf8.ValidString(summary))
+	}
+	if event.Payload.Severity != "critical" || event.Payload.Source != "kubegpt/default" {
+		t.Errorf("payload = %+v, want a critical event from kubegpt/default", event.Payload)
+	}
+}
+
+func TestPagerDutyErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"status":"invalid event","message":"Event object is invalid"}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	provider := &PagerDuty{RoutingKey: "routing-key", URL: server.URL}
+	err := provider.Resolve(context.Background(), "kubegpt-pod-web")
+	if err == nil || !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "Event object is invalid") {
+		t.Errorf("Resolve() error = %v, want the status and body", err)
+	}
+}
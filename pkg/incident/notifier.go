@@ -0,0 +1,46 @@
+package incident
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// Notifier delivers notifications to an incident provider, so that routes
+// can page for the findings they select. Every finding of a notification
+// triggers an incident, or updates the open one; resolved notifications
+// resolve it. Analyses are not forwarded.
+//
+// Unlike the Manager, a Notifier keeps no state, so incidents it triggers
+// from diagnose or report runs are only resolved by watch mode or by hand.
+type Notifier struct {
+	Provider Provider
+}
+
+// Name identifies the notifier in error messages
+func (n *Notifier) Name() string {
+	return n.Provider.Name()
+}
+
+// Notify triggers or resolves one incident per finding
+func (n *Notifier) Notify(ctx context.Context, notification output.Notification) error {
+	if notification.Type == output.NotificationAnalysis {
+		return nil
+	}
+
+	var errs []error
+	for _, finding := range notification.Findings {
+		var err error
+		if notification.Type == output.NotificationResolved {
+			err = n.Provider.Resolve(ctx, DedupKey(finding))
+		} else {
+			err = n.Provider.Trigger(ctx, NewIncident(finding))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", finding.Object(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,100 @@
+package incident
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// OpsgenieAPIURL is the base URL of the Opsgenie API. Accounts in the EU
+// instance use https://api.eu.opsgenie.com.
+const OpsgenieAPIURL = "https://api.opsgenie.com"
+
+// Opsgenie creates and closes Opsgenie alerts, using the dedup key as alias
+type Opsgenie struct {
+	APIKey string
+	// URL overrides OpsgenieAPIURL, for example with a local stand-in
+	URL        string
+	HTTPClient *http.Client
+}
+
+// opsgenieAlert is the body of a create alert request
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Priority    string            `json:"priority"`
+	Source      string            `json:"source"`
+	Entity      string            `json:"entity,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// Name identifies the provider
+func (o *Opsgenie) Name() string {
+	return "opsgenie"
+}
+
+// Trigger creates an alert. Opsgenie deduplicates open alerts by alias.
+func (o *Opsgenie) Trigger(ctx context.Context, incident Incident) error {
+	finding := incident.Finding
+
+	description := finding.Message
+	if finding.Analysis != "" {
+		description = strings.TrimSpace(description + "\n\n" + finding.Analysis)
+	}
+
+	return postJSON(ctx, o.HTTPClient, o.baseURL()+"/v2/alerts", opsgenieAlert{
+		// Opsgenie limits messages to 130 and descriptions to 15000 characters
+		Message:     output.TruncateRunes(incident.Summary, 130),
+		Alias:       incident.DedupKey,
+		Description: output.TruncateRunes(description, 15000),
+		Priority:    OpsgeniePriority(incident.Severity),
+		Source:      "kubegpt",
+		Entity:      incident.Object,
+		Tags:        []string{"kubegpt", "namespace:" + incident.Namespace, "kind:" + strings.ToLower(finding.Kind)},
+		Details: map[string]string{
+			"namespace":   incident.Namespace,
+			"kind":        finding.Kind,
+			"name":        finding.Name,
+			"reason":      incident.Reason,
+			"severity":    string(incident.Severity),
+			"fingerprint": finding.Fingerprint,
+		},
+	}, o.headers())
+}
+
+// Resolve closes the alert with the dedup key as alias
+func (o *Opsgenie) Resolve(ctx context.Context, dedupKey string) error {
+	endpoint := fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias", o.baseURL(), url.PathEscape(dedupKey))
+	return postJSON(ctx, o.HTTPClient, endpoint, map[string]string{
+		"source": "kubegpt",
+		"note":   "Resolved by kubegpt: the issue is no longer detected",
+	}, o.headers())
+}
+
+// OpsgeniePriority maps a finding severity to an Opsgenie priority
+func OpsgeniePriority(severity output.Severity) string {
+	switch severity {
+	case output.SeverityCritical:
+		return "P1"
+	case output.SeverityWarning:
+		return "P3"
+	}
+	return "P5"
+}
+
+func (o *Opsgenie) baseURL() string {
+	if o.URL == "" {
+		return OpsgenieAPIURL
+	}
+	return strings.TrimSuffix(o.URL, "/")
+}
+
+func (o *Opsgenie) headers() map[string]string {
+	return map[string]string{"Authorization": "GenieKey " + o.APIKey}
+}
@@ -0,0 +1,103 @@
+package incident
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty sends incidents to the PagerDuty Events API v2
+type PagerDuty struct {
+	RoutingKey string
+	// URL overrides PagerDutyEventsURL, for example with a local stand-in
+	URL        string
+	HTTPClient *http.Client
+}
+
+// pagerDutyEvent is an Events API v2 event
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string         `json:"summary"`
+	Source        string         `json:"source"`
+	Severity      string         `json:"severity"`
+	Timestamp     string         `json:"timestamp,omitempty"`
+	Component     string         `json:"component,omitempty"`
+	Group         string         `json:"group,omitempty"`
+	Class         string         `json:"class,omitempty"`
+	CustomDetails output.Finding `json:"custom_details"`
+}
+
+// Name identifies the provider
+func (p *PagerDuty) Name() string {
+	return "pagerduty"
+}
+
+// Trigger sends a trigger event
+func (p *PagerDuty) Trigger(ctx context.Context, incident Incident) error {
+	return p.send(ctx, pagerDutyEvent{
+		RoutingKey:  p.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    incident.DedupKey,
+		Payload: &pagerDutyPayload{
+			// PagerDuty truncates summaries at 1024 characters
+			Summary:       output.TruncateRunes(incident.Summary, 1024),
+			Source:        "kubegpt/" + incident.Namespace,
+			Severity:      PagerDutySeverity(incident.Severity),
+			Timestamp:     incident.Finding.DetectedAt.Format(time.RFC3339),
+			Component:     incident.Object,
+			Group:         incident.Namespace,
+			Class:         incident.Reason,
+			CustomDetails: incident.Finding,
+		},
+	})
+}
+
+// Resolve sends a resolve event
+func (p *PagerDuty) Resolve(ctx context.Context, dedupKey string) error {
+	return p.send(ctx, pagerDutyEvent{
+		RoutingKey:  p.RoutingKey,
+		EventAction: "resolve",
+		DedupKey:    dedupKey,
+	})
+}
+
+// PagerDutySeverity maps a finding severity to a PagerDuty event severity
+func PagerDutySeverity(severity output.Severity) string {
+	switch severity {
+	case output.SeverityCritical:
+		return "critical"
+	case output.SeverityWarning:
+		return "warning"
+	}
+	return "info"
+}
+
+// send posts an event. The Events API answers 202 Accepted.
+func (p *PagerDuty) send(ctx context.Context, event pagerDutyEvent) error {
+	url := p.URL
+	if url == "" {
+		url = PagerDutyEventsURL
+	}
+	return postJSON(ctx, p.HTTPClient, url, event, nil)
+}
+
+// postJSON posts v as JSON and fails on non-2xx responses
+func postJSON(ctx context.Context, client *http.Client, url string, v interface{}, headers map[string]string) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return output.PostJSON(ctx, client, url, payload, headers)
+}
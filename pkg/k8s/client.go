@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Client represents a Kubernetes client
@@ -16,6 +19,30 @@ type Client struct {
 	namespace  string
 }
 
+// podLogLines is the number of log lines collected per failing container.
+// The AI layer packs them into its prompt budget, keeping errors first.
+const podLogLines = 200
+
+// CommandObserver receives the arguments, duration and outcome of a kubectl
+// call, for example to export latency metrics
+type CommandObserver func(args []string, duration time.Duration, err error)
+
+// commandObserver is the installed observer, if any
+var commandObserver CommandObserver
+
+// SetCommandObserver installs an observer of the kubectl calls made by all
+// clients. It must be called before any client is used.
+func SetCommandObserver(observer CommandObserver) {
+	commandObserver = observer
+}
+
+// observeCommand reports a finished kubectl call to the observer
+func observeCommand(args []string, start time.Time, err error) {
+	if commandObserver != nil {
+		commandObserver(args, time.Since(start), err)
+	}
+}
+
 // NewClient creates a new Kubernetes client
 func NewClient(kubeconfig string) (*Client, error) {
 	return &Client{
@@ -77,8 +104,10 @@ func (c *Client) GetCurrentNamespace() string {
 // NamespaceExists checks if a namespace exists
 func (c *Client) NamespaceExists(namespace string) bool {
 	// First try the standard way
-	cmd := c.kubectlCommand("get", "namespace", namespace, "--no-headers", "--output", "name")
-	output, err := cmd.Output()
+	args := []string{"get", "namespace", namespace, "--no-headers", "--output", "name"}
+	start := time.Now()
+	output, err := c.kubectlCommand(args...).Output()
+	observeCommand(args, start, err)
 	if err == nil && strings.TrimSpace(string(output)) != "" {
 		return true
 	}
@@ -102,7 +131,12 @@ func (c *Client) NamespaceExists(namespace string) bool {
 
 // kubectlCommand creates a kubectl command with the specified arguments
 func (c *Client) kubectlCommand(args ...string) *exec.Cmd {
-	cmd := exec.Command("kubectl", args...)
+	return c.kubectlCommandContext(context.Background(), args...)
+}
+
+// kubectlCommandContext creates a kubectl command that is killed when ctx is done
+func (c *Client) kubectlCommandContext(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
 
 	// Set kubeconfig if specified
 	if c.kubeconfig != "" {
@@ -114,8 +148,15 @@ func (c *Client) kubectlCommand(args ...string) *exec.Cmd {
 
 // ExecuteKubectl executes a kubectl command and returns the output
 func (c *Client) ExecuteKubectl(args ...string) (string, error) {
-	cmd := c.kubectlCommand(args...)
+	return c.ExecuteKubectlContext(context.Background(), args...)
+}
+
+// ExecuteKubectlContext executes a kubectl command bound to ctx and returns the output
+func (c *Client) ExecuteKubectlContext(ctx context.Context, args ...string) (string, error) {
+	cmd := c.kubectlCommandContext(ctx, args...)
+	start := time.Now()
 	output, err := cmd.CombinedOutput()
+	observeCommand(args, start, err)
 	if err != nil {
 		return "", fmt.Errorf("kubectl error: %w\nOutput: %s", err, string(output))
 	}
@@ -144,7 +185,7 @@ func (c *Client) GetUnhealthyPods(ctx context.Context) ([]PodIssue, error) {
 	}
 
 	// Get real unhealthy pods from the cluster
-	pods, err := c.getRealUnhealthyPods()
+	pods, err := c.getRealUnhealthyPods(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get unhealthy pods: %w", err)
 	}
@@ -158,8 +199,8 @@ func (c *Client) GetUnhealthyPods(ctx context.Context) ([]PodIssue, error) {
 }
 
 // getRealUnhealthyPods attempts to get real unhealthy pods from the cluster
-func (c *Client) getRealUnhealthyPods() ([]PodIssue, error) {
-	output, err := c.ExecuteKubectl("get", "pods", "-n", c.GetCurrentNamespace(), "-o", "json")
+func (c *Client) getRealUnhealthyPods(ctx context.Context) ([]PodIssue, error) {
+	output, err := c.ExecuteKubectlContext(ctx, "get", "pods", "-n", c.GetCurrentNamespace(), "-o", "json")
 	if err != nil {
 		return nil, err
 	}
@@ -167,13 +208,22 @@ func (c *Client) getRealUnhealthyPods() ([]PodIssue, error) {
 	var podList struct {
 		Items []struct {
 			Metadata struct {
-				Name      string `json:"name"`
-				Namespace string `json:"namespace"`
+				Name              string            `json:"name"`
+				Namespace         string            `json:"namespace"`
+				Labels            map[string]string `json:"labels"`
+				CreationTimestamp string            `json:"creationTimestamp"`
 			} `json:"metadata"`
+			Spec struct {
+				NodeName   string                   `json:"nodeName"`
+				Containers []map[string]interface{} `json:"containers"`
+			} `json:"spec"`
 			Status struct {
 				Phase             string `json:"phase"`
+				Reason            string `json:"reason"`
+				Message           string `json:"message"`
 				ContainerStatuses []struct {
 					Name         string `json:"name"`
+					Image        string `json:"image"`
 					Ready        bool   `json:"ready"`
 					RestartCount int    `json:"restartCount"`
 					State        struct {
@@ -181,7 +231,18 @@ func (c *Client) getRealUnhealthyPods() ([]PodIssue, error) {
 							Reason  string `json:"reason"`
 							Message string `json:"message"`
 						} `json:"waiting"`
+						Terminated struct {
+							Reason   string `json:"reason"`
+							Message  string `json:"message"`
+							ExitCode int    `json:"exitCode"`
+						} `json:"terminated"`
 					} `json:"state"`
+					LastState struct {
+						Terminated struct {
+							Reason   string `json:"reason"`
+							ExitCode int    `json:"exitCode"`
+						} `json:"terminated"`
+					} `json:"lastState"`
 				} `json:"containerStatuses"`
 			} `json:"status"`
 		} `json:"items"`
@@ -210,15 +271,25 @@ func (c *Client) getRealUnhealthyPods() ([]PodIssue, error) {
 			podIssue := PodIssue{
 				Name:      pod.Metadata.Name,
 				Namespace: pod.Metadata.Namespace,
+				Labels:    pod.Metadata.Labels,
 				Status:    pod.Status.Phase,
+				Reason:    pod.Status.Reason,
+				Message:   pod.Status.Message,
+				Node:      pod.Spec.NodeName,
+				Spec:      containerSpec(pod.Spec.Containers),
+			}
+			if creationTime, err := time.Parse(time.RFC3339, pod.Metadata.CreationTimestamp); err == nil {
+				podIssue.Age = time.Since(creationTime)
 			}
 
 			// Add container issues
 			for _, container := range pod.Status.ContainerStatuses {
 				containerIssue := ContainerIssue{
 					Name:     container.Name,
+					Image:    container.Image,
 					Ready:    container.Ready,
 					Restarts: container.RestartCount,
+					ExitCode: container.LastState.Terminated.ExitCode,
 				}
 
 				if !container.Ready {
@@ -226,6 +297,10 @@ func (c *Client) getRealUnhealthyPods() ([]PodIssue, error) {
 					if container.State.Waiting.Reason != "" {
 						containerIssue.Reason = container.State.Waiting.Reason
 						containerIssue.Message = container.State.Waiting.Message
+					} else if container.State.Terminated.Reason != "" {
+						containerIssue.Reason = container.State.Terminated.Reason
+						containerIssue.Message = container.State.Terminated.Message
+						containerIssue.ExitCode = container.State.Terminated.ExitCode
 					}
 				} else {
 					containerIssue.Status = "Running"
@@ -234,6 +309,22 @@ func (c *Client) getRealUnhealthyPods() ([]PodIssue, error) {
 				podIssue.Containers = append(podIssue.Containers, containerIssue)
 			}
 
+			// Events and the logs of failing containers give the AI the
+			// context it needs beyond the status
+			if events, err := c.GetObjectEvents(ctx, pod.Metadata.Namespace, pod.Metadata.Name); err == nil {
+				for _, event := range events {
+					podIssue.Events = append(podIssue.Events, event)
+				}
+			}
+			podIssue.Logs = make(map[string]string)
+			for _, container := range podIssue.Containers {
+				if !container.Ready || container.Restarts > 5 {
+					if logs, err := c.GetContainerLogs(ctx, pod.Metadata.Namespace, pod.Metadata.Name, container.Name, podLogLines); err == nil {
+						podIssue.Logs[container.Name] = logs
+					}
+				}
+			}
+
 			unhealthyPods = append(unhealthyPods, podIssue)
 		}
 	}
@@ -249,7 +340,7 @@ func (c *Client) GetFailedEvents(ctx context.Context) ([]interface{}, error) {
 	}
 
 	// Get real failed events from the cluster
-	events, err := c.getRealFailedEvents()
+	events, err := c.getRealFailedEvents(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get events: %w", err)
 	}
@@ -263,12 +354,12 @@ func (c *Client) GetFailedEvents(ctx context.Context) ([]interface{}, error) {
 }
 
 // getRealFailedEvents attempts to get real failed events from the cluster
-func (c *Client) getRealFailedEvents() ([]interface{}, error) {
+func (c *Client) getRealFailedEvents(ctx context.Context) ([]interface{}, error) {
 	// First try to get warning events in the current namespace
-	output, err := c.ExecuteKubectl("get", "events", "-n", c.GetCurrentNamespace(), "--field-selector=type=Warning", "-o", "json")
+	output, err := c.ExecuteKubectlContext(ctx, "get", "events", "-n", c.GetCurrentNamespace(), "--field-selector=type=Warning", "-o", "json")
 	if err != nil {
 		// Try getting all events if specific selector fails
-		output, err = c.ExecuteKubectl("get", "events", "-n", c.GetCurrentNamespace(), "-o", "json")
+		output, err = c.ExecuteKubectlContext(ctx, "get", "events", "-n", c.GetCurrentNamespace(), "-o", "json")
 		if err != nil {
 			return nil, err
 		}
@@ -363,7 +454,7 @@ func (c *Client) GetMisconfiguredDeployments(ctx context.Context) ([]DeploymentI
 	}
 
 	// Get real misconfigured deployments from the cluster
-	deployments, err := c.getRealMisconfiguredDeployments()
+	deployments, err := c.getRealMisconfiguredDeployments(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get misconfigured deployments: %w", err)
 	}
@@ -377,8 +468,8 @@ func (c *Client) GetMisconfiguredDeployments(ctx context.Context) ([]DeploymentI
 }
 
 // getRealMisconfiguredDeployments attempts to get real misconfigured deployments from the cluster
-func (c *Client) getRealMisconfiguredDeployments() ([]DeploymentIssue, error) {
-	output, err := c.ExecuteKubectl("get", "deployments", "-n", c.GetCurrentNamespace(), "-o", "json")
+func (c *Client) getRealMisconfiguredDeployments(ctx context.Context) ([]DeploymentIssue, error) {
+	output, err := c.ExecuteKubectlContext(ctx, "get", "deployments", "-n", c.GetCurrentNamespace(), "-o", "json")
 	if err != nil {
 		return nil, err
 	}
@@ -389,6 +480,7 @@ func (c *Client) getRealMisconfiguredDeployments() ([]DeploymentIssue, error) {
 				Name      string `json:"name"`
 				Namespace string `json:"namespace"`
 				Labels    map[string]string `json:"labels"`
+				CreationTimestamp string `json:"creationTimestamp"`
 			} `json:"metadata"`
 			Spec struct {
 				Replicas int `json:"replicas"`
@@ -398,6 +490,11 @@ func (c *Client) getRealMisconfiguredDeployments() ([]DeploymentIssue, error) {
 				Selector struct {
 					MatchLabels map[string]string `json:"matchLabels"`
 				} `json:"selector"`
+				Template struct {
+					Spec struct {
+						Containers []map[string]interface{} `json:"containers"`
+					} `json:"spec"`
+				} `json:"template"`
 			} `json:"spec"`
 			Status struct {
 				Replicas            int `json:"replicas"`
@@ -447,11 +544,16 @@ func (c *Client) getRealMisconfiguredDeployments() ([]DeploymentIssue, error) {
 			deploymentIssue := DeploymentIssue{
 				Name:              deployment.Metadata.Name,
 				Namespace:         deployment.Metadata.Namespace,
+				Labels:            deployment.Metadata.Labels,
 				Replicas:          deployment.Spec.Replicas,
 				ReadyReplicas:     deployment.Status.ReadyReplicas,
 				UpdatedReplicas:   deployment.Status.UpdatedReplicas,
 				AvailableReplicas: deployment.Status.AvailableReplicas,
 				Strategy:          deployment.Spec.Strategy.Type,
+				Spec:              containerSpec(deployment.Spec.Template.Spec.Containers),
+			}
+			if creationTime, err := time.Parse(time.RFC3339, deployment.Metadata.CreationTimestamp); err == nil {
+				deploymentIssue.Age = time.Since(creationTime)
 			}
 
 			// Add conditions
@@ -491,7 +593,7 @@ func (c *Client) getRealMisconfiguredDeployments() ([]DeploymentIssue, error) {
 			}
 			
 			// Get events related to this deployment
-			eventsOutput, err := c.ExecuteKubectl("get", "events", "--field-selector=involvedObject.name="+deployment.Metadata.Name, 
+			eventsOutput, err := c.ExecuteKubectlContext(ctx, "get", "events", "--field-selector=involvedObject.name="+deployment.Metadata.Name, 
 				"-n", deployment.Metadata.Namespace, "-o", "json")
 			
 			if err == nil {
@@ -523,7 +625,7 @@ func (c *Client) GetServiceIssues(ctx context.Context) ([]interface{}, error) {
 	}
 
 	// Get real service issues from the cluster
-	services, err := c.getRealServiceIssues()
+	services, err := c.getRealServiceIssues(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get service issues: %w", err)
 	}
@@ -537,9 +639,9 @@ func (c *Client) GetServiceIssues(ctx context.Context) ([]interface{}, error) {
 }
 
 // getRealServiceIssues attempts to get real service issues from the cluster
-func (c *Client) getRealServiceIssues() ([]interface{}, error) {
+func (c *Client) getRealServiceIssues(ctx context.Context) ([]interface{}, error) {
 	// Get all services
-	output, err := c.ExecuteKubectl("get", "services", "-n", c.GetCurrentNamespace(), "-o", "json")
+	output, err := c.ExecuteKubectlContext(ctx, "get", "services", "-n", c.GetCurrentNamespace(), "-o", "json")
 	if err != nil {
 		return nil, err
 	}
@@ -547,14 +649,17 @@ func (c *Client) getRealServiceIssues() ([]interface{}, error) {
 	var serviceList struct {
 		Items []struct {
 			Metadata struct {
-				Name      string `json:"name"`
-				Namespace string `json:"namespace"`
+				Name              string            `json:"name"`
+				Namespace         string            `json:"namespace"`
+				Labels            map[string]string `json:"labels"`
+				CreationTimestamp string            `json:"creationTimestamp"`
 			} `json:"metadata"`
 			Spec struct {
-				Type       string            `json:"type"`
-				Selector   map[string]string `json:"selector"`
-				ClusterIP  string            `json:"clusterIP"`
-				Ports      []interface{}     `json:"ports"`
+				Type        string            `json:"type"`
+				Selector    map[string]string `json:"selector"`
+				ClusterIP   string            `json:"clusterIP"`
+				ExternalIPs []string          `json:"externalIPs"`
+				Ports       []interface{}     `json:"ports"`
 			} `json:"spec"`
 		} `json:"items"`
 	}
@@ -571,12 +676,13 @@ func (c *Client) getRealServiceIssues() ([]interface{}, error) {
 		}
 		
 		// Get endpoints for this service
-		endpointsOutput, err := c.ExecuteKubectl("get", "endpoints", service.Metadata.Name, "-n", c.GetCurrentNamespace(), "-o", "json")
+		endpointsOutput, err := c.ExecuteKubectlContext(ctx, "get", "endpoints", service.Metadata.Name, "-n", c.GetCurrentNamespace(), "-o", "json")
 		if err != nil {
 			// If we can't get endpoints, consider it an issue
 			serviceIssues = append(serviceIssues, map[string]interface{}{
 				"name":      service.Metadata.Name,
 				"namespace": service.Metadata.Namespace,
+				"labels":    service.Metadata.Labels,
 				"type":      service.Spec.Type,
 				"issue":     "Cannot retrieve endpoints",
 				"message":   fmt.Sprintf("Error getting endpoints: %v", err),
@@ -595,6 +701,7 @@ func (c *Client) getRealServiceIssues() ([]interface{}, error) {
 			serviceIssues = append(serviceIssues, map[string]interface{}{
 				"name":      service.Metadata.Name,
 				"namespace": service.Metadata.Namespace,
+				"labels":    service.Metadata.Labels,
 				"type":      service.Spec.Type,
 				"issue":     "Invalid endpoint data",
 				"message":   fmt.Sprintf("Error parsing endpoints: %v", err),
@@ -622,23 +729,124 @@ func (c *Client) getRealServiceIssues() ([]interface{}, error) {
 				c.GetCurrentNamespace(), 
 				strings.Join(selectorString, ","))
 			
-			podsOutput, _ := c.ExecuteKubectl(strings.Split(podListCmd, " ")...)
+			podsOutput, _ := c.ExecuteKubectlContext(ctx, strings.Split(podListCmd, " ")...)
 			
 			message := "Service has no endpoint pods"
 			if podsOutput != "" {
 				message = fmt.Sprintf("Service has no endpoint pods. Matching pods status: %s", podsOutput)
 			}
 			
-			serviceIssues = append(serviceIssues, map[string]interface{}{
+			serviceIssue := map[string]interface{}{
 				"name":      service.Metadata.Name,
 				"namespace": service.Metadata.Namespace,
+				"labels":    service.Metadata.Labels,
 				"type":      service.Spec.Type,
 				"issue":     "No endpoints available",
 				"message":   message,
 				"selector":  service.Spec.Selector,
-			})
+				"clusterIP": service.Spec.ClusterIP,
+				"endpoints": 0,
+			}
+			if len(service.Spec.ExternalIPs) > 0 {
+				serviceIssue["externalIPs"] = service.Spec.ExternalIPs
+			}
+			if service.Metadata.CreationTimestamp != "" {
+				serviceIssue["creationTimestamp"] = service.Metadata.CreationTimestamp
+			}
+			if events, err := c.GetObjectEvents(ctx, service.Metadata.Namespace, service.Metadata.Name); err == nil && len(events) > 0 {
+				serviceEvents := make([]interface{}, len(events))
+				for i, event := range events {
+					serviceEvents[i] = event
+				}
+				serviceIssue["events"] = serviceEvents
+			}
+
+			serviceIssues = append(serviceIssues, serviceIssue)
 		}
 	}
 
 	return serviceIssues, nil
 }
+
+// GetObjectEvents returns the events whose involved object has the given name
+// in a namespace, oldest first
+func (c *Client) GetObjectEvents(ctx context.Context, namespace, name string) ([]map[string]interface{}, error) {
+	output, err := c.ExecuteKubectlContext(ctx, "get", "events", "-n", namespace,
+		"--field-selector", "involvedObject.name="+name, "--sort-by=.lastTimestamp", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var eventList struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &eventList); err != nil {
+		return nil, err
+	}
+	return eventList.Items, nil
+}
+
+// containerSpec renders the containers of a pod spec as YAML for the AI,
+// without the values of environment variables, which often hold secrets, and
+// without fields that never explain a failure
+func containerSpec(containers []map[string]interface{}) string {
+	if len(containers) == 0 {
+		return ""
+	}
+
+	for _, container := range containers {
+		delete(container, "terminationMessagePath")
+		delete(container, "terminationMessagePolicy")
+		if env, ok := container["env"].([]interface{}); ok {
+			for _, variable := range env {
+				if variable, ok := variable.(map[string]interface{}); ok {
+					if _, ok := variable["value"]; ok {
+						variable["value"] = "<omitted>"
+					}
+				}
+			}
+		}
+	}
+
+	spec, err := yaml.Marshal(containers)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(string(spec), "\n")
+}
+
+// FormatEvent formats an event as a single line with its time, type, reason,
+// message and repeat count
+func FormatEvent(event map[string]interface{}) string {
+	field := func(key string) string {
+		value, _ := event[key].(string)
+		return value
+	}
+
+	timestamp := field("lastTimestamp")
+	if timestamp == "" {
+		timestamp = field("eventTime")
+	}
+
+	line := fmt.Sprintf("%s %s %s: %s", timestamp, field("type"), field("reason"), strings.TrimSpace(field("message")))
+	if count, ok := event["count"].(float64); ok && count > 1 {
+		line += fmt.Sprintf(" (x%d)", int(count))
+	}
+	return strings.TrimSpace(line)
+}
+
+// GetContainerLogs returns the last tailLines lines of a container's logs,
+// falling back to the previous instance when the current one has none, as is
+// the case for a container in CrashLoopBackOff
+func (c *Client) GetContainerLogs(ctx context.Context, namespace, pod, container string, tailLines int) (string, error) {
+	args := []string{"logs", "-n", namespace, pod, "-c", container, "--tail", strconv.Itoa(tailLines)}
+
+	output, err := c.ExecuteKubectlContext(ctx, args...)
+	if err != nil || strings.TrimSpace(output) == "" {
+		previous, previousErr := c.ExecuteKubectlContext(ctx, append(args, "--previous")...)
+		if previousErr == nil {
+			return previous, nil
+		}
+	}
+	return output, err
+}
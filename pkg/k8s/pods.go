@@ -1,10 +1,9 @@
 package k8s
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"strconv"
-	"strings"
 	"time"
 )
 
@@ -152,7 +151,7 @@ func (c *Client) GetUnhealthyPodsLegacy(namespace string) ([]PodIssue, error) {
 		podIssue.Logs = make(map[string]string)
 		for _, container := range podIssue.Containers {
 			if !container.Ready || container.Restarts > 5 {
-				logs, _ := c.getPodLogs(pod.Metadata.Name, pod.Metadata.Namespace, container.Name, 50)
+				logs, _ := c.GetContainerLogs(context.Background(), pod.Metadata.Namespace, pod.Metadata.Name, container.Name, 50)
 				podIssue.Logs[container.Name] = logs
 			}
 		}
@@ -180,34 +179,3 @@ func (c *Client) getPodEvents(podName, namespace string) []interface{} {
 
 	return eventList.Items
 }
-
-// getPodLogs gets logs for a specific container in a pod
-func (c *Client) getPodLogs(podName, namespace, containerName string, tailLines int) (string, error) {
-	args := []string{"logs", "-n", namespace, podName, "-c", containerName}
-	
-	if tailLines > 0 {
-		args = append(args, "--tail", strconv.Itoa(tailLines))
-	}
-	
-	output, err := c.ExecuteKubectl(args...)
-	if err != nil {
-		// Try to get previous logs if current logs fail
-		args = append(args, "--previous")
-		output, err = c.ExecuteKubectl(args...)
-		if err != nil {
-			return "", err
-		}
-	}
-	
-	// Truncate logs if they're too long
-	if len(output) > 2000 {
-		lines := strings.Split(output, "\n")
-		if len(lines) > 20 {
-			// Take the first 5 and last 15 lines
-			truncatedLines := append(lines[:5], append([]string{"...[logs truncated]..."}, lines[len(lines)-15:]...)...)
-			output = strings.Join(truncatedLines, "\n")
-		}
-	}
-	
-	return output, nil
-}
\ No newline at end of file
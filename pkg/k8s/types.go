@@ -8,6 +8,7 @@ import (
 type PodIssue struct {
 	Name       string
 	Namespace  string
+	Labels     map[string]string
 	Status     string
 	Message    string
 	Reason     string
@@ -16,6 +17,9 @@ type PodIssue struct {
 	Containers []ContainerIssue
 	Events     []interface{}
 	Logs       map[string]string
+	Spec       string
+	// Analysis and Fix are the responses of the AI, which ai.ParseDiagnosis
+	// reads
 	Analysis   string
 	Fix        string
 }
@@ -36,6 +40,7 @@ type ContainerIssue struct {
 type DeploymentIssue struct {
 	Name             string
 	Namespace        string
+	Labels           map[string]string
 	Replicas         int
 	ReadyReplicas    int
 	UpdatedReplicas  int
@@ -46,6 +51,9 @@ type DeploymentIssue struct {
 	Reason           string
 	Conditions       interface{}
 	Events           []interface{}
+	Spec             string
+	// Analysis and Fix are the responses of the AI, which ai.ParseDiagnosis
+	// reads
 	Analysis         string
 	Fix              string
 }
\ No newline at end of file
@@ -0,0 +1,221 @@
+package kubegpt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+	"github.com/junioroyewunmi/kubegpt/pkg/k8s"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// analyze attaches analyses, and fixes if requested, to the first maxItems
+// pods and deployments, running up to concurrency items at a time. Results
+// and errors are recorded in item order whatever order the items finish in,
+// and failures are recorded rather than aborting the remaining items.
+func analyze(ctx context.Context, amazonQ *ai.AmazonQClient, results *Results, maxItems, concurrency int, withFix bool, progress func(Event)) {
+	// Workers report progress one event at a time
+	var progressMu sync.Mutex
+	report := func(event Event) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		progress(event)
+	}
+
+	var jobs []func() []error
+	for i := range results.UnhealthyPods {
+		if i >= maxItems {
+			break
+		}
+		i := i
+		jobs = append(jobs, func() []error {
+			return analyzePod(ctx, amazonQ, &results.UnhealthyPods[i], withFix, report)
+		})
+	}
+	for i := range results.MisconfiguredDeployments {
+		if i >= maxItems {
+			break
+		}
+		i := i
+		jobs = append(jobs, func() []error {
+			return analyzeDeployment(ctx, amazonQ, &results.MisconfiguredDeployments[i], withFix, report)
+		})
+	}
+
+	errs := make([][]error, len(jobs))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, len(jobs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				// Items queued before ctx was done are skipped
+				if ctx.Err() == nil {
+					errs[i] = jobs[i]()
+				}
+			}
+		}()
+	}
+	for i := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	for _, jobErrs := range errs {
+		results.AnalysisErrors = append(results.AnalysisErrors, jobErrs...)
+	}
+}
+
+// analyzePod attaches the analysis, and fix if requested, of one pod. A
+// failed analysis skips the fix.
+func analyzePod(ctx context.Context, amazonQ *ai.AmazonQClient, pod *k8s.PodIssue, withFix bool, progress func(Event)) []error {
+	progress(Event{Type: Analyzing, Kind: "Pod", Name: pod.Name})
+	analysis, err := amazonQ.AnalyzePodIssue(ctx, *pod)
+	if err != nil {
+		err = fmt.Errorf("analyzing pod %s: %w", pod.Name, err)
+		progress(Event{Type: Analyzed, Kind: "Pod", Name: pod.Name, Err: err})
+		return []error{err}
+	}
+	pod.Analysis = analysis.Raw
+	progress(Event{Type: Analyzed, Kind: "Pod", Name: pod.Name})
+
+	if withFix {
+		progress(Event{Type: Fixing, Kind: "Pod", Name: pod.Name})
+		fix, err := amazonQ.GeneratePodFix(ctx, *pod)
+		if err != nil {
+			err = fmt.Errorf("generating fix for pod %s: %w", pod.Name, err)
+			progress(Event{Type: Fixed, Kind: "Pod", Name: pod.Name, Err: err})
+			return []error{err}
+		}
+		pod.Fix = fix.Raw
+		progress(Event{Type: Fixed, Kind: "Pod", Name: pod.Name})
+	}
+	return nil
+}
+
+// analyzeDeployment attaches the analysis, and fix if requested, of one
+// deployment. A failed analysis skips the fix.
+func analyzeDeployment(ctx context.Context, amazonQ *ai.AmazonQClient, deployment *k8s.DeploymentIssue, withFix bool, progress func(Event)) []error {
+	progress(Event{Type: Analyzing, Kind: "Deployment", Name: deployment.Name})
+	analysis, err := amazonQ.AnalyzeDeploymentIssue(ctx, *deployment)
+	if err != nil {
+		err = fmt.Errorf("analyzing deployment %s: %w", deployment.Name, err)
+		progress(Event{Type: Analyzed, Kind: "Deployment", Name: deployment.Name, Err: err})
+		return []error{err}
+	}
+	deployment.Analysis = analysis.Raw
+	progress(Event{Type: Analyzed, Kind: "Deployment", Name: deployment.Name})
+
+	if withFix {
+		progress(Event{Type: Fixing, Kind: "Deployment", Name: deployment.Name})
+		fix, err := amazonQ.GenerateDeploymentFix(ctx, *deployment)
+		if err != nil {
+			err = fmt.Errorf("generating fix for deployment %s: %w", deployment.Name, err)
+			progress(Event{Type: Fixed, Kind: "Deployment", Name: deployment.Name, Err: err})
+			return []error{err}
+		}
+		deployment.Fix = fix.Raw
+		progress(Event{Type: Fixed, Kind: "Deployment", Name: deployment.Name})
+	}
+	return nil
+}
+
+// AnalyzeFinding asks the AI about a single finding, using the richer pod,
+// deployment and service prompts when the underlying object is in results
+func AnalyzeFinding(ctx context.Context, amazonQ *ai.AmazonQClient, results output.DiagnosticResults, finding output.Finding) (*ai.Diagnosis, error) {
+	switch object := findObject(results, finding).(type) {
+	case k8s.PodIssue:
+		return amazonQ.AnalyzePodIssue(ctx, object)
+	case k8s.DeploymentIssue:
+		return amazonQ.AnalyzeDeploymentIssue(ctx, object)
+	case map[string]interface{}:
+		return amazonQ.AnalyzeServiceIssue(ctx, object)
+	}
+	return amazonQ.ExplainError(ctx, describeFinding(finding))
+}
+
+// FixFinding asks the AI for a fix of a pod or deployment finding whose
+// object is in results
+func FixFinding(ctx context.Context, amazonQ *ai.AmazonQClient, results output.DiagnosticResults, finding output.Finding) (*ai.Diagnosis, error) {
+	if finding.Kind != "Pod" && finding.Kind != "Deployment" {
+		return nil, fmt.Errorf("fixes are only generated for pods and deployments")
+	}
+	switch object := findObject(results, finding).(type) {
+	case k8s.PodIssue:
+		return amazonQ.GeneratePodFix(ctx, object)
+	case k8s.DeploymentIssue:
+		return amazonQ.GenerateDeploymentFix(ctx, object)
+	}
+	return nil, fmt.Errorf("%s is not in the results", finding.Object())
+}
+
+// FindingPrompt returns the prompt that AnalyzeFinding, or FixFinding if fix
+// is set, would send for a finding
+func FindingPrompt(amazonQ *ai.AmazonQClient, results output.DiagnosticResults, finding output.Finding, fix bool) (string, error) {
+	object := findObject(results, finding)
+	if fix {
+		if finding.Kind != "Pod" && finding.Kind != "Deployment" {
+			return "", fmt.Errorf("fixes are only generated for pods and deployments")
+		}
+		switch object := object.(type) {
+		case k8s.PodIssue:
+			return amazonQ.PodFixPrompt(object)
+		case k8s.DeploymentIssue:
+			return amazonQ.DeploymentFixPrompt(object)
+		}
+		return "", fmt.Errorf("%s is not in the results", finding.Object())
+	}
+
+	switch object := object.(type) {
+	case k8s.PodIssue:
+		return amazonQ.PodPrompt(object)
+	case k8s.DeploymentIssue:
+		return amazonQ.DeploymentPrompt(object)
+	case map[string]interface{}:
+		return amazonQ.ServicePrompt(object)
+	}
+	return amazonQ.ErrorPrompt(describeFinding(finding))
+}
+
+// findObject returns the pod, deployment or service map behind a finding, or
+// nil if it is not in results or is an event
+func findObject(results output.DiagnosticResults, finding output.Finding) interface{} {
+	switch finding.Kind {
+	case "Pod":
+		for _, pod := range results.UnhealthyPods {
+			if pod.Name == finding.Name {
+				return pod
+			}
+		}
+	case "Deployment":
+		for _, deployment := range results.MisconfiguredDeployments {
+			if deployment.Name == finding.Name {
+				return deployment
+			}
+		}
+	case "Service":
+		for _, service := range results.ServiceIssues {
+			if serviceMap, ok := service.(map[string]interface{}); ok && serviceMap["name"] == finding.Name {
+				return serviceMap
+			}
+		}
+	}
+	return nil
+}
+
+// describeFinding describes a finding without an object for the error prompt
+func describeFinding(finding output.Finding) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s: %s", finding.Object(), finding.Reason))
+	if finding.Message != "" {
+		sb.WriteString("\n" + finding.Message)
+	}
+	return sb.String()
+}
@@ -0,0 +1,338 @@
+// Package kubegpt is the library behind the kubegpt CLI. Diagnose collects the
+// unhealthy objects of a namespace and analyzes them with AI, reporting its
+// progress through a callback instead of printing, so that operators and other
+// tools can embed the same diagnosis as the CLI.
+//
+//	results, err := kubegpt.Diagnose(ctx, kubegpt.Options{
+//		Namespace: "payments",
+//		Analyze:   true,
+//		Progress: func(event kubegpt.Event) {
+//			log.Printf("%s %s %s", event.Type, event.Kind, event.Name)
+//		},
+//	})
+package kubegpt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+	"github.com/junioroyewunmi/kubegpt/pkg/k8s"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// Kinds of objects that can be diagnosed, in collection order
+var Kinds = []string{"Pod", "Event", "Deployment", "Service"}
+
+// DefaultMaxItems is the number of pods and deployments analyzed when
+// Options.MaxItems is not set
+const DefaultMaxItems = 5
+
+// DefaultConcurrency is the number of objects analyzed at the same time when
+// Options.Concurrency is not set
+const DefaultConcurrency = 4
+
+// Options configures a diagnosis
+type Options struct {
+	// Kubeconfig is the kubeconfig file; empty uses kubectl's default
+	Kubeconfig string
+	// Namespace to diagnose; empty uses the namespace of the current context
+	Namespace string
+	// Kinds selects the collectors to run from Kinds, case-insensitively.
+	// Empty runs all of them.
+	Kinds []string
+	// CollectTimeout bounds the collection, but not the analysis; zero
+	// leaves it to ctx
+	CollectTimeout time.Duration
+	// Names keeps only the objects with these names. Events are matched on
+	// the object they refer to. Empty keeps everything.
+	Names []string
+	// Objects keeps only the objects with these names, keyed by kind as in
+	// Kinds, case-insensitively. Events are matched on the kind and name of
+	// the object they refer to. Empty keeps everything.
+	Objects map[string][]string
+	// Analyze asks the AI about each pod and deployment, up to MaxItems of
+	// each. Fix additionally asks for a fix and implies Analyze.
+	Analyze bool
+	Fix     bool
+	// MaxItems caps the analyzed pods and deployments per kind; zero means
+	// DefaultMaxItems
+	MaxItems int
+	// Concurrency is the number of objects analyzed at the same time; zero
+	// means DefaultConcurrency. The analysis and fix of one object run one
+	// after the other. Rate limits are set on the AI client.
+	Concurrency int
+	// AI is the client used for analyses; nil creates a default client
+	AI *ai.AmazonQClient
+	// Progress, if set, is called as the diagnosis advances, one event at a
+	// time. Events of objects analyzed concurrently interleave.
+	Progress func(Event)
+}
+
+// EventType describes a step of a diagnosis
+type EventType string
+
+const (
+	// Started is sent once the namespace has been resolved
+	Started EventType = "started"
+	// Collecting is sent before the collector of Kind runs
+	Collecting EventType = "collecting"
+	// Collected is sent after the collector of Kind ran, with the number of
+	// objects found in Count or the collector's error in Err
+	Collected EventType = "collected"
+	// Analyzing is sent before the AI analyzes the object Kind/Name
+	Analyzing EventType = "analyzing"
+	// Analyzed is sent after an analysis, with Err set if it failed
+	Analyzed EventType = "analyzed"
+	// Fixing is sent before the AI generates a fix for Kind/Name
+	Fixing EventType = "fixing"
+	// Fixed is sent after a fix was generated, with Err set if it failed
+	Fixed EventType = "fixed"
+)
+
+// Event reports the progress of a diagnosis
+type Event struct {
+	Type      EventType
+	Namespace string
+	Kind      string
+	Name      string
+	Count     int
+	Err       error
+}
+
+// Results are the outcome of a diagnosis
+type Results struct {
+	output.DiagnosticResults
+	// CollectErrors are the failures of collectors. The kinds of failed
+	// collectors are missing from CheckedKinds.
+	CollectErrors []error
+	// AnalysisErrors are the failures of individual analyses and fixes,
+	// which leave the other items analyzed
+	AnalysisErrors []error
+}
+
+// Errors returns the collector and analysis errors together
+func (r *Results) Errors() []error {
+	return append(append([]error(nil), r.CollectErrors...), r.AnalysisErrors...)
+}
+
+// Diagnose collects and optionally analyzes the unhealthy objects of a
+// namespace. Failures of single collectors and analyses are recorded in the
+// results rather than aborting the diagnosis; the returned error is set for
+// invalid options, or together with the partial results when ctx is done.
+func Diagnose(ctx context.Context, opts Options) (*Results, error) {
+	kinds, err := selectKinds(opts.Kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := k8s.NewClient(opts.Kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating Kubernetes client: %w", err)
+	}
+	if opts.Namespace != "" {
+		client.SetNamespace(opts.Namespace)
+	}
+
+	results := &Results{DiagnosticResults: output.DiagnosticResults{
+		Namespace: client.GetCurrentNamespace(),
+		Timestamp: time.Now(),
+	}}
+	progress := func(event Event) {
+		if opts.Progress != nil {
+			event.Namespace = results.Namespace
+			opts.Progress(event)
+		}
+	}
+
+	progress(Event{Type: Started})
+
+	collectCtx := ctx
+	if opts.CollectTimeout > 0 {
+		var cancel context.CancelFunc
+		collectCtx, cancel = context.WithTimeout(ctx, opts.CollectTimeout)
+		defer cancel()
+	}
+
+	for _, kind := range Kinds {
+		if !kinds[kind] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		progress(Event{Type: Collecting, Kind: kind})
+		count, err := collect(collectCtx, client, kind, &results.DiagnosticResults)
+		if err != nil {
+			results.CollectErrors = append(results.CollectErrors, err)
+		}
+		progress(Event{Type: Collected, Kind: kind, Count: count, Err: err})
+	}
+
+	results.DiagnosticResults = FilterResults(results.DiagnosticResults, opts.Names)
+	results.DiagnosticResults = FilterObjects(results.DiagnosticResults, opts.Objects)
+
+	if opts.Analyze || opts.Fix {
+		amazonQ := opts.AI
+		if amazonQ == nil {
+			amazonQ = ai.NewAmazonQClient()
+		}
+		maxItems := opts.MaxItems
+		if maxItems <= 0 {
+			maxItems = DefaultMaxItems
+		}
+		concurrency := opts.Concurrency
+		if concurrency <= 0 {
+			concurrency = DefaultConcurrency
+		}
+		analyze(ctx, amazonQ, results, maxItems, concurrency, opts.Fix, progress)
+	}
+
+	return results, ctx.Err()
+}
+
+// selectKinds validates and normalizes Options.Kinds
+func selectKinds(requested []string) (map[string]bool, error) {
+	selected := make(map[string]bool)
+	if len(requested) == 0 {
+		for _, kind := range Kinds {
+			selected[kind] = true
+		}
+		return selected, nil
+	}
+
+	for _, name := range requested {
+		found := false
+		for _, kind := range Kinds {
+			if strings.EqualFold(name, kind) {
+				selected[kind] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unsupported kind %q (expected pod, event, deployment or service)", name)
+		}
+	}
+	return selected, nil
+}
+
+// collect runs the collector of one kind and stores what it found
+func collect(ctx context.Context, client *k8s.Client, kind string, results *output.DiagnosticResults) (int, error) {
+	switch kind {
+	case "Pod":
+		pods, err := client.GetUnhealthyPods(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("getting unhealthy pods: %w", err)
+		}
+		results.UnhealthyPods = pods
+		return len(pods), nil
+	case "Event":
+		events, err := client.GetFailedEvents(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("getting failed events: %w", err)
+		}
+		results.FailedEvents = events
+		return len(events), nil
+	case "Deployment":
+		deployments, err := client.GetMisconfiguredDeployments(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("getting misconfigured deployments: %w", err)
+		}
+		results.MisconfiguredDeployments = deployments
+		return len(deployments), nil
+	case "Service":
+		services, err := client.GetServiceIssues(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("getting service issues: %w", err)
+		}
+		results.ServiceIssues = services
+		return len(services), nil
+	}
+	return 0, fmt.Errorf("unsupported kind %q", kind)
+}
+
+// FilterResults keeps only the objects whose name is in names. Events are
+// matched on the name of the object they refer to. An empty names list keeps
+// everything.
+func FilterResults(results output.DiagnosticResults, names []string) output.DiagnosticResults {
+	if len(names) == 0 {
+		return results
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	return filter(results, func(kind, name string) bool {
+		return wanted[name]
+	})
+}
+
+// FilterObjects keeps only the objects whose name is listed under their kind
+// in objects, so that a pod and a deployment of the same name are told
+// apart. Kinds are matched case-insensitively. Events are matched on the kind
+// and name of the object they refer to. An empty objects map keeps
+// everything.
+func FilterObjects(results output.DiagnosticResults, objects map[string][]string) output.DiagnosticResults {
+	if len(objects) == 0 {
+		return results
+	}
+
+	wanted := make(map[string]bool)
+	for kind, names := range objects {
+		for _, name := range names {
+			wanted[strings.ToLower(kind)+"/"+name] = true
+		}
+	}
+
+	return filter(results, func(kind, name string) bool {
+		return wanted[strings.ToLower(kind)+"/"+name]
+	})
+}
+
+// filter keeps only the objects that keep accepts. Events are passed the kind
+// and name of the object they refer to.
+func filter(results output.DiagnosticResults, keep func(kind, name string) bool) output.DiagnosticResults {
+	filtered := results
+	filtered.UnhealthyPods = nil
+	filtered.MisconfiguredDeployments = nil
+	filtered.ServiceIssues = nil
+	filtered.FailedEvents = nil
+
+	for _, pod := range results.UnhealthyPods {
+		if keep("Pod", pod.Name) {
+			filtered.UnhealthyPods = append(filtered.UnhealthyPods, pod)
+		}
+	}
+
+	for _, deployment := range results.MisconfiguredDeployments {
+		if keep("Deployment", deployment.Name) {
+			filtered.MisconfiguredDeployments = append(filtered.MisconfiguredDeployments, deployment)
+		}
+	}
+
+	for _, service := range results.ServiceIssues {
+		if serviceMap, ok := service.(map[string]interface{}); ok {
+			if name, _ := serviceMap["name"].(string); keep("Service", name) {
+				filtered.ServiceIssues = append(filtered.ServiceIssues, service)
+			}
+		}
+	}
+
+	for _, event := range results.FailedEvents {
+		if eventMap, ok := event.(map[string]interface{}); ok {
+			if involvedObject, ok := eventMap["involvedObject"].(map[string]interface{}); ok {
+				kind, _ := involvedObject["kind"].(string)
+				if name, _ := involvedObject["name"].(string); keep(kind, name) {
+					filtered.FailedEvents = append(filtered.FailedEvents, event)
+				}
+			}
+		}
+	}
+
+	return filtered
+}
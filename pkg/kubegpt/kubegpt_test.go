@@ -0,0 +1,58 @@
+package kubegpt
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/k8s"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+func TestFilterObjects(t *testing.T) {
+	event := func(kind, name string) interface{} {
+		return map[string]interface{}{"involvedObject": map[string]interface{}{"kind": kind, "name": name}}
+	}
+	results := output.DiagnosticResults{
+		UnhealthyPods:            []k8s.PodIssue{{Name: "api"}, {Name: "web"}},
+		MisconfiguredDeployments: []k8s.DeploymentIssue{{Name: "api"}},
+		ServiceIssues:            []interface{}{map[string]interface{}{"name": "api"}},
+		FailedEvents:             []interface{}{event("Pod", "api"), event("Deployment", "api"), event("Pod", "web")},
+	}
+
+	tests := []struct {
+		name    string
+		objects map[string][]string
+		want    output.DiagnosticResults
+	}{
+		{
+			name: "empty keeps everything",
+			want: results,
+		},
+		{
+			name:    "a pod does not select a deployment of the same name",
+			objects: map[string][]string{"pod": {"api"}},
+			want: output.DiagnosticResults{
+				UnhealthyPods: []k8s.PodIssue{{Name: "api"}},
+				FailedEvents:  []interface{}{event("Pod", "api")},
+			},
+		},
+		{
+			name:    "several kinds",
+			objects: map[string][]string{"Deployment": {"api"}, "service": {"api"}, "pod": {"web"}},
+			want: output.DiagnosticResults{
+				UnhealthyPods:            []k8s.PodIssue{{Name: "web"}},
+				MisconfiguredDeployments: []k8s.DeploymentIssue{{Name: "api"}},
+				ServiceIssues:            []interface{}{map[string]interface{}{"name": "api"}},
+				FailedEvents:             []interface{}{event("Deployment", "api"), event("Pod", "web")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FilterObjects(results, tt.objects); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterObjects() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
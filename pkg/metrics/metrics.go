@@ -0,0 +1,139 @@
+// Package metrics exports kubegpt's findings and the latency of its AI and
+// kubectl calls in the Prometheus format, for the long-running watch and serve
+// modes.
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+	"github.com/junioroyewunmi/kubegpt/pkg/k8s"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "kubegpt"
+
+var (
+	openFindings = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "findings_open",
+		Help:      "Number of open findings by namespace, kind, reason and severity.",
+	}, []string{"namespace", "kind", "reason", "severity"})
+
+	lastCollection = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_collection_timestamp_seconds",
+		Help:      "Unix time of the last collection that updated the findings of a namespace.",
+	}, []string{"namespace"})
+
+	aiCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_requests_total",
+		Help:      "Number of AI calls by operation.",
+	}, []string{"operation"})
+
+	aiFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_request_failures_total",
+		Help:      "Number of failed AI calls by operation.",
+	}, []string{"operation"})
+
+	aiDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ai_request_duration_seconds",
+		Help:      "Duration of AI calls by operation.",
+		// AI calls take seconds to minutes
+		Buckets: []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+	}, []string{"operation"})
+
+	kubectlDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kubectl_duration_seconds",
+		Help:      "Duration of kubectl calls by command and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"command", "result"})
+)
+
+var (
+	registry = prometheus.NewRegistry()
+	once     sync.Once
+
+	// mu guards the replacement of the findings of a namespace, which
+	// deletes and re-adds series
+	mu sync.Mutex
+)
+
+// Register registers the metrics and starts observing AI and kubectl calls.
+// It is safe to call more than once.
+func Register() {
+	once.Do(func() {
+		registry.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+			openFindings, lastCollection, aiCalls, aiFailures, aiDuration, kubectlDuration,
+		)
+		ai.SetCallObserver(ObserveAICall)
+		k8s.SetCommandObserver(ObserveKubectl)
+	})
+}
+
+// Handler serves the registered metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// SetFindings replaces the open findings of a namespace for the kinds that
+// were checked. Kinds whose collector did not run or failed keep their last
+// known series rather than dropping to zero.
+func SetFindings(ns string, checked map[string]bool, findings []output.Finding, at time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for kind, ok := range checked {
+		if ok {
+			openFindings.DeletePartialMatch(prometheus.Labels{"namespace": ns, "kind": kind})
+		}
+	}
+	for _, finding := range findings {
+		if checked[finding.Kind] {
+			openFindings.WithLabelValues(ns, finding.Kind, finding.Reason, string(finding.Severity)).Inc()
+		}
+	}
+	lastCollection.WithLabelValues(ns).Set(float64(at.Unix()))
+}
+
+// ObserveAICall records an AI call
+func ObserveAICall(operation string, duration time.Duration, err error) {
+	aiCalls.WithLabelValues(operation).Inc()
+	if err != nil {
+		aiFailures.WithLabelValues(operation).Inc()
+	}
+	aiDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+// ObserveKubectl records a kubectl call
+func ObserveKubectl(args []string, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	kubectlDuration.WithLabelValues(commandLabel(args), result).Observe(duration.Seconds())
+}
+
+// commandLabel reduces kubectl arguments to the verb and, for get, the
+// resource type, so that object names do not end up in label values
+func commandLabel(args []string) string {
+	if len(args) == 0 {
+		return "unknown"
+	}
+	if args[0] == "get" && len(args) > 1 && !strings.HasPrefix(args[1], "-") {
+		return "get " + args[1]
+	}
+	return args[0]
+}
@@ -0,0 +1,166 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Discord limits (https://discord.com/developers/docs/resources/message#embed-object-embed-limits)
+const (
+	discordMaxEmbeds      = 10
+	discordMaxMessageText = 6000
+	discordMaxTitle       = 256
+	discordMaxDescription = 4096
+	discordMaxFieldValue  = 1024
+)
+
+// DiscordNotifier posts notifications to a Discord webhook as embeds
+type DiscordNotifier struct {
+	WebhookURL string
+	HTTPClient *http.Client
+}
+
+// discordMessage is the body of a Discord webhook execution
+type discordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+// discordEmbed is a Discord rich embed
+type discordEmbed struct {
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+	Fields      []discordField `json:"fields,omitempty"`
+}
+
+// discordField is a field of a Discord embed
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// Name identifies the notifier in error messages
+func (d *DiscordNotifier) Name() string {
+	return "Discord"
+}
+
+// Notify posts a notification as a summary embed followed by one embed per
+// finding, split into as many messages as Discord's limits require
+func (d *DiscordNotifier) Notify(ctx context.Context, notification Notification) error {
+	for _, message := range discordMessages(notification) {
+		payload, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		if err := PostJSON(ctx, d.HTTPClient, d.WebhookURL, payload, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discordMessages builds the webhook messages of a notification
+func discordMessages(notification Notification) []discordMessage {
+	summary := discordEmbed{
+		Title:       truncate(notification.Title, discordMaxTitle),
+		Description: truncate(notification.Text, discordMaxDescription),
+		Color:       discordSeverityColor(notification.MaxSeverity()),
+		Timestamp:   notification.Timestamp.Format(time.RFC3339),
+	}
+	if notification.Type == NotificationReport || notification.Type == NotificationAlert {
+		counts := notification.SeverityCounts()
+		summary.Fields = []discordField{
+			{Name: "Namespace", Value: discordValue(notification.Namespace), Inline: true},
+			{Name: "Findings", Value: fmt.Sprint(len(notification.Findings)), Inline: true},
+			{Name: "Critical", Value: fmt.Sprint(counts[string(SeverityCritical)]), Inline: true},
+			{Name: "Warning", Value: fmt.Sprint(counts[string(SeverityWarning)]), Inline: true},
+			{Name: "Info", Value: fmt.Sprint(counts[string(SeverityInfo)]), Inline: true},
+		}
+	}
+
+	embeds := []discordEmbed{summary}
+	for _, finding := range notification.Findings {
+		embeds = append(embeds, discordFindingEmbed(finding))
+	}
+
+	var messages []discordMessage
+	current := discordMessage{}
+	size := 0
+	for _, embed := range embeds {
+		embedSize := discordEmbedSize(embed)
+		if len(current.Embeds) > 0 && (len(current.Embeds) == discordMaxEmbeds || size+embedSize > discordMaxMessageText) {
+			messages = append(messages, current)
+			current = discordMessage{}
+			size = 0
+		}
+		current.Embeds = append(current.Embeds, embed)
+		size += embedSize
+	}
+	return append(messages, current)
+}
+
+// discordFindingEmbed renders a finding, with its analysis and fix, as an embed
+func discordFindingEmbed(finding Finding) discordEmbed {
+	description := finding.Message
+	if finding.Analysis != "" {
+		if description != "" {
+			description += "\n\n"
+		}
+		description += strings.TrimSpace(finding.Analysis)
+	}
+
+	embed := discordEmbed{
+		Title:       truncate(fmt.Sprintf("%s: %s", finding.Object(), finding.Reason), discordMaxTitle),
+		Description: truncate(description, discordMaxDescription),
+		Color:       discordSeverityColor(finding.Severity),
+		Fields: []discordField{
+			{Name: "Severity", Value: string(finding.Severity), Inline: true},
+			{Name: "Fingerprint", Value: discordValue(finding.Fingerprint), Inline: true},
+		},
+	}
+	if finding.SuggestedFix != "" {
+		fix := strings.Trim(stripCodeFence(finding.SuggestedFix), "\n")
+		embed.Fields = append(embed.Fields, discordField{
+			Name:  "Suggested fix",
+			Value: "```yaml\n" + truncate(fix, discordMaxFieldValue-len("```yaml\n\n```")) + "\n```",
+		})
+	}
+	return embed
+}
+
+// discordSeverityColor maps a severity to an embed color
+func discordSeverityColor(severity Severity) int {
+	switch severity {
+	case SeverityCritical:
+		return 0xCF222E
+	case SeverityWarning:
+		return 0xBF8700
+	case SeverityInfo:
+		return 0x0969DA
+	}
+	return 0x1A7F37
+}
+
+// discordValue returns a field value, which Discord requires to be non-empty
+func discordValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return truncate(value, discordMaxFieldValue)
+}
+
+// discordEmbedSize counts the characters Discord includes in its per-message limit
+func discordEmbedSize(embed discordEmbed) int {
+	size := len(embed.Title) + len(embed.Description)
+	for _, field := range embed.Fields {
+		size += len(field.Name) + len(field.Value)
+	}
+	return size
+}
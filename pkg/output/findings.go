@@ -0,0 +1,338 @@
+package output
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+)
+
+// Severity ranks how urgently a finding needs attention
+type Severity string
+
+const (
+	// SeverityInfo is for findings that do not affect availability
+	SeverityInfo Severity = "info"
+	// SeverityWarning is for degraded but still serving resources
+	SeverityWarning Severity = "warning"
+	// SeverityCritical is for resources that are down or cannot start
+	SeverityCritical Severity = "critical"
+)
+
+// Rank orders severities from info (1) to critical (3). Unknown severities rank 0.
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityInfo:
+		return 1
+	case SeverityWarning:
+		return 2
+	case SeverityCritical:
+		return 3
+	}
+	return 0
+}
+
+// ParseSeverity parses a severity name
+func ParseSeverity(name string) (Severity, error) {
+	severity := Severity(strings.ToLower(name))
+	if severity.Rank() == 0 {
+		return "", fmt.Errorf("unknown severity %q (expected info, warning or critical)", name)
+	}
+	return severity, nil
+}
+
+// Finding is a single issue detected on a Kubernetes object. It flattens the
+// per-resource slices of DiagnosticResults into one comparable shape and is
+// the unit of the machine-readable output schema.
+type Finding struct {
+	Fingerprint string             `json:"fingerprint" yaml:"fingerprint"`
+	Kind        string             `json:"kind" yaml:"kind"`
+	Namespace   string             `json:"namespace" yaml:"namespace"`
+	Name        string             `json:"name" yaml:"name"`
+	Labels      map[string]string  `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Severity    Severity           `json:"severity" yaml:"severity"`
+	Reason      string             `json:"reason" yaml:"reason"`
+	Message     string             `json:"message,omitempty" yaml:"message,omitempty"`
+	DetectedAt  time.Time          `json:"detectedAt" yaml:"detectedAt"`
+	Containers  []ContainerFinding `json:"containers,omitempty" yaml:"containers,omitempty"`
+	Replicas    *ReplicaFinding    `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	Event       *EventFinding      `json:"event,omitempty" yaml:"event,omitempty"`
+	// Analysis and SuggestedFix render Diagnosis as markdown and as YAML
+	// patches, for outputs that only show text
+	Analysis     string        `json:"analysis,omitempty" yaml:"analysis,omitempty"`
+	SuggestedFix string        `json:"suggestedFix,omitempty" yaml:"suggestedFix,omitempty"`
+	Diagnosis    *ai.Diagnosis `json:"diagnosis,omitempty" yaml:"diagnosis,omitempty"`
+}
+
+// ContainerFinding is the state of one container of a pod finding
+type ContainerFinding struct {
+	Name     string `json:"name" yaml:"name"`
+	Ready    bool   `json:"ready" yaml:"ready"`
+	Status   string `json:"status" yaml:"status"`
+	Restarts int    `json:"restarts" yaml:"restarts"`
+	Reason   string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Message  string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// ReplicaFinding is the replica status of a deployment finding
+type ReplicaFinding struct {
+	Desired   int `json:"desired" yaml:"desired"`
+	Ready     int `json:"ready" yaml:"ready"`
+	Updated   int `json:"updated" yaml:"updated"`
+	Available int `json:"available" yaml:"available"`
+}
+
+// EventFinding holds the event specific fields of an event finding
+type EventFinding struct {
+	Type     string `json:"type" yaml:"type"`
+	Count    int    `json:"count,omitempty" yaml:"count,omitempty"`
+	LastSeen string `json:"lastSeen,omitempty" yaml:"lastSeen,omitempty"`
+}
+
+// fingerprint returns a stable identifier for a finding. It is derived from
+// the object identity, so a pod that moves from one failure reason to another
+// keeps the same fingerprint. Events are keyed by reason as well, since one
+// object can carry several unrelated warning events.
+func fingerprint(kind, namespace, name, reason string) string {
+	key := fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+	if kind == "Event" {
+		key += "/" + reason
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// setDiagnosis parses the analysis and fix responses of the AI into the
+// finding's diagnosis and its text forms
+func (f *Finding) setDiagnosis(analysis, fix string) {
+	if analysis == "" && fix == "" {
+		return
+	}
+
+	var diagnosis ai.Diagnosis
+	if analysis != "" {
+		diagnosis = *ai.ParseDiagnosis(analysis)
+		f.Analysis = diagnosis.Markdown()
+	}
+	if fix != "" {
+		fixDiagnosis := ai.ParseDiagnosis(fix)
+		diagnosis = diagnosis.Merge(fixDiagnosis)
+		f.SuggestedFix = fixDiagnosis.FixText()
+	}
+	f.Diagnosis = &diagnosis
+}
+
+// Object returns the finding's object reference in kind/namespace/name form
+func (f Finding) Object() string {
+	return fmt.Sprintf("%s %s/%s", f.Kind, f.Namespace, f.Name)
+}
+
+// criticalContainerReasons are container states that keep a pod from serving
+var criticalContainerReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"OOMKilled":                  true,
+	"RunContainerError":          true,
+}
+
+// Findings flattens the diagnostic results into a list of findings
+func (r DiagnosticResults) Findings() []Finding {
+	var findings []Finding
+
+	for _, pod := range r.UnhealthyPods {
+		finding := Finding{
+			Kind:       "Pod",
+			Namespace:  namespaceOr(pod.Namespace, r.Namespace),
+			Name:       pod.Name,
+			Labels:     pod.Labels,
+			Severity:   SeverityWarning,
+			Reason:     pod.Reason,
+			Message:    pod.Message,
+			DetectedAt: r.Timestamp,
+		}
+		finding.setDiagnosis(pod.Analysis, pod.Fix)
+
+		if pod.Status == "Failed" {
+			finding.Severity = SeverityCritical
+		}
+
+		// The pod-level reason is usually empty, so fall back to the first
+		// container that reports one
+		for _, container := range pod.Containers {
+			if finding.Reason == "" && container.Reason != "" {
+				finding.Reason = container.Reason
+				finding.Message = container.Message
+			}
+			if criticalContainerReasons[container.Reason] {
+				finding.Severity = SeverityCritical
+			}
+			finding.Containers = append(finding.Containers, ContainerFinding{
+				Name:     container.Name,
+				Ready:    container.Ready,
+				Status:   container.Status,
+				Restarts: container.Restarts,
+				Reason:   container.Reason,
+				Message:  container.Message,
+			})
+		}
+		if finding.Reason == "" {
+			finding.Reason = pod.Status
+		}
+
+		finding.Fingerprint = fingerprint(finding.Kind, finding.Namespace, finding.Name, finding.Reason)
+		findings = append(findings, finding)
+	}
+
+	for _, deployment := range r.MisconfiguredDeployments {
+		finding := Finding{
+			Kind:       "Deployment",
+			Namespace:  namespaceOr(deployment.Namespace, r.Namespace),
+			Name:       deployment.Name,
+			Labels:     deployment.Labels,
+			Severity:   SeverityWarning,
+			Reason:     deployment.Reason,
+			Message:    deployment.Message,
+			DetectedAt: r.Timestamp,
+			Replicas: &ReplicaFinding{
+				Desired:   deployment.Replicas,
+				Ready:     deployment.ReadyReplicas,
+				Updated:   deployment.UpdatedReplicas,
+				Available: deployment.AvailableReplicas,
+			},
+		}
+		finding.setDiagnosis(deployment.Analysis, deployment.Fix)
+
+		// No ready replicas at all means the workload is down, while a fully
+		// ready deployment is only flagged for its conditions
+		if deployment.Replicas > 0 && deployment.ReadyReplicas == 0 {
+			finding.Severity = SeverityCritical
+		} else if deployment.ReadyReplicas >= deployment.Replicas {
+			finding.Severity = SeverityInfo
+		}
+
+		finding.Fingerprint = fingerprint(finding.Kind, finding.Namespace, finding.Name, finding.Reason)
+		findings = append(findings, finding)
+	}
+
+	for _, service := range r.ServiceIssues {
+		serviceMap, ok := service.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		// A service without endpoints drops all of its traffic
+		finding := Finding{
+			Kind:       "Service",
+			Namespace:  namespaceOr(stringField(serviceMap, "namespace"), r.Namespace),
+			Name:       stringField(serviceMap, "name"),
+			Labels:     labelsField(serviceMap, "labels"),
+			Severity:   SeverityCritical,
+			Reason:     stringField(serviceMap, "issue"),
+			Message:    stringField(serviceMap, "message"),
+			DetectedAt: r.Timestamp,
+		}
+
+		finding.Fingerprint = fingerprint(finding.Kind, finding.Namespace, finding.Name, finding.Reason)
+		findings = append(findings, finding)
+	}
+
+	for _, event := range r.FailedEvents {
+		eventMap, ok := event.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		object := stringField(eventMap, "object")
+		if object == "" {
+			if involvedObject, ok := eventMap["involvedObject"].(map[string]interface{}); ok {
+				object = fmt.Sprintf("%s/%s", stringField(involvedObject, "kind"), stringField(involvedObject, "name"))
+			}
+		}
+
+		finding := Finding{
+			Kind:       "Event",
+			Namespace:  namespaceOr(stringField(eventMap, "namespace"), r.Namespace),
+			Name:       object,
+			Severity:   SeverityWarning,
+			Reason:     stringField(eventMap, "reason"),
+			Message:    strings.TrimSpace(stringField(eventMap, "message")),
+			DetectedAt: r.Timestamp,
+			Event: &EventFinding{
+				Type:     stringField(eventMap, "type"),
+				LastSeen: stringField(eventMap, "lastTimestamp"),
+			},
+		}
+		if count, ok := eventMap["count"].(float64); ok {
+			finding.Event.Count = int(count)
+		}
+
+		finding.Fingerprint = fingerprint(finding.Kind, finding.Namespace, finding.Name, finding.Reason)
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
+
+// CheckedKinds returns the finding kinds whose collector ran successfully.
+// Collectors that failed or were not run leave their slice nil, so their
+// absence of findings says nothing about the cluster.
+func (r DiagnosticResults) CheckedKinds() map[string]bool {
+	return map[string]bool{
+		"Pod":        r.UnhealthyPods != nil,
+		"Deployment": r.MisconfiguredDeployments != nil,
+		"Service":    r.ServiceIssues != nil,
+		"Event":      r.FailedEvents != nil,
+	}
+}
+
+// AIFallbacks returns the number of findings diagnosed by the built-in rules
+// because the AI could not be used
+func (r DiagnosticResults) AIFallbacks() int {
+	fallbacks := 0
+	for _, finding := range r.Findings() {
+		if finding.Diagnosis != nil && finding.Diagnosis.Fallback != "" {
+			fallbacks++
+		}
+	}
+	return fallbacks
+}
+
+// stringField reads a string value from a loosely typed kubectl JSON map
+func stringField(m map[string]interface{}, key string) string {
+	if value, ok := m[key].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// labelsField reads a label map from a loosely typed kubectl JSON map, which
+// holds either the collector's map[string]string or decoded JSON
+func labelsField(m map[string]interface{}, key string) map[string]string {
+	switch labels := m[key].(type) {
+	case map[string]string:
+		return labels
+	case map[string]interface{}:
+		result := make(map[string]string, len(labels))
+		for name, value := range labels {
+			if text, ok := value.(string); ok {
+				result[name] = text
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// namespaceOr returns namespace, or fallback if namespace is empty
+func namespaceOr(namespace, fallback string) string {
+	if namespace != "" {
+		return namespace
+	}
+	return fallback
+}
@@ -0,0 +1,177 @@
+package output
+
+import (
+	"bytes"
+	_ "embed"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed templates/report.html
+var htmlReportTemplate string
+
+// htmlSection is one collapsible per-resource section of the HTML report
+type htmlSection struct {
+	ID       string
+	Title    string
+	Findings []Finding
+}
+
+// htmlReport is the data passed to the HTML report template
+type htmlReport struct {
+	Report
+	Sections []htmlSection
+}
+
+// markdownRenderer renders AI analysis. Raw HTML in the analysis is dropped
+// rather than passed through, since it comes from a model and not from us.
+var markdownRenderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// GenerateHTMLReport renders the results as a single self-contained HTML page
+// with embedded CSS and JavaScript, so it can be mailed or attached to a
+// ticket and opened offline. errs are the non-fatal errors of the run.
+func GenerateHTMLReport(results DiagnosticResults, errs []error) (string, error) {
+	data := htmlReport{Report: NewReport(results, errs)}
+
+	for _, check := range []struct {
+		id, kind, title string
+	}{
+		{"pods", "Pod", "Unhealthy Pods"},
+		{"deployments", "Deployment", "Misconfigured Deployments"},
+		{"services", "Service", "Service Issues"},
+		{"events", "Event", "Warning Events"},
+	} {
+		section := htmlSection{ID: check.id, Title: check.title}
+		for _, finding := range data.Findings {
+			if finding.Kind == check.kind {
+				section.Findings = append(section.Findings, finding)
+			}
+		}
+		data.Sections = append(data.Sections, section)
+	}
+
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"markdown": renderMarkdown,
+		"fix":      renderFix,
+	}).Parse(htmlReportTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// renderMarkdown converts AI analysis markdown to HTML
+func renderMarkdown(source string) template.HTML {
+	var buf bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(source), &buf); err != nil {
+		return template.HTML("<pre>" + html.EscapeString(source) + "</pre>")
+	}
+	return template.HTML(buf.String())
+}
+
+var (
+	codeFence  = regexp.MustCompile("(?s)```([\\w-]*)[ \t]*\n(.*?)```")
+	yamlKey    = regexp.MustCompile(`^(\s*(?:-\s+)?)([^\s#:'"][^#:]*?|"[^"]*"|'[^']*'):(\s|$)`)
+	yamlScalar = regexp.MustCompile(`^(?:-?\d+(?:\.\d+)?|true|false|null|~)$`)
+)
+
+// renderFix renders a suggested fix. A bare YAML manifest is highlighted as a
+// whole; otherwise the fix is treated as markdown whose YAML code blocks are
+// highlighted and whose prose is rendered around them.
+func renderFix(source string) template.HTML {
+	if !codeFence.MatchString(source) {
+		var manifest interface{}
+		if err := yaml.Unmarshal([]byte(source), &manifest); err == nil {
+			switch manifest.(type) {
+			case map[string]interface{}, []interface{}:
+				return yamlBlock(source)
+			}
+		}
+		return renderMarkdown(source)
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, match := range codeFence.FindAllStringSubmatchIndex(source, -1) {
+		sb.WriteString(string(renderMarkdown(source[last:match[0]])))
+		language := source[match[2]:match[3]]
+		code := source[match[4]:match[5]]
+		if language == "" || language == "yaml" || language == "yml" {
+			sb.WriteString(string(yamlBlock(code)))
+		} else {
+			sb.WriteString("<pre><code>" + html.EscapeString(strings.Trim(code, "\n")) + "</code></pre>")
+		}
+		last = match[1]
+	}
+	sb.WriteString(string(renderMarkdown(source[last:])))
+	return template.HTML(sb.String())
+}
+
+// yamlBlock renders YAML as a highlighted code block. Keys, scalars and
+// comments are wrapped in spans styled by the report's CSS.
+func yamlBlock(source string) template.HTML {
+	var sb strings.Builder
+	sb.WriteString(`<pre class="yaml"><code>`)
+	for i, line := range strings.Split(strings.Trim(source, "\n"), "\n") {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(highlightYAMLLine(line))
+	}
+	sb.WriteString("</code></pre>")
+	return template.HTML(sb.String())
+}
+
+// highlightYAMLLine highlights a single line of YAML
+func highlightYAMLLine(line string) string {
+	if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+		return `<span class="y-c">` + html.EscapeString(line) + `</span>`
+	}
+
+	var sb strings.Builder
+	value := line
+	if match := yamlKey.FindStringSubmatch(line); match != nil {
+		sb.WriteString(html.EscapeString(match[1]))
+		sb.WriteString(`<span class="y-k">` + html.EscapeString(match[2]) + `</span>:`)
+		value = line[len(match[0])-len(match[3]):]
+	} else if indent := len(line) - len(strings.TrimLeft(line, " ")); strings.HasPrefix(line[indent:], "- ") {
+		sb.WriteString(html.EscapeString(line[:indent+2]))
+		value = line[indent+2:]
+	}
+
+	// Split off a trailing comment, which needs whitespace before the #
+	comment := ""
+	if index := strings.Index(value, " #"); index >= 0 && !strings.ContainsAny(value[:index], `"'`) {
+		value, comment = value[:index], value[index:]
+	}
+
+	leading := value[:len(value)-len(strings.TrimLeft(value, " "))]
+	scalar := strings.TrimSpace(value)
+	sb.WriteString(leading)
+	switch {
+	case scalar == "":
+	case strings.HasPrefix(scalar, `"`) || strings.HasPrefix(scalar, "'"):
+		sb.WriteString(`<span class="y-s">` + html.EscapeString(scalar) + `</span>`)
+	case yamlScalar.MatchString(scalar):
+		sb.WriteString(`<span class="y-n">` + html.EscapeString(scalar) + `</span>`)
+	default:
+		sb.WriteString(html.EscapeString(scalar))
+	}
+	sb.WriteString(value[len(leading)+len(scalar):])
+
+	if comment != "" {
+		sb.WriteString(`<span class="y-c">` + html.EscapeString(comment) + `</span>`)
+	}
+	return sb.String()
+}
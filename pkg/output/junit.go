@@ -0,0 +1,162 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// junitChecks are the resource checks of a diagnostic run, in report order
+var junitChecks = []struct {
+	name string
+	kind string
+}{
+	{"pods", "Pod"},
+	{"deployments", "Deployment"},
+	{"services", "Service"},
+	{"events", "Event"},
+}
+
+// GenerateJUnitReport renders the results as JUnit XML with one test suite
+// per resource check and one test case per finding. Findings below threshold
+// are reported as passing test cases; an empty threshold fails every finding.
+// Checks that ran without findings get a single passing test case so that the
+// check is still visible in CI. errs are reported as test errors.
+func GenerateJUnitReport(results DiagnosticResults, threshold Severity, errs []error) (string, error) {
+	findings := results.Findings()
+	ran := results.CheckedKinds()
+	timestamp := results.Timestamp.Format("2006-01-02T15:04:05")
+
+	report := junitTestSuites{Name: fmt.Sprintf("kubegpt %s", results.Namespace)}
+
+	for _, check := range junitChecks {
+		suite := junitTestSuite{
+			Name:      fmt.Sprintf("%s/%s", results.Namespace, check.name),
+			Timestamp: timestamp,
+		}
+
+		for _, finding := range findings {
+			if finding.Kind != check.kind {
+				continue
+			}
+
+			testCase := junitTestCase{
+				Name:      fmt.Sprintf("%s %s", finding.Kind, finding.Name),
+				ClassName: fmt.Sprintf("kubegpt.%s.%s", finding.Namespace, check.name),
+				SystemOut: finding.Analysis,
+			}
+
+			if threshold == "" || finding.Severity.Rank() >= threshold.Rank() {
+				testCase.Failure = &junitFailure{
+					Message: fmt.Sprintf("%s: %s", finding.Reason, finding.Message),
+					Type:    string(finding.Severity),
+					Body:    junitFailureBody(finding),
+				}
+				suite.Failures++
+			}
+
+			suite.Cases = append(suite.Cases, testCase)
+		}
+
+		if len(suite.Cases) == 0 && ran[check.kind] {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      fmt.Sprintf("no unhealthy %s", check.name),
+				ClassName: fmt.Sprintf("kubegpt.%s.%s", results.Namespace, check.name),
+			})
+		}
+
+		if len(suite.Cases) == 0 {
+			continue
+		}
+
+		suite.Tests = len(suite.Cases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if len(errs) > 0 {
+		suite := junitTestSuite{
+			Name:      fmt.Sprintf("%s/errors", results.Namespace),
+			Timestamp: timestamp,
+		}
+		for i, err := range errs {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      fmt.Sprintf("error %d", i+1),
+				ClassName: fmt.Sprintf("kubegpt.%s.errors", results.Namespace),
+				Error:     &junitFailure{Message: err.Error(), Type: "error"},
+			})
+		}
+		suite.Tests = len(suite.Cases)
+		suite.Errors = len(suite.Cases)
+		report.Tests += suite.Tests
+		report.Errors += suite.Errors
+		report.Suites = append(report.Suites, suite)
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return xml.Header + string(data) + "\n", nil
+}
+
+// junitFailureBody is the detailed failure text of a finding
+func junitFailureBody(finding Finding) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("Object: %s\n", finding.Object()))
+	sb.WriteString(fmt.Sprintf("Severity: %s\n", finding.Severity))
+	sb.WriteString(fmt.Sprintf("Reason: %s\n", finding.Reason))
+	if finding.Message != "" {
+		sb.WriteString(fmt.Sprintf("Message: %s\n", finding.Message))
+	}
+	for _, container := range finding.Containers {
+		sb.WriteString(fmt.Sprintf("Container %s: %s (Restarts: %d)", container.Name, container.Status, container.Restarts))
+		if container.Reason != "" {
+			sb.WriteString(", " + container.Reason)
+		}
+		sb.WriteString("\n")
+	}
+	if finding.SuggestedFix != "" {
+		sb.WriteString("\nSuggested Fix:\n")
+		sb.WriteString(finding.SuggestedFix)
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
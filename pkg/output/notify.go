@@ -0,0 +1,183 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NotificationType describes why a notification is sent
+type NotificationType string
+
+const (
+	// NotificationReport is the result of a diagnose or report run
+	NotificationReport NotificationType = "report"
+	// NotificationOpened is sent by watch when an issue opens
+	NotificationOpened NotificationType = "opened"
+	// NotificationChanged is sent by watch when an open issue changes
+	NotificationChanged NotificationType = "changed"
+	// NotificationResolved is sent by watch when an issue resolves
+	NotificationResolved NotificationType = "resolved"
+	// NotificationAnalysis carries the AI analysis of an open issue
+	NotificationAnalysis NotificationType = "analysis"
+	// NotificationAlert is the diagnosis of an Alertmanager alert
+	NotificationAlert NotificationType = "alert"
+)
+
+// Notification is a message for a notifier. Findings carry their analysis
+// and suggested fix, if any.
+type Notification struct {
+	Type      NotificationType `json:"type" yaml:"type"`
+	Title     string           `json:"title" yaml:"title"`
+	Text      string           `json:"text,omitempty" yaml:"text,omitempty"`
+	Namespace string           `json:"namespace" yaml:"namespace"`
+	Timestamp time.Time        `json:"timestamp" yaml:"timestamp"`
+	Findings  []Finding        `json:"findings" yaml:"findings"`
+	// ThreadKey groups notifications about the same subject, such as the
+	// fingerprint of an Alertmanager alert. Threaded notifiers reply to the
+	// first notification with the same key until one resolves it.
+	ThreadKey string `json:"threadKey,omitempty" yaml:"threadKey,omitempty"`
+}
+
+// Notifier delivers notifications to a chat or incident tool
+type Notifier interface {
+	// Name identifies the notifier in error messages
+	Name() string
+	// Notify delivers a notification
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// NewReportNotification builds the notification for a diagnostic run
+func NewReportNotification(results DiagnosticResults) Notification {
+	findings := results.Findings()
+	if findings == nil {
+		findings = []Finding{}
+	}
+
+	return Notification{
+		Type:      NotificationReport,
+		Title:     fmt.Sprintf("Kubernetes Diagnostic Report: %s", results.Namespace),
+		Namespace: results.Namespace,
+		Timestamp: results.Timestamp,
+		Findings:  findings,
+	}
+}
+
+// SeverityCounts counts the findings of the notification by severity name,
+// like the bySeverity summary of reports
+func (n Notification) SeverityCounts() map[string]int {
+	counts := map[string]int{string(SeverityCritical): 0, string(SeverityWarning): 0, string(SeverityInfo): 0}
+	for _, finding := range n.Findings {
+		counts[string(finding.Severity)]++
+	}
+	return counts
+}
+
+// MaxSeverity returns the highest severity among the findings, or an empty
+// severity if there are none
+func (n Notification) MaxSeverity() Severity {
+	var max Severity
+	for _, finding := range n.Findings {
+		if finding.Severity.Rank() > max.Rank() {
+			max = finding.Severity
+		}
+	}
+	return max
+}
+
+// NotifyAll delivers a notification to every notifier. A failing notifier
+// does not keep the others from being notified; all failures are returned.
+func NotifyAll(ctx context.Context, notifiers []Notifier, notification Notification) []error {
+	var errs []error
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(ctx, notification); err != nil {
+			errs = append(errs, fmt.Errorf("sending to %s: %w", notifier.Name(), err))
+		}
+	}
+	return errs
+}
+
+// Dispatcher decides which notifiers receive a notification and delivers it.
+// It returns the number of messages delivered and the failures.
+type Dispatcher interface {
+	Dispatch(ctx context.Context, notification Notification) (int, []error)
+}
+
+// Broadcast is a dispatcher that delivers every notification to all of its
+// notifiers
+type Broadcast []Notifier
+
+// Dispatch delivers the notification to every notifier
+func (b Broadcast) Dispatch(ctx context.Context, notification Notification) (int, []error) {
+	errs := NotifyAll(ctx, b, notification)
+	return len(b) - len(errs), errs
+}
+
+// Dispatchers is a dispatcher that hands every notification to each of its
+// dispatchers in turn
+type Dispatchers []Dispatcher
+
+// Dispatch hands the notification to every dispatcher
+func (d Dispatchers) Dispatch(ctx context.Context, notification Notification) (int, []error) {
+	var sent int
+	var errs []error
+	for _, dispatcher := range d {
+		n, dispatchErrs := dispatcher.Dispatch(ctx, notification)
+		sent += n
+		errs = append(errs, dispatchErrs...)
+	}
+	return sent, errs
+}
+
+// PostJSON posts a JSON payload with optional extra headers and fails on
+// non-2xx responses. Requests without a client time out after 30 seconds.
+// Errors name the endpoint by its scheme and host only, since webhook URLs
+// often embed tokens.
+func PostJSON(ctx context.Context, client *http.Client, endpoint string, payload []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("invalid URL %s", RedactURL(endpoint))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("%s %s: %w", urlErr.Op, RedactURL(endpoint), urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var body bytes.Buffer
+		body.ReadFrom(resp.Body)
+		if message := strings.TrimSpace(body.String()); message != "" {
+			return fmt.Errorf("unexpected response %s: %s", resp.Status, TruncateRunes(message, 200))
+		}
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return nil
+}
+
+// RedactURL shortens a URL to its scheme and host, leaving out the path,
+// query and credentials, which may hold tokens
+func RedactURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return "<invalid URL>"
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
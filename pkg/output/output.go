@@ -1,14 +1,14 @@
 package output
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"net/http"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
 	"github.com/junioroyewunmi/kubegpt/pkg/k8s"
 )
 
@@ -34,6 +34,9 @@ func PrintTerminalOutput(results DiagnosticResults) {
 	fmt.Printf("- Failed Events: %d\n", len(results.FailedEvents))
 	fmt.Printf("- Misconfigured Deployments: %d\n", len(results.MisconfiguredDeployments))
 	fmt.Printf("- Service Issues: %d\n", len(results.ServiceIssues))
+	if fallbacks := results.AIFallbacks(); fallbacks > 0 {
+		color.New(color.FgYellow).Printf("- AI unavailable: %d finding(s) diagnosed by built-in rules\n", fallbacks)
+	}
 	fmt.Println()
 
 	// Print unhealthy pods
@@ -61,17 +64,13 @@ func PrintTerminalOutput(results DiagnosticResults) {
 			if pod.Analysis != "" {
 				fmt.Println()
 				fmt.Println("    Analysis:")
-				for _, line := range strings.Split(pod.Analysis, "\n") {
-					fmt.Printf("    %s\n", line)
-				}
+				PrintDiagnosis(ai.ParseDiagnosis(pod.Analysis), "    ")
 			}
 
 			if pod.Fix != "" {
 				fmt.Println()
 				fmt.Println("    Suggested Fix:")
-				for _, line := range strings.Split(pod.Fix, "\n") {
-					fmt.Printf("    %s\n", line)
-				}
+				PrintDiagnosis(ai.ParseDiagnosis(pod.Fix), "    ")
 			}
 
 			fmt.Println()
@@ -97,17 +96,13 @@ func PrintTerminalOutput(results DiagnosticResults) {
 			if deployment.Analysis != "" {
 				fmt.Println()
 				fmt.Println("    Analysis:")
-				for _, line := range strings.Split(deployment.Analysis, "\n") {
-					fmt.Printf("    %s\n", line)
-				}
+				PrintDiagnosis(ai.ParseDiagnosis(deployment.Analysis), "    ")
 			}
 
 			if deployment.Fix != "" {
 				fmt.Println()
 				fmt.Println("    Suggested Fix:")
-				for _, line := range strings.Split(deployment.Fix, "\n") {
-					fmt.Printf("    %s\n", line)
-				}
+				PrintDiagnosis(ai.ParseDiagnosis(deployment.Fix), "    ")
 			}
 
 			fmt.Println()
@@ -115,82 +110,62 @@ func PrintTerminalOutput(results DiagnosticResults) {
 	}
 }
 
-// GenerateMarkdownReport generates a markdown report from the diagnostic results
-func GenerateMarkdownReport(results DiagnosticResults) string {
-	var sb strings.Builder
-
-	sb.WriteString(fmt.Sprintf("# Kubernetes Diagnostic Report\n\n"))
-	sb.WriteString(fmt.Sprintf("**Namespace:** %s  \n", results.Namespace))
-	sb.WriteString(fmt.Sprintf("**Time:** %s  \n\n", results.Timestamp.Format("Mon, 02 Jan 2006 15:04:05 MST")))
-
-	// Summary
-	sb.WriteString("## Summary\n\n")
-	sb.WriteString(fmt.Sprintf("- Unhealthy Pods: %d\n", len(results.UnhealthyPods)))
-	sb.WriteString(fmt.Sprintf("- Failed Events: %d\n", len(results.FailedEvents)))
-	sb.WriteString(fmt.Sprintf("- Misconfigured Deployments: %d\n", len(results.MisconfiguredDeployments)))
-	sb.WriteString(fmt.Sprintf("- Service Issues: %d\n\n", len(results.ServiceIssues)))
-
-	// Unhealthy pods
-	if len(results.UnhealthyPods) > 0 {
-		sb.WriteString("## Unhealthy Pods\n\n")
-
-		for i, pod := range results.UnhealthyPods {
-			sb.WriteString(fmt.Sprintf("### %d. Pod: %s\n\n", i+1, pod.Name))
-			sb.WriteString(fmt.Sprintf("**Status:** %s  \n", pod.Status))
-
-			if len(pod.Containers) > 0 {
-				sb.WriteString("**Container Issues:**  \n")
-				for _, container := range pod.Containers {
-					sb.WriteString(fmt.Sprintf("- %s: %s (Restarts: %d)  \n", container.Name, container.Status, container.Restarts))
-					if container.Reason != "" {
-						sb.WriteString(fmt.Sprintf("  - Reason: %s  \n", container.Reason))
-					}
-					if container.Message != "" {
-						sb.WriteString(fmt.Sprintf("  - Message: %s  \n", container.Message))
-					}
-				}
-			}
-
-			if pod.Analysis != "" {
-				sb.WriteString("\n**Analysis:**  \n")
-				sb.WriteString(fmt.Sprintf("```\n%s\n```\n\n", pod.Analysis))
-			}
-
-			if pod.Fix != "" {
-				sb.WriteString("**Suggested Fix:**  \n")
-				sb.WriteString(fmt.Sprintf("```\n%s\n```\n\n", pod.Fix))
-			}
+// PrintDiagnosis prints the summary, root causes, commands and patches of a
+// diagnosis to the terminal, each line prefixed with indent. A response that
+// did not follow the response format is printed as it is.
+func PrintDiagnosis(diagnosis *ai.Diagnosis, indent string) {
+	heading := color.New(color.FgWhite, color.Bold)
+	printLines := func(text, prefix string) {
+		for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+			fmt.Printf("%s%s%s\n", indent, prefix, line)
 		}
 	}
 
-	// Misconfigured deployments
-	if len(results.MisconfiguredDeployments) > 0 {
-		sb.WriteString("## Misconfigured Deployments\n\n")
-
-		for i, deployment := range results.MisconfiguredDeployments {
-			sb.WriteString(fmt.Sprintf("### %d. Deployment: %s\n\n", i+1, deployment.Name))
-			sb.WriteString(fmt.Sprintf("**Replicas:** %d/%d ready  \n", deployment.ReadyReplicas, deployment.Replicas))
-			
-			if deployment.Reason != "" {
-				sb.WriteString(fmt.Sprintf("**Reason:** %s  \n", deployment.Reason))
-			}
-			if deployment.Message != "" {
-				sb.WriteString(fmt.Sprintf("**Message:** %s  \n", deployment.Message))
-			}
-
-			if deployment.Analysis != "" {
-				sb.WriteString("\n**Analysis:**  \n")
-				sb.WriteString(fmt.Sprintf("```\n%s\n```\n\n", deployment.Analysis))
-			}
+	if !diagnosis.Structured {
+		printLines(strings.TrimSpace(diagnosis.Raw), "")
+		return
+	}
 
-			if deployment.Fix != "" {
-				sb.WriteString("**Suggested Fix:**  \n")
-				sb.WriteString(fmt.Sprintf("```\n%s\n```\n\n", deployment.Fix))
+	if note := diagnosis.FallbackNote(); note != "" {
+		color.New(color.FgYellow).Printf("%s%s\n", indent, note)
+	}
+	if diagnosis.Summary != "" {
+		printLines(diagnosis.Summary, "")
+	}
+	if len(diagnosis.RootCauses) > 0 {
+		fmt.Println()
+		heading.Printf("%sRoot causes:\n", indent)
+		for _, cause := range diagnosis.RootCauses {
+			if cause.Confidence > 0 {
+				fmt.Printf("%s- %s (%.0f%% confidence)\n", indent, cause.Cause, cause.Confidence*100)
+			} else {
+				fmt.Printf("%s- %s\n", indent, cause.Cause)
 			}
 		}
 	}
-
-	return sb.String()
+	if len(diagnosis.DiagnosticCommands) > 0 {
+		fmt.Println()
+		heading.Printf("%sDiagnostic commands (read-only):\n", indent)
+		for _, command := range diagnosis.DiagnosticCommands {
+			fmt.Printf("%s  $ %s\n", indent, command)
+		}
+	}
+	if len(diagnosis.RemediationCommands) > 0 {
+		fmt.Println()
+		heading.Printf("%sRemediation commands:\n", indent)
+		for _, command := range diagnosis.RemediationCommands {
+			fmt.Printf("%s  $ %s\n", indent, command)
+		}
+	}
+	for _, patch := range diagnosis.Patches {
+		fmt.Println()
+		if description := patch.Heading(); description != "" {
+			heading.Printf("%sPatch (%s):\n", indent, description)
+		} else {
+			heading.Printf("%sPatch:\n", indent)
+		}
+		printLines(patch.YAML, "  ")
+	}
 }
 
 // WriteToFile writes content to a file
@@ -198,27 +173,15 @@ func WriteToFile(filename, content string) error {
 	return os.WriteFile(filename, []byte(content), 0644)
 }
 
-// SendToSlack sends the report to a Slack webhook
+// SendToSlack sends the report to a Slack webhook as Block Kit messages
 func SendToSlack(webhookURL string, results DiagnosticResults) error {
-	// Generate a simple markdown report
-	report := GenerateMarkdownReport(results)
-	
-	// Create a simple payload
-	payload := fmt.Sprintf(`{"text": "Kubernetes Diagnostic Report for namespace %s", "blocks": [{"type": "section", "text": {"type": "mrkdwn", "text": "%s"}}]}`, 
-		results.Namespace, 
-		strings.ReplaceAll(report, "\n", "\\n"),
-	)
-	
-	// Send the request
-	resp, err := http.Post(webhookURL, "application/json", bytes.NewBuffer([]byte(payload)))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to send to Slack: %s", resp.Status)
-	}
-	
-	return nil
-}
\ No newline at end of file
+	client := &SlackClient{WebhookURL: webhookURL}
+	return client.PostReport(context.Background(), results)
+}
+
+// SendSlackText sends a mrkdwn message to a Slack webhook
+func SendSlackText(webhookURL, text string) error {
+	client := &SlackClient{WebhookURL: webhookURL}
+	_, err := client.PostText(context.Background(), text, "")
+	return err
+}
@@ -0,0 +1,142 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// SARIF 2.1.0 document types, limited to the fields KubeGPT emits
+// (https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html)
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Version        string      `json:"version,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]string `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifLevels maps finding severities to SARIF result levels
+var sarifLevels = map[Severity]string{
+	SeverityCritical: "error",
+	SeverityWarning:  "warning",
+	SeverityInfo:     "note",
+}
+
+// GenerateSARIFReport renders the findings about manifest-backed objects
+// (pods, deployments and services) as a SARIF 2.1.0 log. Events describe
+// runtime history rather than a manifest and are left out. Each distinct
+// reason becomes a rule, and objects are located by their logical
+// namespace/Kind/name path.
+func GenerateSARIFReport(results DiagnosticResults, toolVersion string) (string, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "kubegpt",
+			InformationURI: "https://github.com/junioroyewunmi/kubegpt",
+			Version:        toolVersion,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := make(map[string]sarifRule)
+	for _, finding := range results.Findings() {
+		if finding.Kind == "Event" {
+			continue
+		}
+
+		ruleID := fmt.Sprintf("%s/%s", finding.Kind, finding.Reason)
+		if _, ok := rules[ruleID]; !ok {
+			rules[ruleID] = sarifRule{
+				ID:               ruleID,
+				Name:             finding.Reason,
+				ShortDescription: sarifMessage{Text: fmt.Sprintf("%s reports %s", finding.Kind, finding.Reason)},
+			}
+		}
+
+		text := fmt.Sprintf("%s: %s", finding.Object(), finding.Reason)
+		if finding.Message != "" {
+			text += " - " + finding.Message
+		}
+
+		result := sarifResult{
+			RuleID:  ruleID,
+			Level:   sarifLevels[finding.Severity],
+			Message: sarifMessage{Text: text},
+			Locations: []sarifLocation{{LogicalLocations: []sarifLogicalLocation{{
+				Name:               finding.Name,
+				FullyQualifiedName: fmt.Sprintf("%s/%s/%s", finding.Namespace, finding.Kind, finding.Name),
+				Kind:               "object",
+			}}}},
+			PartialFingerprints: map[string]string{"kubegpt/v1": finding.Fingerprint},
+		}
+		if finding.SuggestedFix != "" {
+			result.Properties = map[string]string{"suggestedFix": finding.SuggestedFix}
+		}
+
+		run.Results = append(run.Results, result)
+	}
+
+	for _, rule := range rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,142 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+	"gopkg.in/yaml.v3"
+)
+
+// APIVersion identifies the machine-readable output schema. Fields may be
+// added within a version; renaming or removing a field, or changing its
+// meaning, requires a new version. See docs/output-schema.md.
+const APIVersion = "kubegpt/v1"
+
+// Document kinds
+const (
+	KindDiagnosticReport = "DiagnosticReport"
+	KindExplanation      = "Explanation"
+)
+
+// Report is the machine-readable form of DiagnosticResults
+type Report struct {
+	APIVersion  string    `json:"apiVersion" yaml:"apiVersion"`
+	Kind        string    `json:"kind" yaml:"kind"`
+	Namespace   string    `json:"namespace" yaml:"namespace"`
+	GeneratedAt time.Time `json:"generatedAt" yaml:"generatedAt"`
+	Summary     Summary   `json:"summary" yaml:"summary"`
+	Findings    []Finding `json:"findings" yaml:"findings"`
+	Errors      []string  `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// Summary counts the findings of a report by severity and kind
+type Summary struct {
+	Total      int            `json:"total" yaml:"total"`
+	BySeverity map[string]int `json:"bySeverity" yaml:"bySeverity"`
+	ByKind     map[string]int `json:"byKind" yaml:"byKind"`
+	// AIFallbacks counts the findings diagnosed by the built-in rules
+	// because the AI could not be used
+	AIFallbacks int `json:"aiFallbacks,omitempty" yaml:"aiFallbacks,omitempty"`
+}
+
+// Explanation is the machine-readable result of the explain command
+type Explanation struct {
+	APIVersion  string        `json:"apiVersion" yaml:"apiVersion"`
+	Kind        string        `json:"kind" yaml:"kind"`
+	GeneratedAt time.Time     `json:"generatedAt" yaml:"generatedAt"`
+	Input       string        `json:"input" yaml:"input"`
+	Explanation string        `json:"explanation" yaml:"explanation"`
+	Diagnosis   *ai.Diagnosis `json:"diagnosis,omitempty" yaml:"diagnosis,omitempty"`
+}
+
+// NewReport builds the machine-readable report for a diagnostic run. errs are
+// the non-fatal errors encountered while collecting or analyzing.
+func NewReport(results DiagnosticResults, errs []error) Report {
+	report := Report{
+		APIVersion:  APIVersion,
+		Kind:        KindDiagnosticReport,
+		Namespace:   results.Namespace,
+		GeneratedAt: results.Timestamp,
+		Findings:    results.Findings(),
+		Summary: Summary{
+			BySeverity: map[string]int{
+				string(SeverityCritical): 0,
+				string(SeverityWarning):  0,
+				string(SeverityInfo):     0,
+			},
+			ByKind: map[string]int{},
+		},
+	}
+
+	// Keep findings a list rather than null when there are none
+	if report.Findings == nil {
+		report.Findings = []Finding{}
+	}
+
+	for _, finding := range report.Findings {
+		report.Summary.Total++
+		report.Summary.BySeverity[string(finding.Severity)]++
+		report.Summary.ByKind[finding.Kind]++
+		if finding.Diagnosis != nil && finding.Diagnosis.Fallback != "" {
+			report.Summary.AIFallbacks++
+		}
+	}
+
+	for _, err := range errs {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	return report
+}
+
+// NewExplanation builds the machine-readable result of an explanation
+func NewExplanation(input string, diagnosis *ai.Diagnosis) Explanation {
+	return Explanation{
+		APIVersion:  APIVersion,
+		Kind:        KindExplanation,
+		GeneratedAt: time.Now(),
+		Input:       input,
+		Explanation: diagnosis.Markdown(),
+		Diagnosis:   diagnosis,
+	}
+}
+
+// IsStructuredFormat reports whether format is one of the machine-readable
+// output formats
+func IsStructuredFormat(format string) bool {
+	return format == "json" || format == "yaml"
+}
+
+// IsMachineReadable reports whether format is meant for other programs
+// rather than people: the structured formats plus JUnit and SARIF
+func IsMachineReadable(format string) bool {
+	return IsStructuredFormat(format) || format == "junit" || format == "sarif"
+}
+
+// Encode encodes a document as JSON or YAML
+func Encode(format string, v interface{}) (string, error) {
+	switch format {
+	case "json":
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(v); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	case "yaml":
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return "", err
+		}
+		encoder.Close()
+		return buf.String(), nil
+	}
+	return "", fmt.Errorf("unsupported structured format: %s", format)
+}
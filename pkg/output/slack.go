@@ -0,0 +1,490 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Slack limits (https://api.slack.com/reference/block-kit/blocks). Texts are
+// counted in bytes here, which is stricter than Slack's character count.
+const (
+	slackMaxBlocks      = 50
+	slackMaxHeaderText  = 150
+	slackMaxSectionText = 3000
+	slackMaxFieldText   = 2000
+	slackMaxFallback    = 3000
+	// slackMaxMessageText keeps the total text of one message well under the
+	// 40,000 character limit of chat.postMessage and webhook payloads
+	slackMaxMessageText = 12000
+)
+
+// SlackAPIURL is the base URL of the Slack Web API
+const SlackAPIURL = "https://slack.com/api"
+
+// SlackMessage is a Block Kit message for an incoming webhook or chat.postMessage
+type SlackMessage struct {
+	Channel  string       `json:"channel,omitempty"`
+	ThreadTS string       `json:"thread_ts,omitempty"`
+	Text     string       `json:"text"`
+	Blocks   []SlackBlock `json:"blocks,omitempty"`
+}
+
+// SlackBlock is a Block Kit layout block
+type SlackBlock struct {
+	Type     string      `json:"type"`
+	Text     *SlackText  `json:"text,omitempty"`
+	Fields   []SlackText `json:"fields,omitempty"`
+	Elements []SlackText `json:"elements,omitempty"`
+}
+
+// SlackText is a Block Kit text object
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SlackClient posts Block Kit messages to Slack, either through an incoming
+// webhook or through the Web API with a bot token. Only the Web API returns
+// message timestamps, so analyses are threaded under the report only when a
+// token is set; with a webhook they follow the report as separate messages.
+type SlackClient struct {
+	WebhookURL string
+	Token      string
+	Channel    string
+	// APIURL overrides SlackAPIURL, mainly for local stand-ins
+	APIURL     string
+	HTTPClient *http.Client
+
+	// threads maps thread keys and finding fingerprints to the message that
+	// opened them, so that later notifications about the same subject are
+	// threaded under it
+	mu      sync.Mutex
+	threads map[string]string
+}
+
+// Name identifies the notifier in error messages
+func (c *SlackClient) Name() string {
+	return "Slack"
+}
+
+// PostReport posts a diagnostic report: a header with summary fields and one
+// section per finding, followed by the AI analysis and suggested fix of each
+// finding. Messages that would exceed Slack's limits are split.
+func (c *SlackClient) PostReport(ctx context.Context, results DiagnosticResults) error {
+	return c.Notify(ctx, NewReportNotification(results))
+}
+
+// Notify posts a notification. Reports and alerts get a header with summary
+// fields; other notifications are a single section. Analyses follow the
+// notification, threaded under it if the client is threaded. Notifications
+// with a thread key, and notifications about a single finding that opened
+// earlier, are threaded under the first message posted for them.
+func (c *SlackClient) Notify(ctx context.Context, notification Notification) error {
+	key := notification.ThreadKey
+	if key == "" && len(notification.Findings) == 1 && notification.Type != NotificationOpened {
+		key = notification.Findings[0].Fingerprint
+	}
+	threadTS := ""
+	if key != "" {
+		threadTS = c.thread(key)
+	}
+
+	ts := threadTS
+	if notification.Type != NotificationAnalysis {
+		messages := slackMessages(notification)
+		for i, message := range messages {
+			// Overflow goes into the thread, so the channel only shows one
+			// message per notification
+			message.ThreadTS = ts
+			posted, err := c.Post(ctx, message)
+			if err != nil {
+				return err
+			}
+			if i == 0 && ts == "" {
+				ts = posted
+			}
+		}
+	}
+
+	for _, finding := range notification.Findings {
+		if _, err := c.PostAnalysis(ctx, finding, ts); err != nil {
+			return err
+		}
+	}
+
+	if notification.ThreadKey != "" {
+		if notification.Type == NotificationResolved {
+			c.setThread(notification.ThreadKey, "")
+		} else if threadTS == "" {
+			c.setThread(notification.ThreadKey, ts)
+		}
+	} else if len(notification.Findings) == 1 {
+		switch notification.Type {
+		case NotificationOpened:
+			c.setThread(notification.Findings[0].Fingerprint, ts)
+		case NotificationResolved:
+			c.setThread(notification.Findings[0].Fingerprint, "")
+		}
+	}
+
+	return nil
+}
+
+// thread returns the timestamp of the message that opened a finding
+func (c *SlackClient) thread(fingerprint string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.threads[fingerprint]
+}
+
+// setThread records or, with an empty timestamp, forgets the message that
+// opened a finding
+func (c *SlackClient) setThread(fingerprint, ts string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ts == "" {
+		delete(c.threads, fingerprint)
+		return
+	}
+	if c.threads == nil {
+		c.threads = make(map[string]string)
+	}
+	c.threads[fingerprint] = ts
+}
+
+// PostAnalysis posts the analysis and suggested fix of a finding, as replies
+// to threadTS if the client is threaded. It does nothing for findings without
+// analysis or fix.
+func (c *SlackClient) PostAnalysis(ctx context.Context, finding Finding, threadTS string) (string, error) {
+	var blocks []SlackBlock
+	if finding.Analysis != "" {
+		blocks = append(blocks, slackSections(SlackMarkdown(finding.Analysis))...)
+	}
+	if finding.SuggestedFix != "" {
+		blocks = append(blocks, slackSection("*Suggested fix*"))
+		blocks = append(blocks, slackCodeSections(finding.SuggestedFix)...)
+	}
+	if len(blocks) == 0 {
+		return "", nil
+	}
+
+	title := fmt.Sprintf("Analysis for %s", finding.Object())
+	blocks = append([]SlackBlock{slackSection("*" + slackEscape(title) + "*")}, blocks...)
+	return c.postBlocks(ctx, title, blocks, threadTS)
+}
+
+// PostText posts a mrkdwn message, split into several sections or messages if
+// it is too long, and returns the timestamp of the first message
+func (c *SlackClient) PostText(ctx context.Context, text, threadTS string) (string, error) {
+	return c.postBlocks(ctx, text, slackSections(text), threadTS)
+}
+
+// postBlocks posts blocks as one or more messages. Messages after the first
+// reply to the first one if no thread was given.
+func (c *SlackClient) postBlocks(ctx context.Context, fallback string, blocks []SlackBlock, threadTS string) (string, error) {
+	first := ""
+	for _, message := range splitSlackBlocks(fallback, blocks) {
+		message.ThreadTS = threadTS
+		ts, err := c.Post(ctx, message)
+		if err != nil {
+			return first, err
+		}
+		if first == "" {
+			first = ts
+			if threadTS == "" {
+				threadTS = ts
+			}
+		}
+	}
+	return first, nil
+}
+
+// Post sends a single message and returns its timestamp. Webhooks do not
+// return one, so the timestamp is empty unless a bot token is used.
+func (c *SlackClient) Post(ctx context.Context, message SlackMessage) (string, error) {
+	if c.Token == "" {
+		if c.WebhookURL == "" {
+			return "", fmt.Errorf("slack webhook URL or bot token not provided")
+		}
+		// Incoming webhooks are bound to a channel and reject threading fields
+		message.Channel = ""
+		message.ThreadTS = ""
+		_, err := c.send(ctx, c.WebhookURL, "", message)
+		return "", err
+	}
+
+	if c.Channel == "" {
+		return "", fmt.Errorf("slack channel is required when using a bot token")
+	}
+	message.Channel = c.Channel
+
+	apiURL := c.APIURL
+	if apiURL == "" {
+		apiURL = SlackAPIURL
+	}
+	body, err := c.send(ctx, strings.TrimSuffix(apiURL, "/")+"/chat.postMessage", c.Token, message)
+	if err != nil {
+		return "", err
+	}
+
+	// The Web API answers 200 even for failures and reports them in the body
+	var response struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		TS    string `json:"ts"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("decoding Slack response: %w", err)
+	}
+	if !response.OK {
+		return "", fmt.Errorf("failed to send to Slack: %s", response.Error)
+	}
+	return response.TS, nil
+}
+
+// send posts a JSON payload and returns the response body
+func (c *SlackClient) send(ctx context.Context, url, token string, message SlackMessage) ([]byte, error) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body bytes.Buffer
+	body.ReadFrom(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to send to Slack: %s", resp.Status)
+	}
+	return body.Bytes(), nil
+}
+
+// slackMessages builds the Block Kit messages of a notification, split at
+// Slack's limits. Reports and alerts get a header with summary fields and one
+// section per finding; other notifications get a single section.
+func slackMessages(notification Notification) []SlackMessage {
+	counts := notification.SeverityCounts()
+	fallback := notification.Title
+
+	var blocks []SlackBlock
+	switch notification.Type {
+	case NotificationReport, NotificationAlert:
+		blocks = append(blocks,
+			SlackBlock{Type: "header", Text: &SlackText{Type: "plain_text", Text: truncate(notification.Title, slackMaxHeaderText)}},
+			SlackBlock{Type: "section", Fields: []SlackText{
+				slackField("Namespace", notification.Namespace),
+				slackField("Time", notification.Timestamp.Format("Mon, 02 Jan 2006 15:04:05 MST")),
+				slackField("Findings", fmt.Sprint(len(notification.Findings))),
+				slackField("Critical", fmt.Sprint(counts[string(SeverityCritical)])),
+				slackField("Warning", fmt.Sprint(counts[string(SeverityWarning)])),
+				slackField("Info", fmt.Sprint(counts[string(SeverityInfo)])),
+			}},
+		)
+		if notification.Text != "" {
+			blocks = append(blocks, slackSections(slackEscape(notification.Text))...)
+		}
+		blocks = append(blocks, SlackBlock{Type: "divider"})
+		if notification.Type == NotificationReport && len(notification.Findings) == 0 {
+			blocks = append(blocks, slackSection(":white_check_mark: No issues found"))
+		}
+		for _, finding := range notification.Findings {
+			blocks = append(blocks, slackFindingSection(finding))
+		}
+		fallback = fmt.Sprintf("%s (%d findings, %d critical)", notification.Title, len(notification.Findings), counts[string(SeverityCritical)])
+	default:
+		text := "*" + slackEscape(notification.Title) + "*"
+		if notification.Text != "" {
+			text += "\n" + slackEscape(notification.Text)
+		}
+		blocks = append(blocks, slackSections(text)...)
+	}
+
+	return splitSlackBlocks(fallback, blocks)
+}
+
+// slackFindingSection renders a single finding as a section block
+func slackFindingSection(finding Finding) SlackBlock {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s *%s* %s", slackSeverityEmoji(finding.Severity), slackEscape(finding.Object()), slackEscape(finding.Reason)))
+	if finding.Message != "" {
+		sb.WriteString("\n" + slackEscape(finding.Message))
+	}
+	if finding.Replicas != nil {
+		sb.WriteString(fmt.Sprintf("\nReplicas: %d/%d ready", finding.Replicas.Ready, finding.Replicas.Desired))
+	}
+	for _, container := range finding.Containers {
+		if !container.Ready {
+			sb.WriteString(fmt.Sprintf("\nContainer `%s`: %s, %d restarts", slackEscape(container.Name), slackEscape(container.Status), container.Restarts))
+		}
+	}
+	return slackSection(truncate(sb.String(), slackMaxSectionText))
+}
+
+// slackSeverityEmoji returns the emoji shown in front of a finding
+func slackSeverityEmoji(severity Severity) string {
+	switch severity {
+	case SeverityCritical:
+		return ":red_circle:"
+	case SeverityWarning:
+		return ":large_orange_circle:"
+	}
+	return ":large_blue_circle:"
+}
+
+// splitSlackBlocks groups blocks into messages that stay within the block and
+// text limits of a single message
+func splitSlackBlocks(fallback string, blocks []SlackBlock) []SlackMessage {
+	fallback = truncate(fallback, slackMaxFallback)
+
+	var messages []SlackMessage
+	current := SlackMessage{Text: fallback}
+	size := 0
+	for _, block := range blocks {
+		blockSize := slackBlockSize(block)
+		if len(current.Blocks) > 0 && (len(current.Blocks) == slackMaxBlocks || size+blockSize > slackMaxMessageText) {
+			messages = append(messages, current)
+			current = SlackMessage{Text: fallback + " (continued)"}
+			size = 0
+		}
+		current.Blocks = append(current.Blocks, block)
+		size += blockSize
+	}
+	return append(messages, current)
+}
+
+// slackBlockSize returns the amount of text in a block
+func slackBlockSize(block SlackBlock) int {
+	size := 0
+	if block.Text != nil {
+		size += len(block.Text.Text)
+	}
+	for _, field := range block.Fields {
+		size += len(field.Text)
+	}
+	return size
+}
+
+// slackSection returns a mrkdwn section block
+func slackSection(text string) SlackBlock {
+	return SlackBlock{Type: "section", Text: &SlackText{Type: "mrkdwn", Text: text}}
+}
+
+// slackField returns a mrkdwn field of a section block
+func slackField(label, value string) SlackText {
+	return SlackText{Type: "mrkdwn", Text: truncate(fmt.Sprintf("*%s*\n%s", label, slackEscape(value)), slackMaxFieldText)}
+}
+
+// slackSections splits mrkdwn text into as many section blocks as needed
+func slackSections(text string) []SlackBlock {
+	var blocks []SlackBlock
+	for _, chunk := range splitText(strings.TrimSpace(text), slackMaxSectionText) {
+		blocks = append(blocks, slackSection(chunk))
+	}
+	return blocks
+}
+
+// slackCodeSections splits text into code blocks, each in its own section
+func slackCodeSections(text string) []SlackBlock {
+	text = strings.Trim(stripCodeFence(text), "\n")
+
+	var blocks []SlackBlock
+	for _, chunk := range splitText(text, slackMaxSectionText-len("```\n\n```")) {
+		blocks = append(blocks, slackSection("```\n"+chunk+"\n```"))
+	}
+	return blocks
+}
+
+// splitText splits text into chunks of at most limit bytes, preferring to
+// break at line ends and then at spaces
+func splitText(text string, limit int) []string {
+	var chunks []string
+	for len(text) > limit {
+		cut := strings.LastIndex(text[:limit], "\n")
+		if cut <= 0 {
+			cut = strings.LastIndex(text[:limit], " ")
+		}
+		if cut <= 0 {
+			cut = limit
+			// Never cut a multi-byte character in half
+			for cut > 0 && !utf8RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		chunks = append(chunks, text[:cut])
+		text = strings.TrimLeft(text[cut:], "\n ")
+	}
+	if text != "" || len(chunks) == 0 {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+// utf8RuneStart reports whether b can start a UTF-8 encoded rune
+func utf8RuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// truncate shortens text to at most limit bytes, marking the cut with an ellipsis
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := limit - len("…")
+	for cut > 0 && !utf8RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
+
+// slackEscape escapes the characters Slack treats as control sequences in mrkdwn
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+var (
+	markdownHeading = regexp.MustCompile(`(?m)^#{1,6}\s+(.+?)\s*#*$`)
+	markdownBold    = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	markdownLink    = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+)
+
+// SlackMarkdown converts the markdown produced by the AI to Slack mrkdwn:
+// headings and bold text become *bold* and links use Slack's <url|text> form
+func SlackMarkdown(markdown string) string {
+	text := slackEscape(markdown)
+	text = markdownHeading.ReplaceAllString(text, "*$1*")
+	text = markdownBold.ReplaceAllString(text, "*$1*")
+	text = markdownLink.ReplaceAllString(text, "<$2|$1>")
+	return text
+}
+
+// stripCodeFence removes a markdown code fence around text, if there is one
+func stripCodeFence(text string) string {
+	if match := codeFence.FindStringSubmatch(text); match != nil && strings.TrimSpace(codeFence.ReplaceAllString(text, "")) == "" {
+		return match[2]
+	}
+	return text
+}
@@ -0,0 +1,87 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// slackRecorder stands in for the Slack Web API and records the thread of
+// every posted message
+type slackRecorder struct {
+	mu      sync.Mutex
+	threads []string
+}
+
+func (s *slackRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var message SlackMessage
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.threads = append(s.threads, message.ThreadTS)
+	fmt.Fprintf(w, `{"ok":true,"ts":"%d"}`, len(s.threads))
+}
+
+func TestSlackClientNotifyThreads(t *testing.T) {
+	finding := Finding{Kind: "Pod", Namespace: "default", Name: "web", Reason: "CrashLoopBackOff", Fingerprint: "pod-web"}
+	alert := func(typ NotificationType, key string) Notification {
+		return Notification{Type: typ, Title: "alert", Namespace: "default", Findings: []Finding{}, ThreadKey: key}
+	}
+	watch := func(typ NotificationType) Notification {
+		return Notification{Type: typ, Title: "watch", Namespace: "default", Findings: []Finding{finding}}
+	}
+
+	tests := []struct {
+		name          string
+		notifications []Notification
+		want          []string
+	}{
+		{
+			name:          "alert repeats and resolution reply to the first diagnosis",
+			notifications: []Notification{alert(NotificationAlert, "a1"), alert(NotificationAlert, "a1"), alert(NotificationResolved, "a1")},
+			want:          []string{"", "1", "1"},
+		},
+		{
+			name:          "alert fires again after it resolved",
+			notifications: []Notification{alert(NotificationAlert, "a1"), alert(NotificationResolved, "a1"), alert(NotificationAlert, "a1")},
+			want:          []string{"", "1", ""},
+		},
+		{
+			name:          "alerts with different fingerprints get their own threads",
+			notifications: []Notification{alert(NotificationAlert, "a1"), alert(NotificationAlert, "a2"), alert(NotificationResolved, "a2")},
+			want:          []string{"", "", "2"},
+		},
+		{
+			name:          "watch findings are threaded by finding fingerprint",
+			notifications: []Notification{watch(NotificationOpened), watch(NotificationChanged), watch(NotificationResolved), watch(NotificationChanged)},
+			want:          []string{"", "1", "1", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &slackRecorder{}
+			server := httptest.NewServer(recorder)
+			defer server.Close()
+
+			client := &SlackClient{Token: "token", Channel: "#alerts", APIURL: server.URL}
+			for _, notification := range tt.notifications {
+				if err := client.Notify(context.Background(), notification); err != nil {
+					t.Fatalf("Notify: %v", err)
+				}
+			}
+
+			if fmt.Sprint(recorder.threads) != fmt.Sprint(tt.want) {
+				t.Errorf("thread_ts = %q, want %q", recorder.threads, tt.want)
+			}
+		})
+	}
+}
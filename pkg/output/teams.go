@@ -0,0 +1,156 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// teamsMaxCardSize keeps a card under the 28 KB limit of Teams messages,
+// leaving room for the message envelope
+const teamsMaxCardSize = 24000
+
+// teamsMaxText limits a single analysis or fix within a card
+const teamsMaxText = 6000
+
+// TeamsNotifier posts notifications to a Microsoft Teams incoming webhook or
+// Workflows webhook as Adaptive Cards
+type TeamsNotifier struct {
+	WebhookURL string
+	HTTPClient *http.Client
+}
+
+// Name identifies the notifier in error messages
+func (t *TeamsNotifier) Name() string {
+	return "Microsoft Teams"
+}
+
+// Notify posts a notification as one or more Adaptive Cards. Findings that do
+// not fit in one card continue in the next.
+func (t *TeamsNotifier) Notify(ctx context.Context, notification Notification) error {
+	for _, card := range teamsCards(notification) {
+		payload, err := json.Marshal(map[string]interface{}{
+			"type": "message",
+			"attachments": []map[string]interface{}{{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content":     card,
+			}},
+		})
+		if err != nil {
+			return err
+		}
+		if err := PostJSON(ctx, t.HTTPClient, t.WebhookURL, payload, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// teamsElement is an Adaptive Card element
+type teamsElement map[string]interface{}
+
+// teamsCards builds the Adaptive Cards of a notification
+func teamsCards(notification Notification) []teamsElement {
+	header := []teamsElement{{
+		"type":   "TextBlock",
+		"text":   notification.Title,
+		"size":   "Large",
+		"weight": "Bolder",
+		"wrap":   true,
+	}}
+	if notification.Text != "" {
+		header = append(header, teamsElement{"type": "TextBlock", "text": notification.Text, "wrap": true})
+	}
+
+	if notification.Type == NotificationReport || notification.Type == NotificationAlert {
+		counts := notification.SeverityCounts()
+		header = append(header, teamsElement{
+			"type": "FactSet",
+			"facts": []map[string]string{
+				{"title": "Namespace", "value": notification.Namespace},
+				{"title": "Time", "value": notification.Timestamp.Format("Mon, 02 Jan 2006 15:04:05 MST")},
+				{"title": "Findings", "value": fmt.Sprint(len(notification.Findings))},
+				{"title": "Critical", "value": fmt.Sprint(counts[string(SeverityCritical)])},
+				{"title": "Warning", "value": fmt.Sprint(counts[string(SeverityWarning)])},
+				{"title": "Info", "value": fmt.Sprint(counts[string(SeverityInfo)])},
+			},
+		})
+	}
+
+	var cards []teamsElement
+	body := header
+	size := elementSize(header)
+	for _, finding := range notification.Findings {
+		container := teamsFindingContainer(finding, notification.Type != NotificationAnalysis)
+		containerSize := elementSize(container)
+		if len(body) > len(header) && size+containerSize > teamsMaxCardSize {
+			cards = append(cards, adaptiveCard(body))
+			body = []teamsElement{{"type": "TextBlock", "text": notification.Title + " (continued)", "weight": "Bolder", "wrap": true}}
+			size = elementSize(body)
+		}
+		body = append(body, container)
+		size += containerSize
+	}
+	return append(cards, adaptiveCard(body))
+}
+
+// teamsFindingContainer renders a finding, with its analysis and fix, as a
+// container element
+func teamsFindingContainer(finding Finding, withDetails bool) teamsElement {
+	items := []teamsElement{{
+		"type":   "TextBlock",
+		"text":   fmt.Sprintf("%s: %s", finding.Object(), finding.Reason),
+		"weight": "Bolder",
+		"color":  teamsSeverityColor(finding.Severity),
+		"wrap":   true,
+	}}
+	if withDetails && finding.Message != "" {
+		items = append(items, teamsElement{"type": "TextBlock", "text": finding.Message, "isSubtle": true, "wrap": true})
+	}
+	if finding.Analysis != "" {
+		items = append(items, teamsElement{"type": "TextBlock", "text": truncate(TeamsMarkdown(finding.Analysis), teamsMaxText), "wrap": true})
+	}
+	if finding.SuggestedFix != "" {
+		items = append(items,
+			teamsElement{"type": "TextBlock", "text": "Suggested fix", "weight": "Bolder"},
+			teamsElement{"type": "TextBlock", "text": truncate(stripCodeFence(finding.SuggestedFix), teamsMaxText), "fontType": "Monospace", "wrap": true},
+		)
+	}
+
+	return teamsElement{"type": "Container", "separator": true, "items": items}
+}
+
+// teamsSeverityColor maps a severity to an Adaptive Card text color
+func teamsSeverityColor(severity Severity) string {
+	switch severity {
+	case SeverityCritical:
+		return "Attention"
+	case SeverityWarning:
+		return "Warning"
+	}
+	return "Accent"
+}
+
+// adaptiveCard wraps body elements in an Adaptive Card
+func adaptiveCard(body []teamsElement) teamsElement {
+	return teamsElement{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"msteams": map[string]string{"width": "Full"},
+		"body":    body,
+	}
+}
+
+// elementSize returns the encoded size of card elements
+func elementSize(v interface{}) int {
+	encoded, _ := json.Marshal(v)
+	return len(encoded)
+}
+
+// TeamsMarkdown converts the markdown produced by the AI to the subset Adaptive
+// Card text blocks support, which has no headings
+func TeamsMarkdown(markdown string) string {
+	return markdownHeading.ReplaceAllString(markdown, "**$1**")
+}
@@ -0,0 +1,246 @@
+package output
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+)
+
+// DefaultMarkdownTemplate is the layout of the markdown report. It is a
+// starting point for custom --template files.
+//
+//go:embed templates/report.md.tmpl
+var DefaultMarkdownTemplate string
+
+// defaultMarkdown is parsed once; a template that does not parse is a build
+// bug
+var defaultMarkdown = template.Must(template.New("report.md.tmpl").Funcs(TemplateFuncs()).Parse(DefaultMarkdownTemplate))
+
+// TemplateData is what report templates are rendered against: the full
+// DiagnosticResults model, so .Namespace, .Timestamp, .UnhealthyPods and the
+// other fields work as is, plus the flattened findings and the run's errors
+type TemplateData struct {
+	DiagnosticResults
+	Findings []Finding
+	Summary  Summary
+	Errors   []string
+}
+
+// NewTemplateData builds the data of a report template
+func NewTemplateData(results DiagnosticResults, errs []error) TemplateData {
+	report := NewReport(results, errs)
+	return TemplateData{
+		DiagnosticResults: results,
+		Findings:          report.Findings,
+		Summary:           report.Summary,
+		Errors:            report.Errors,
+	}
+}
+
+// TemplateFuncs returns the helper functions available to report templates:
+//
+//	add            adds integers, for 1-based numbering: {{ add $i 1 }}
+//	severityColor  hex color of a severity: {{ severityColor .Severity }}
+//	colorize       wraps text in the terminal color of a severity
+//	duration       formats a duration compactly, such as 2d3h or 4m10s
+//	since          time elapsed since a timestamp: {{ since .Timestamp | duration }}
+//	truncate       shortens text to n characters: {{ truncate 80 .Message }}
+//	escapeMarkdown escapes markdown syntax in untrusted text
+//	indent         indents every line by n spaces
+//	upper, lower   change case
+//	json           encodes a value as JSON
+//	diagnosis      parses the .Analysis or .Fix of a pod or deployment:
+//	               {{ (diagnosis .Analysis).Markdown }}, {{ (diagnosis .Fix).FixText }}
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"add":            func(a, b int) int { return a + b },
+		"severityColor":  severityColor,
+		"colorize":       colorize,
+		"duration":       formatDuration,
+		"since":          time.Since,
+		"truncate":       truncateRunes,
+		"escapeMarkdown": escapeMarkdown,
+		"indent":         indent,
+		"upper":          strings.ToUpper,
+		"lower":          strings.ToLower,
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"diagnosis": ai.ParseDiagnosis,
+	}
+}
+
+// GenerateMarkdownReport generates a markdown report from the diagnostic results
+func GenerateMarkdownReport(results DiagnosticResults) (string, error) {
+	var b bytes.Buffer
+	if err := defaultMarkdown.Execute(&b, NewTemplateData(results, nil)); err != nil {
+		return "", fmt.Errorf("rendering markdown report: %w", err)
+	}
+	return b.String(), nil
+}
+
+// RenderTemplateFile renders the results with the Go template at path. Files
+// ending in .html, .htm, .gohtml or .html.tmpl use html/template, which
+// escapes values for HTML; anything else uses text/template.
+func RenderTemplateFile(path string, results DiagnosticResults, errs []error) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading template: %w", err)
+	}
+
+	name := filepath.Base(path)
+	data := NewTemplateData(results, errs)
+	var b bytes.Buffer
+
+	if isHTMLTemplate(name) {
+		tmpl, err := htmltemplate.New(name).Funcs(htmltemplate.FuncMap(TemplateFuncs())).Parse(string(content))
+		if err != nil {
+			return "", fmt.Errorf("parsing template: %w", err)
+		}
+		if err := tmpl.Execute(&b, data); err != nil {
+			return "", fmt.Errorf("rendering template: %w", err)
+		}
+		return b.String(), nil
+	}
+
+	tmpl, err := template.New(name).Funcs(TemplateFuncs()).Parse(string(content))
+	if err != nil {
+		return "", fmt.Errorf("parsing template: %w", err)
+	}
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// isHTMLTemplate reports whether a template file name denotes HTML output
+func isHTMLTemplate(name string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".tmpl")
+	switch filepath.Ext(name) {
+	case ".html", ".htm", ".gohtml":
+		return true
+	}
+	return false
+}
+
+// severityColor returns the hex color of a severity, matching the HTML report
+func severityColor(severity Severity) string {
+	switch severity {
+	case SeverityCritical:
+		return "#cf222e"
+	case SeverityWarning:
+		return "#bf8700"
+	case SeverityInfo:
+		return "#0969da"
+	}
+	return "#1a7f37"
+}
+
+// colorize wraps text in the terminal color of a severity. Like the rest of
+// the terminal output it stays plain when colors are disabled.
+func colorize(severity Severity, text string) string {
+	switch severity {
+	case SeverityCritical:
+		return color.New(color.FgRed, color.Bold).Sprint(text)
+	case SeverityWarning:
+		return color.New(color.FgYellow).Sprint(text)
+	case SeverityInfo:
+		return color.New(color.FgCyan).Sprint(text)
+	}
+	return color.New(color.FgGreen).Sprint(text)
+}
+
+// formatDuration formats a duration with its two largest units, such as
+// 2d3h, 5h12m or 4m10s
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+
+	var parts []string
+	for _, unit := range units {
+		if d >= unit.size {
+			parts = append(parts, fmt.Sprintf("%d%s", d/unit.size, unit.suffix))
+			d %= unit.size
+		} else if len(parts) > 0 {
+			// Keep the two units adjacent, so 1d0h5m reads as 1d
+			parts = append(parts, "")
+		}
+		if len(parts) == 2 {
+			break
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// truncateRunes is TruncateRunes with the limit first, so templates can pipe
+// text to it
+func truncateRunes(limit int, text string) string {
+	return TruncateRunes(text, limit)
+}
+
+// TruncateRunes shortens text to at most limit characters, ending in an
+// ellipsis when cut. Unlike slicing bytes, it never splits a multi-byte
+// character, so the result stays valid UTF-8 for APIs with length limits.
+func TruncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if limit <= 0 || len(runes) <= limit {
+		return text
+	}
+	if limit <= 3 {
+		return string(runes[:limit])
+	}
+	return string(runes[:limit-3]) + "..."
+}
+
+// markdownSpecial are the characters escapeMarkdown escapes
+const markdownSpecial = "\\`*_{}[]()<>#+-.!|~"
+
+// escapeMarkdown escapes the characters markdown would interpret, so that
+// messages and names from the cluster render literally
+func escapeMarkdown(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune(markdownSpecial, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// indent prefixes every non-empty line of text with n spaces
+func indent(n int, text string) string {
+	prefix := strings.Repeat(" ", n)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
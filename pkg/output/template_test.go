@@ -0,0 +1,200 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/junioroyewunmi/kubegpt/pkg/k8s"
+)
+
+// goldenResults are the results of testdata/report.md: pods and deployments
+// with and without reasons and messages, but no AI analyses, whose layout
+// changed with structured diagnoses
+func goldenResults() DiagnosticResults {
+	return DiagnosticResults{
+		Namespace: "shop",
+		Timestamp: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		UnhealthyPods: []k8s.PodIssue{
+			{
+				Name:   "api-7d9f",
+				Status: "Running",
+				Containers: []k8s.ContainerIssue{
+					{Name: "api", Status: "Waiting", Restarts: 12, Reason: "CrashLoopBackOff", Message: "back-off 5m0s restarting failed container"},
+					{Name: "sidecar", Status: "Running"},
+				},
+			},
+			{Name: "worker-0", Status: "Pending"},
+		},
+		FailedEvents: []interface{}{map[string]interface{}{"reason": "FailedScheduling"}},
+		MisconfiguredDeployments: []k8s.DeploymentIssue{
+			{Name: "api", Replicas: 3, ReadyReplicas: 1, Reason: "MinimumReplicasUnavailable", Message: "Deployment does not have minimum availability."},
+			{Name: "web", Replicas: 2},
+		},
+	}
+}
+
+func TestGenerateMarkdownReportLayout(t *testing.T) {
+	want, err := os.ReadFile(filepath.Join("testdata", "report.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := GenerateMarkdownReport(goldenResults())
+	if err != nil {
+		t.Fatalf("GenerateMarkdownReport: %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("GenerateMarkdownReport() =\n%s\nwant testdata/report.md\n%s", got, want)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+	funcs := TemplateFuncs()
+
+	tests := []struct {
+		name     string
+		template string
+		data     interface{}
+		want     string
+	}{
+		{"add", `{{ add 1 2 }}`, nil, "3"},
+		{"severityColor", `{{ severityColor "critical" }} {{ severityColor "" }}`, nil, "#cf222e #1a7f37"},
+		{"colorize without colors", `{{ colorize "warning" "web" }}`, nil, "web"},
+		{"duration", `{{ duration . }}`, 26*time.Hour + 5*time.Minute, "1d2h"},
+		{"short duration", `{{ duration . }}`, 1500 * time.Millisecond, "2s"},
+		{"minutes and seconds", `{{ duration . }}`, 4*time.Minute + 10*time.Second, "4m10s"},
+		{"truncate in a pipeline", `{{ . | truncate 6 }}`, "CrashLoopBackOff", "Cra..."},
+		{"escapeMarkdown", `{{ escapeMarkdown . }}`, "*web*_[1]", `\*web\*\_\[1\]`},
+		{"indent", `{{ indent 2 . }}`, "a\n\nb", "  a\n\n  b"},
+		{"upper and lower", `{{ upper "pod" }} {{ lower "POD" }}`, nil, "POD pod"},
+		{"json", `{{ json . }}`, map[string]int{"restarts": 3}, `{"restarts":3}`},
+		{"diagnosis", `{{ (diagnosis .).Summary }}`, `{"summary": "The pod crashes."}`, "The pod crashes."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New(tt.name).Funcs(funcs).Parse(tt.template)
+			if err != nil {
+				t.Fatalf("parsing: %v", err)
+			}
+			var b strings.Builder
+			if err := tmpl.Execute(&b, tt.data); err != nil {
+				t.Fatalf("rendering: %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("%s = %q, want %q", tt.template, b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTemplateFile(t *testing.T) {
+	results := DiagnosticResults{
+		Namespace:     "shop",
+		UnhealthyPods: []k8s.PodIssue{{Name: "<script>alert(1)</script>", Status: "Pending"}},
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		template string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "text templates keep values as they are",
+			file:     "report.md",
+			template: `{{ .Namespace }}: {{ range .UnhealthyPods }}{{ .Name }}{{ end }}`,
+			want:     "shop: <script>alert(1)</script>",
+		},
+		{
+			name:     "html templates escape values",
+			file:     "report.html",
+			template: `<p>{{ range .UnhealthyPods }}{{ .Name }}{{ end }}</p>`,
+			want:     "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>",
+		},
+		{
+			name:     "html detection ignores case and .tmpl",
+			file:     "Report.HTML.tmpl",
+			template: `{{ range .UnhealthyPods }}{{ .Name }}{{ end }}`,
+			want:     "&lt;script&gt;alert(1)&lt;/script&gt;",
+		},
+		{
+			name:     "findings and summary",
+			file:     "summary.txt",
+			template: `{{ len .Findings }} finding(s), {{ .Summary.Total }} total`,
+			want:     "1 finding(s), 1 total",
+		},
+		{
+			name:     "parse errors",
+			file:     "broken.md",
+			template: `{{ .Namespace `,
+			wantErr:  "parsing template",
+		},
+		{
+			name:     "execution errors",
+			file:     "missing.md",
+			template: `{{ .Missing }}`,
+			wantErr:  "rendering template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.template), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := RenderTemplateFile(path, results, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("RenderTemplateFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderTemplateFile: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderTemplateFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  string
+	}{
+		{"short text is kept", "CrashLoopBackOff", 20, "CrashLoopBackOff"},
+		{"exact length is kept", "abcdef", 6, "abcdef"},
+		{"long text ends in an ellipsis", "abcdefgh", 6, "abc..."},
+		{"multi-byte characters are not split", "ペンディング中のポッド", 6, "ペンデ..."},
+		{"tiny limits cut without an ellipsis", "ポッド", 2, "ポッ"},
+		{"no limit keeps the text", "abcdef", 0, "abcdef"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateRunes(tt.text, tt.limit)
+			if got != tt.want {
+				t.Errorf("TruncateRunes(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("TruncateRunes(%q, %d) = %q is not valid UTF-8", tt.text, tt.limit, got)
+			}
+		})
+	}
+}
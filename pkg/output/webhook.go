@@ -0,0 +1,111 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// WebhookSignatureHeader carries the HMAC-SHA256 signature of a webhook body,
+// as "sha256=<hex>", when a signing secret is configured
+const WebhookSignatureHeader = "X-Kubegpt-Signature"
+
+// KindNotification is the document kind of the default webhook body
+const KindNotification = "Notification"
+
+// WebhookNotifier posts notifications to a generic HTTP endpoint. The body is
+// the notification as versioned JSON unless a Go template is configured.
+type WebhookNotifier struct {
+	URL        string
+	Headers    map[string]string
+	Secret     string
+	HTTPClient *http.Client
+
+	body *template.Template
+}
+
+// webhookTemplateFuncs are the helpers available to webhook body templates
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// NewWebhookNotifier creates a webhook notifier. bodyTemplate is a Go
+// text/template rendered with the Notification; an empty template sends the
+// notification as JSON.
+func NewWebhookNotifier(url, bodyTemplate, secret string, headers map[string]string) (*WebhookNotifier, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+
+	notifier := &WebhookNotifier{URL: url, Headers: headers, Secret: secret}
+	if bodyTemplate != "" {
+		tmpl, err := template.New("body").Funcs(webhookTemplateFuncs).Option("missingkey=error").Parse(bodyTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("parsing webhook body template: %w", err)
+		}
+		notifier.body = tmpl
+	}
+	return notifier, nil
+}
+
+// Name identifies the notifier in error messages and logs by the scheme and
+// host of its URL, leaving out any token in the path or query
+func (w *WebhookNotifier) Name() string {
+	return "webhook " + RedactURL(w.URL)
+}
+
+// Notify posts a notification
+func (w *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := w.render(notification)
+	if err != nil {
+		return err
+	}
+
+	headers := make(map[string]string, len(w.Headers)+1)
+	for key, value := range w.Headers {
+		headers[key] = value
+	}
+	if w.Secret != "" {
+		headers[WebhookSignatureHeader] = SignWebhook(w.Secret, body)
+	}
+
+	return PostJSON(ctx, w.HTTPClient, w.URL, body, headers)
+}
+
+// render renders the body of a notification
+func (w *WebhookNotifier) render(notification Notification) ([]byte, error) {
+	if w.body == nil {
+		return json.Marshal(struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+			Notification
+		}{APIVersion, KindNotification, notification})
+	}
+
+	var buf bytes.Buffer
+	if err := w.body.Execute(&buf, notification); err != nil {
+		return nil, fmt.Errorf("rendering webhook body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SignWebhook returns the signature header value of a webhook body. Receivers
+// recompute it over the raw body with the shared secret and compare in
+// constant time.
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,140 @@
+package route
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// MatchType is the comparison a matcher performs
+type MatchType string
+
+const (
+	// MatchEqual matches values equal to the matcher value
+	MatchEqual MatchType = "="
+	// MatchNotEqual matches values different from the matcher value
+	MatchNotEqual MatchType = "!="
+	// MatchRegexp matches values the regular expression fully matches
+	MatchRegexp MatchType = "=~"
+	// MatchNotRegexp matches values the regular expression does not fully match
+	MatchNotRegexp MatchType = "!~"
+)
+
+// Matcher compares one field of a finding with a value, in the form of an
+// Alertmanager matcher: severity="critical", namespace=~"prod-.*" or
+// labels.team!="payments"
+type Matcher struct {
+	Name  string
+	Type  MatchType
+	Value string
+	re    *regexp.Regexp
+}
+
+// matcherSyntax splits a matcher into field, operator and value
+var matcherSyntax = regexp.MustCompile(`^\s*([A-Za-z][\w./-]*)\s*(=~|!~|!=|=)\s*(.*?)\s*$`)
+
+// ParseMatcher parses a matcher. The value may be double quoted.
+func ParseMatcher(text string) (*Matcher, error) {
+	parts := matcherSyntax.FindStringSubmatch(text)
+	if parts == nil {
+		return nil, fmt.Errorf("invalid matcher %q (expected field=value, field!=value, field=~regex or field!~regex)", text)
+	}
+
+	name := parts[1]
+	if !validField(name) {
+		return nil, fmt.Errorf("invalid matcher %q: unknown field %q (expected namespace, kind, name, severity, reason or labels.<key>)", text, name)
+	}
+
+	value := parts[3]
+	if strings.HasPrefix(value, `"`) {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid matcher %q: %w", text, err)
+		}
+		value = unquoted
+	}
+
+	matcher := &Matcher{Name: name, Type: MatchType(parts[2]), Value: value}
+	if matcher.Type == MatchRegexp || matcher.Type == MatchNotRegexp {
+		// Regular expressions are anchored, as in Alertmanager
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid matcher %q: %w", text, err)
+		}
+		matcher.re = re
+	}
+	return matcher, nil
+}
+
+// ParseMatchers parses a list of matchers
+func ParseMatchers(texts []string) ([]*Matcher, error) {
+	matchers := make([]*Matcher, 0, len(texts))
+	for _, text := range texts {
+		matcher, err := ParseMatcher(text)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+// Matches reports whether the finding satisfies the matcher. A label the
+// finding does not have compares as an empty value.
+func (m *Matcher) Matches(finding output.Finding) bool {
+	value := Field(finding, m.Name)
+	switch m.Type {
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(value)
+	case MatchNotRegexp:
+		return !m.re.MatchString(value)
+	}
+	return value == m.Value
+}
+
+// String formats the matcher as it is written in the config file
+func (m *Matcher) String() string {
+	return fmt.Sprintf("%s%s%q", m.Name, m.Type, m.Value)
+}
+
+// matchAll reports whether the finding satisfies every matcher
+func matchAll(matchers []*Matcher, finding output.Finding) bool {
+	for _, matcher := range matchers {
+		if !matcher.Matches(finding) {
+			return false
+		}
+	}
+	return true
+}
+
+// Field returns the value of a finding field that matchers and groupBy refer
+// to: namespace, kind, name, severity, reason or labels.<key>
+func Field(finding output.Finding, name string) string {
+	switch name {
+	case "namespace":
+		return finding.Namespace
+	case "kind":
+		return finding.Kind
+	case "name":
+		return finding.Name
+	case "severity":
+		return string(finding.Severity)
+	case "reason":
+		return finding.Reason
+	}
+	return finding.Labels[strings.TrimPrefix(name, "labels.")]
+}
+
+// validField reports whether name is a field Field knows
+func validField(name string) bool {
+	switch name {
+	case "namespace", "kind", "name", "severity", "reason":
+		return true
+	}
+	return strings.HasPrefix(name, "labels.") && len(name) > len("labels.")
+}
@@ -0,0 +1,213 @@
+// Package route decides which receivers get notified about which findings,
+// with a routing tree modelled on Alertmanager routes: matchers select
+// findings, child routes refine their parent, grouping decides how findings
+// are bundled into notifications and repeat intervals throttle notifications
+// that did not change. Silences mute matching findings for a while.
+package route
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// NoReceiver is the receiver name that drops findings, for routes that
+// should not notify anybody
+const NoReceiver = "none"
+
+// GroupByAll groups by every field, so that each finding gets its own
+// notification
+const GroupByAll = "..."
+
+// Config is a node of the routing tree in the config file:
+//
+//	route:
+//	  receiver: team-slack
+//	  groupBy: [namespace]
+//	  repeatInterval: 4h
+//	  routes:
+//	    - matchers: ['namespace="payments"', 'severity="critical"']
+//	      receiver: payments-pagerduty
+//	    - matchers: ['namespace=~"staging-.*"']
+//	      receiver: staging-slack
+//	    - matchers: ['severity="info"']
+//	      receiver: none
+//
+// Receivers refer to notifiers by name. Receiver, groupBy and repeatInterval
+// are inherited from the parent route when not set.
+type Config struct {
+	Receiver       string   `mapstructure:"receiver"`
+	Matchers       []string `mapstructure:"matchers"`
+	GroupBy        []string `mapstructure:"groupBy"`
+	RepeatInterval string   `mapstructure:"repeatInterval"`
+	// Continue keeps evaluating the sibling routes after this one matched
+	Continue bool     `mapstructure:"continue"`
+	Routes   []Config `mapstructure:"routes"`
+}
+
+// Route is a parsed node of the routing tree
+type Route struct {
+	// ID identifies the route by its position in the tree, such as "0.2.1"
+	ID             string
+	Receiver       string
+	Matchers       []*Matcher
+	GroupBy        []string
+	RepeatInterval time.Duration
+	Continue       bool
+	Routes         []*Route
+}
+
+// New parses a routing tree. The root route matches every finding.
+func New(config Config) (*Route, error) {
+	return newRoute(config, &Route{}, "0")
+}
+
+// newRoute parses a route, inheriting unset settings from parent
+func newRoute(config Config, parent *Route, id string) (*Route, error) {
+	matchers, err := ParseMatchers(config.Matchers)
+	if err != nil {
+		return nil, fmt.Errorf("route %s: %w", id, err)
+	}
+
+	route := &Route{
+		ID:             id,
+		Receiver:       config.Receiver,
+		Matchers:       matchers,
+		GroupBy:        config.GroupBy,
+		RepeatInterval: parent.RepeatInterval,
+		Continue:       config.Continue,
+	}
+	if route.Receiver == "" {
+		route.Receiver = parent.Receiver
+	}
+	if route.GroupBy == nil {
+		route.GroupBy = parent.GroupBy
+	}
+	for _, field := range route.GroupBy {
+		if field != GroupByAll && !validField(field) {
+			return nil, fmt.Errorf("route %s: unknown groupBy field %q", id, field)
+		}
+	}
+	if config.RepeatInterval != "" {
+		interval, err := time.ParseDuration(config.RepeatInterval)
+		if err != nil || interval < 0 {
+			return nil, fmt.Errorf("route %s: invalid repeatInterval %q", id, config.RepeatInterval)
+		}
+		route.RepeatInterval = interval
+	}
+
+	for i, child := range config.Routes {
+		childRoute, err := newRoute(child, route, fmt.Sprintf("%s.%d", id, i))
+		if err != nil {
+			return nil, err
+		}
+		route.Routes = append(route.Routes, childRoute)
+	}
+	return route, nil
+}
+
+// Match returns the routes a finding is dispatched to. Child routes are
+// tried in order and the first match wins, unless it sets continue. A route
+// whose children all fail to match handles the finding itself.
+func (r *Route) Match(finding output.Finding) []*Route {
+	if !matchAll(r.Matchers, finding) {
+		return nil
+	}
+
+	var matched []*Route
+	for _, child := range r.Routes {
+		routes := child.Match(finding)
+		matched = append(matched, routes...)
+		if len(routes) > 0 && !child.Continue {
+			break
+		}
+	}
+	if len(matched) == 0 {
+		matched = []*Route{r}
+	}
+	return matched
+}
+
+// Receivers returns the receivers the tree refers to, except NoReceiver
+func (r *Route) Receivers() []string {
+	var receivers []string
+	if r.Receiver != "" && r.Receiver != NoReceiver {
+		receivers = append(receivers, r.Receiver)
+	}
+	for _, child := range r.Routes {
+		receivers = append(receivers, child.Receivers()...)
+	}
+	return receivers
+}
+
+// groupKey returns the group of a finding within the route, as the values
+// of the route's groupBy fields
+func (r *Route) groupKey(finding output.Finding) []string {
+	var key []string
+	for _, field := range r.GroupBy {
+		if field == GroupByAll {
+			return []string{"fingerprint=" + finding.Fingerprint}
+		}
+		key = append(key, fmt.Sprintf("%s=%s", field, Field(finding, field)))
+	}
+	return key
+}
+
+// SilenceConfig is an entry of the silences list in the config file:
+//
+//	silences:
+//	  - matchers: ['namespace="payments"', 'labels.app="batch"']
+//	    endsAt: 2026-01-31T18:00:00Z
+//	    comment: nightly batch migration
+//
+// startsAt and endsAt are RFC 3339 times and both optional.
+type SilenceConfig struct {
+	Matchers []string  `mapstructure:"matchers"`
+	StartsAt time.Time `mapstructure:"startsAt"`
+	EndsAt   time.Time `mapstructure:"endsAt"`
+	Comment  string    `mapstructure:"comment"`
+}
+
+// Silence mutes the findings it matches while it is active
+type Silence struct {
+	Matchers []*Matcher
+	StartsAt time.Time
+	EndsAt   time.Time
+	Comment  string
+}
+
+// NewSilence parses a silence
+func NewSilence(config SilenceConfig) (*Silence, error) {
+	if len(config.Matchers) == 0 {
+		return nil, fmt.Errorf("at least one matcher is required")
+	}
+	matchers, err := ParseMatchers(config.Matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	if !config.EndsAt.IsZero() && config.EndsAt.Before(config.StartsAt) {
+		return nil, fmt.Errorf("endsAt is before startsAt")
+	}
+
+	return &Silence{
+		Matchers: matchers,
+		StartsAt: config.StartsAt,
+		EndsAt:   config.EndsAt,
+		Comment:  config.Comment,
+	}, nil
+}
+
+// Active reports whether the silence is in effect at the given time
+func (s *Silence) Active(now time.Time) bool {
+	if !s.StartsAt.IsZero() && now.Before(s.StartsAt) {
+		return false
+	}
+	return s.EndsAt.IsZero() || now.Before(s.EndsAt)
+}
+
+// Matches reports whether the silence mutes the finding
+func (s *Silence) Matches(finding output.Finding) bool {
+	return matchAll(s.Matchers, finding)
+}
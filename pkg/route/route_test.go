@@ -0,0 +1,177 @@
+package route
+
+import (
+	"testing"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+var payments = output.Finding{
+	Fingerprint: "pod-payments-api",
+	Kind:        "Pod",
+	Namespace:   "payments",
+	Name:        "api-0",
+	Severity:    output.SeverityCritical,
+	Reason:      "CrashLoopBackOff",
+	Labels:      map[string]string{"team": "payments"},
+}
+
+func TestParseMatcher(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    bool
+		wantErr bool
+	}{
+		{text: `namespace="payments"`, want: true},
+		{text: `namespace = payments`, want: true},
+		{text: `namespace!="payments"`, want: false},
+		{text: `namespace=~"pay.*"`, want: true},
+		{text: `namespace=~"pay"`, want: false},
+		{text: `namespace!~"staging-.*"`, want: true},
+		{text: `labels.team="payments"`, want: true},
+		{text: `labels.tier=""`, want: true},
+		{text: `severity="warning"`, want: false},
+		{text: `owner="payments"`, wantErr: true},
+		{text: `namespace=~"("`, wantErr: true},
+		{text: `namespace`, wantErr: true},
+		{text: `namespace="unterminated`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			matcher, err := ParseMatcher(tt.text)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMatcher(%q) succeeded, want an error", tt.text)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMatcher(%q): %v", tt.text, err)
+			}
+			if got := matcher.Matches(payments); got != tt.want {
+				t.Errorf("%s matches = %v, want %v", matcher, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteMatch(t *testing.T) {
+	root, err := New(Config{
+		Receiver:       "team-slack",
+		GroupBy:        []string{"namespace"},
+		RepeatInterval: "4h",
+		Routes: []Config{
+			{Matchers: []string{`namespace="payments"`, `severity="critical"`}, Receiver: "payments-pagerduty", Continue: true},
+			{Matchers: []string{`namespace="payments"`}, Receiver: "payments-slack", RepeatInterval: "1h"},
+			{Matchers: []string{`severity="info"`}, Receiver: NoReceiver},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	warning := payments
+	warning.Severity = output.SeverityWarning
+	info := payments
+	info.Namespace = "default"
+	info.Severity = output.SeverityInfo
+	other := payments
+	other.Namespace = "default"
+
+	tests := []struct {
+		name      string
+		finding   output.Finding
+		receivers []string
+	}{
+		{"continue also tries the next sibling", payments, []string{"payments-pagerduty", "payments-slack"}},
+		{"first matching child wins", warning, []string{"payments-slack"}},
+		{"none drops the finding", info, []string{NoReceiver}},
+		{"unmatched findings stay at the root", other, []string{"team-slack"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := root.Match(tt.finding)
+			var receivers []string
+			for _, route := range routes {
+				receivers = append(receivers, route.Receiver)
+			}
+			if len(receivers) != len(tt.receivers) {
+				t.Fatalf("receivers = %v, want %v", receivers, tt.receivers)
+			}
+			for i := range receivers {
+				if receivers[i] != tt.receivers[i] {
+					t.Fatalf("receivers = %v, want %v", receivers, tt.receivers)
+				}
+			}
+		})
+	}
+
+	// Children inherit the group and repeat interval they do not set
+	child := root.Routes[0]
+	if len(child.GroupBy) != 1 || child.GroupBy[0] != "namespace" || child.RepeatInterval != 4*time.Hour {
+		t.Errorf("child route = %+v, want groupBy and repeatInterval inherited", child)
+	}
+	if root.Routes[1].RepeatInterval != time.Hour {
+		t.Errorf("repeatInterval = %s, want the child's own 1h", root.Routes[1].RepeatInterval)
+	}
+}
+
+func TestNewRouteErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"invalid matcher", Config{Routes: []Config{{Matchers: []string{"namespace"}}}}},
+		{"unknown groupBy field", Config{GroupBy: []string{"owner"}}},
+		{"invalid repeat interval", Config{RepeatInterval: "often"}},
+		{"negative repeat interval", Config{RepeatInterval: "-1h"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.config); err == nil {
+				t.Errorf("New succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestSilence(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		config  SilenceConfig
+		want    bool
+		wantErr bool
+	}{
+		{name: "open ended", config: SilenceConfig{Matchers: []string{`namespace="payments"`}}, want: true},
+		{name: "active window", config: SilenceConfig{Matchers: []string{`namespace="payments"`}, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}, want: true},
+		{name: "not started", config: SilenceConfig{Matchers: []string{`namespace="payments"`}, StartsAt: now.Add(time.Hour)}, want: false},
+		{name: "expired", config: SilenceConfig{Matchers: []string{`namespace="payments"`}, EndsAt: now}, want: false},
+		{name: "other findings", config: SilenceConfig{Matchers: []string{`labels.team="platform"`}}, want: false},
+		{name: "no matchers", config: SilenceConfig{}, wantErr: true},
+		{name: "ends before it starts", config: SilenceConfig{Matchers: []string{`kind="Pod"`}, StartsAt: now, EndsAt: now.Add(-time.Hour)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			silence, err := NewSilence(tt.config)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewSilence succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewSilence: %v", err)
+			}
+			if got := silence.Active(now) && silence.Matches(payments); got != tt.want {
+				t.Errorf("silenced = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package route
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// Router dispatches notifications through a routing tree. Each notification
+// is split into one notification per route, receiver and group, with the
+// silenced findings removed.
+type Router struct {
+	root      *Route
+	silences  []*Silence
+	receivers map[string]output.Notifier
+	statePath string
+	now       func() time.Time
+
+	mu    sync.Mutex
+	state *state
+}
+
+// sentGroup records the last notification of a group, so that unchanged
+// groups are not sent again before the repeat interval has passed
+type sentGroup struct {
+	Hash     string        `json:"hash"`
+	SentAt   time.Time     `json:"sentAt"`
+	Interval time.Duration `json:"interval"`
+}
+
+// state is the content of the state file
+type state struct {
+	Groups map[string]*sentGroup `json:"groups"`
+}
+
+// NewRouter creates a router. Every receiver of the tree must be one of
+// receivers. The repeat interval state is kept in statePath so that it
+// holds across separate runs; an empty path keeps it in memory.
+func NewRouter(root *Route, silences []*Silence, receivers map[string]output.Notifier, statePath string) (*Router, error) {
+	for _, receiver := range root.Receivers() {
+		if _, ok := receivers[receiver]; !ok {
+			return nil, fmt.Errorf("route refers to unknown receiver %q", receiver)
+		}
+	}
+
+	return &Router{
+		root:      root,
+		silences:  silences,
+		receivers: receivers,
+		statePath: statePath,
+		now:       time.Now,
+	}, nil
+}
+
+// DefaultStatePath returns the default location of the state file
+func DefaultStatePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kubegpt", "notifications.json"), nil
+}
+
+// group is the part of a notification that goes to one receiver
+type group struct {
+	key      string
+	route    *Route
+	labels   []string
+	findings []output.Finding
+}
+
+// Dispatch routes the findings of a notification and delivers one
+// notification per receiver and group. A notification without findings is
+// routed by its namespace alone. Silenced findings are dropped, and a group
+// that is identical to the one last sent within its route's repeat interval
+// is skipped, except for resolved and analysis notifications.
+func (r *Router) Dispatch(ctx context.Context, notification output.Notification) (int, []error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	findings := notification.Findings
+	if len(findings) == 0 {
+		findings = []output.Finding{{Namespace: notification.Namespace}}
+	}
+
+	var groups []*group
+	byKey := make(map[string]*group)
+	for _, finding := range findings {
+		if finding.Fingerprint != "" && r.silenced(finding, now) {
+			continue
+		}
+
+		for _, route := range r.root.Match(finding) {
+			if route.Receiver == "" || route.Receiver == NoReceiver {
+				continue
+			}
+
+			labels := route.groupKey(finding)
+			key := strings.Join(append([]string{route.ID, route.Receiver, string(notification.Type)}, labels...), "|")
+			g, ok := byKey[key]
+			if !ok {
+				g = &group{key: key, route: route, labels: labels}
+				byKey[key] = g
+				groups = append(groups, g)
+			}
+			if finding.Fingerprint != "" {
+				g.findings = append(g.findings, finding)
+			}
+		}
+	}
+	if len(groups) == 0 {
+		return 0, nil
+	}
+
+	if err := r.load(); err != nil {
+		return 0, []error{err}
+	}
+
+	var sent int
+	var errs []error
+	for _, g := range groups {
+		hash := groupHash(g.findings)
+		throttled := notification.Type != output.NotificationResolved && notification.Type != output.NotificationAnalysis
+		if last, ok := r.state.Groups[g.key]; ok && throttled && last.Hash == hash && now.Sub(last.SentAt) < g.route.RepeatInterval {
+			continue
+		}
+
+		part := notification
+		part.Findings = g.findings
+		if part.Findings == nil {
+			part.Findings = []output.Finding{}
+		}
+		if len(g.labels) > 0 && len(g.findings) < len(notification.Findings) {
+			part.Title = fmt.Sprintf("%s (%s)", notification.Title, strings.Join(g.labels, ", "))
+		}
+
+		if err := r.receivers[g.route.Receiver].Notify(ctx, part); err != nil {
+			errs = append(errs, fmt.Errorf("sending to receiver %s: %w", g.route.Receiver, err))
+			continue
+		}
+		sent++
+		if throttled && g.route.RepeatInterval > 0 {
+			r.state.Groups[g.key] = &sentGroup{Hash: hash, SentAt: now, Interval: g.route.RepeatInterval}
+		}
+	}
+
+	if err := r.save(now); err != nil {
+		errs = append(errs, err)
+	}
+	return sent, errs
+}
+
+// silenced reports whether an active silence mutes the finding
+func (r *Router) silenced(finding output.Finding, now time.Time) bool {
+	for _, silence := range r.silences {
+		if silence.Active(now) && silence.Matches(finding) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupHash identifies the content of a group by the identity, severity and
+// reason of its findings, so that a changed finding is sent again at once
+func groupHash(findings []output.Finding) string {
+	entries := make([]string, len(findings))
+	for i, finding := range findings {
+		entries[i] = strings.Join([]string{finding.Fingerprint, string(finding.Severity), finding.Reason}, "/")
+	}
+	sort.Strings(entries)
+
+	sum := sha256.Sum256([]byte(strings.Join(entries, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// load reads the state file once. A missing file is an empty state.
+func (r *Router) load() error {
+	if r.state != nil {
+		return nil
+	}
+	r.state = &state{Groups: make(map[string]*sentGroup)}
+	if r.statePath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(r.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading notification state: %w", err)
+	}
+	if err := json.Unmarshal(data, r.state); err != nil {
+		return fmt.Errorf("parsing notification state %s: %w", r.statePath, err)
+	}
+	if r.state.Groups == nil {
+		r.state.Groups = make(map[string]*sentGroup)
+	}
+	return nil
+}
+
+// save drops the groups whose repeat interval has passed and writes the
+// state file atomically
+func (r *Router) save(now time.Time) error {
+	for key, group := range r.state.Groups {
+		if now.Sub(group.SentAt) >= group.Interval {
+			delete(r.state.Groups, key)
+		}
+	}
+	if r.statePath == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(r.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.statePath), 0700); err != nil {
+		return fmt.Errorf("writing notification state: %w", err)
+	}
+	tmp := r.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing notification state: %w", err)
+	}
+	if err := os.Rename(tmp, r.statePath); err != nil {
+		return fmt.Errorf("writing notification state: %w", err)
+	}
+	return nil
+}
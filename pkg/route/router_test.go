@@ -0,0 +1,186 @@
+package route
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// recorder is a receiver that records the findings of every notification
+type recorder struct {
+	name string
+	sent *[]string
+	err  error
+}
+
+func (r recorder) Name() string {
+	return r.name
+}
+
+func (r recorder) Notify(ctx context.Context, notification output.Notification) error {
+	if r.err != nil {
+		return r.err
+	}
+	var names []string
+	for _, finding := range notification.Findings {
+		names = append(names, finding.Name)
+	}
+	*r.sent = append(*r.sent, r.name+":"+strings.Join(names, ","))
+	return nil
+}
+
+func pod(namespace, name string, severity output.Severity) output.Finding {
+	return output.Finding{
+		Fingerprint: namespace + "/" + name,
+		Kind:        "Pod",
+		Namespace:   namespace,
+		Name:        name,
+		Severity:    severity,
+		Reason:      "CrashLoopBackOff",
+	}
+}
+
+func TestRouterDispatch(t *testing.T) {
+	web := pod("default", "web", output.SeverityCritical)
+	worker := pod("default", "worker", output.SeverityWarning)
+	batch := pod("batch", "nightly", output.SeverityWarning)
+	api := pod("payments", "api", output.SeverityCritical)
+
+	config := Config{
+		Receiver:       "slack",
+		GroupBy:        []string{"namespace"},
+		RepeatInterval: "1h",
+		Routes: []Config{
+			{Matchers: []string{`namespace="payments"`}, Receiver: "pagerduty", GroupBy: []string{GroupByAll}},
+		},
+	}
+	silence := SilenceConfig{Matchers: []string{`namespace="batch"`}}
+
+	// step is a dispatch at an offset from the first one
+	type step struct {
+		at           time.Duration
+		notification output.Notification
+		want         []string
+	}
+	notify := func(typ output.NotificationType, findings ...output.Finding) output.Notification {
+		return output.Notification{Type: typ, Namespace: "default", Findings: findings}
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "groups by namespace and drops silenced findings",
+			steps: []step{
+				{notification: notify(output.NotificationReport, web, worker, batch, api), want: []string{"pagerduty:api", "slack:web,worker"}},
+			},
+		},
+		{
+			name: "unchanged groups wait for the repeat interval",
+			steps: []step{
+				{notification: notify(output.NotificationReport, web), want: []string{"slack:web"}},
+				{at: 30 * time.Minute, notification: notify(output.NotificationReport, web)},
+				{at: time.Hour, notification: notify(output.NotificationReport, web), want: []string{"slack:web"}},
+			},
+		},
+		{
+			name: "changed groups are sent at once",
+			steps: []step{
+				{notification: notify(output.NotificationReport, web), want: []string{"slack:web"}},
+				{at: time.Minute, notification: notify(output.NotificationReport, web, worker), want: []string{"slack:web,worker"}},
+			},
+		},
+		{
+			name: "resolved notifications are never throttled",
+			steps: []step{
+				{notification: notify(output.NotificationResolved, web), want: []string{"slack:web"}},
+				{at: time.Minute, notification: notify(output.NotificationResolved, web), want: []string{"slack:web"}},
+			},
+		},
+		{
+			name: "notifications without findings route by namespace",
+			steps: []step{
+				{notification: output.Notification{Type: output.NotificationAlert, Namespace: "payments", Findings: []output.Finding{}}, want: []string{"pagerduty:"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := New(config)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			muted, err := NewSilence(silence)
+			if err != nil {
+				t.Fatalf("NewSilence: %v", err)
+			}
+
+			var sent []string
+			receivers := map[string]output.Notifier{
+				"slack":     recorder{name: "slack", sent: &sent},
+				"pagerduty": recorder{name: "pagerduty", sent: &sent},
+			}
+			router, err := NewRouter(root, []*Silence{muted}, receivers, "")
+			if err != nil {
+				t.Fatalf("NewRouter: %v", err)
+			}
+
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, s := range tt.steps {
+				sent = nil
+				router.now = func() time.Time { return start.Add(s.at) }
+				if _, errs := router.Dispatch(context.Background(), s.notification); len(errs) > 0 {
+					t.Fatalf("step %d: Dispatch: %v", i+1, errs)
+				}
+				sort.Strings(sent)
+				if strings.Join(sent, " ") != strings.Join(s.want, " ") {
+					t.Errorf("step %d: sent %q, want %q", i+1, sent, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterDispatchRetriesFailedReceivers(t *testing.T) {
+	root, err := New(Config{Receiver: "slack", RepeatInterval: "1h"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var sent []string
+	failing := recorder{name: "slack", sent: &sent, err: errors.New("unavailable")}
+	router, err := NewRouter(root, nil, map[string]output.Notifier{"slack": failing}, "")
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	notification := output.Notification{Type: output.NotificationReport, Findings: []output.Finding{pod("default", "web", output.SeverityCritical)}}
+	if n, errs := router.Dispatch(context.Background(), notification); n != 0 || len(errs) != 1 {
+		t.Fatalf("Dispatch = %d, %v, want 0 sent and one error", n, errs)
+	}
+
+	// A failed send is not recorded, so it is not throttled
+	router.receivers["slack"] = recorder{name: "slack", sent: &sent}
+	if n, errs := router.Dispatch(context.Background(), notification); n != 1 || len(errs) != 0 {
+		t.Errorf("Dispatch after recovery = %d, %v, want 1 sent", n, errs)
+	}
+}
+
+func TestNewRouterUnknownReceiver(t *testing.T) {
+	root, err := New(Config{Receiver: "slack", Routes: []Config{{Matchers: []string{`severity="critical"`}, Receiver: "pagerduty"}}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var sent []string
+	if _, err := NewRouter(root, nil, map[string]output.Notifier{"slack": recorder{name: "slack", sent: &sent}}, ""); err == nil {
+		t.Error("NewRouter succeeded, want an error for the unknown pagerduty receiver")
+	}
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// AlertmanagerPayload is the body Alertmanager posts to webhook receivers
+// (https://prometheus.io/docs/alerting/latest/configuration/#webhook_config)
+type AlertmanagerPayload struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	TruncatedAlerts   int               `json:"truncatedAlerts"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []Alert           `json:"alerts"`
+}
+
+// Alert is a single alert in an Alertmanager payload
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// AlertRequest is a single alert scoped to the Kubernetes objects named in
+// its labels
+type AlertRequest struct {
+	Fingerprint string
+	AlertName   string
+	Status      string
+	Summary     string
+	Namespace   string
+	Kinds       []string
+	// Names are the names of the scoped objects by kind, so that pod=api
+	// does not also select a deployment named api
+	Names  map[string][]string
+	Labels map[string]string
+}
+
+// AlertResponse reports what was done for a single alert
+type AlertResponse struct {
+	Fingerprint string `json:"fingerprint"`
+	AlertName   string `json:"alertname"`
+	Status      string `json:"status"`
+	Skipped     string `json:"skipped,omitempty"`
+	// Error is why the alert could not be handled. Other alerts of the batch
+	// are still handled, and the batch is acknowledged, so that Alertmanager
+	// does not resend the alerts that were notified.
+	Error   string            `json:"error,omitempty"`
+	Results *output.Report    `json:"results,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// NewAlertRequest scopes an alert to the objects named by its namespace, pod,
+// deployment and service labels. Alerts that only carry a namespace are
+// scoped to the whole namespace.
+func NewAlertRequest(alert Alert) AlertRequest {
+	req := AlertRequest{
+		Fingerprint: alert.Fingerprint,
+		AlertName:   alert.Labels["alertname"],
+		Status:      alert.Status,
+		Namespace:   alert.Labels["namespace"],
+		Labels:      alert.Labels,
+	}
+
+	req.Summary = alert.Annotations["summary"]
+	if req.Summary == "" {
+		req.Summary = alert.Annotations["description"]
+	}
+
+	for _, kind := range []string{"pod", "deployment", "service"} {
+		name := alert.Labels[kind]
+		if name == "" {
+			continue
+		}
+		if req.Names == nil {
+			req.Names = make(map[string][]string)
+		}
+		req.Kinds = append(req.Kinds, kind)
+		req.Names[kind] = append(req.Names[kind], name)
+	}
+
+	// Events about the scoped objects usually explain why the alert fired
+	if len(req.Kinds) > 0 {
+		req.Kinds = append(req.Kinds, "event")
+	}
+
+	return req
+}
+
+func (s *Server) handleAlertmanager(w http.ResponseWriter, r *http.Request) {
+	// Alertmanager adds fields over time, so unlike the other endpoints the
+	// payload is decoded leniently
+	var payload AlertmanagerPayload
+	if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20)).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid Alertmanager payload: %w", err))
+		return
+	}
+
+	responses := []AlertResponse{}
+	for _, alert := range payload.Alerts {
+		req := NewAlertRequest(alert)
+		response := AlertResponse{
+			Fingerprint: req.Fingerprint,
+			AlertName:   req.AlertName,
+			Status:      req.Status,
+			Labels:      req.Labels,
+		}
+
+		if req.Namespace == "" {
+			response.Skipped = "alert has no namespace label"
+			responses = append(responses, response)
+			continue
+		}
+
+		results, errs, err := s.backend.Alert(r.Context(), req)
+		if err != nil {
+			response.Error = err.Error()
+			responses = append(responses, response)
+			continue
+		}
+
+		if req.Status == "firing" {
+			report := output.NewReport(results, errs)
+			response.Results = &report
+		}
+		responses = append(responses, response)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"alerts": responses})
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewAlertRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		labels    map[string]string
+		wantKinds []string
+		wantNames map[string][]string
+	}{
+		{
+			name:      "pod",
+			labels:    map[string]string{"namespace": "shop", "pod": "api-7d9f"},
+			wantKinds: []string{"pod", "event"},
+			wantNames: map[string][]string{"pod": {"api-7d9f"}},
+		},
+		{
+			name:      "names are kept per kind",
+			labels:    map[string]string{"namespace": "shop", "pod": "api", "service": "checkout"},
+			wantKinds: []string{"pod", "service", "event"},
+			wantNames: map[string][]string{"pod": {"api"}, "service": {"checkout"}},
+		},
+		{
+			name:   "namespace only",
+			labels: map[string]string{"namespace": "shop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := NewAlertRequest(Alert{Labels: tt.labels})
+			if !reflect.DeepEqual(req.Kinds, tt.wantKinds) {
+				t.Errorf("Kinds = %q, want %q", req.Kinds, tt.wantKinds)
+			}
+			if !reflect.DeepEqual(req.Names, tt.wantNames) {
+				t.Errorf("Names = %v, want %v", req.Names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestHandleAlertmanager(t *testing.T) {
+	payload := `{
+		"version": "4",
+		"groupKey": "{}:{alertname=\"KubePodCrashLooping\"}",
+		"status": "firing",
+		"receiver": "kubegpt",
+		"alerts": [
+			{
+				"status": "firing",
+				"labels": {"alertname": "KubePodCrashLooping", "namespace": "shop", "pod": "api-7d9f", "severity": "critical"},
+				"annotations": {"summary": "Pod is crash looping"},
+				"startsAt": "2024-05-01T12:00:00Z",
+				"fingerprint": "a1"
+			},
+			{
+				"status": "resolved",
+				"labels": {"alertname": "KubeDeploymentReplicasMismatch", "namespace": "shop", "deployment": "api"},
+				"annotations": {"description": "Deployment has not matched the expected number of replicas"},
+				"startsAt": "2024-05-01T11:00:00Z",
+				"endsAt": "2024-05-01T12:05:00Z",
+				"fingerprint": "b2"
+			},
+			{
+				"status": "firing",
+				"labels": {"alertname": "NodeDiskPressure", "node": "worker-1"},
+				"fingerprint": "c3"
+			},
+			{
+				"status": "firing",
+				"labels": {"alertname": "KubeServiceDown", "namespace": "shop", "service": "checkout"},
+				"fingerprint": "d4",
+				"unknownField": true
+			}
+		]
+	}`
+
+	backend := &fakeBackend{alertErr: map[string]error{"KubeServiceDown": errors.New("cluster unreachable")}}
+	server := New(Config{}, backend)
+	req := httptest.NewRequest(http.MethodPost, "/v1/alertmanager", strings.NewReader(payload))
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var response struct {
+		Alerts []AlertResponse `json:"alerts"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+
+	type summary struct {
+		Fingerprint, Status, Skipped, Error string
+		Results                             bool
+	}
+	var got []summary
+	for _, alert := range response.Alerts {
+		got = append(got, summary{alert.Fingerprint, alert.Status, alert.Skipped, alert.Error, alert.Results != nil})
+	}
+	want := []summary{
+		{"a1", "firing", "", "", true},
+		{"b2", "resolved", "", "", false},
+		{"c3", "firing", "alert has no namespace label", "", false},
+		{"d4", "firing", "", "cluster unreachable", false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("alerts = %+v, want %+v", got, want)
+	}
+
+	// The alert without a namespace never reaches the backend
+	if len(backend.alerts) != 3 {
+		t.Fatalf("backend got %d alerts, want 3", len(backend.alerts))
+	}
+	crashLooping := backend.alerts[0]
+	if crashLooping.AlertName != "KubePodCrashLooping" || crashLooping.Namespace != "shop" || crashLooping.Summary != "Pod is crash looping" {
+		t.Errorf("alert request = %+v, want the crash looping pod in shop", crashLooping)
+	}
+	if resolved := backend.alerts[1]; resolved.Summary != "Deployment has not matched the expected number of replicas" {
+		t.Errorf("summary = %q, want the description when there is no summary", resolved.Summary)
+	}
+}
+
+func TestHandleAlertmanagerInvalidPayload(t *testing.T) {
+	server := New(Config{}, &fakeBackend{})
+	status, message := do(t, server, "POST", "/v1/alertmanager", "", `{"alerts": {}}`)
+	if status != http.StatusBadRequest || !strings.Contains(message, "invalid Alertmanager payload") {
+		t.Errorf("status = %d (%s), want 400", status, message)
+	}
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// DiagnoseRequest is the body of POST /v1/diagnose
+type DiagnoseRequest struct {
+	Namespace string   `json:"namespace"`
+	Kinds     []string `json:"kinds,omitempty"`
+	Names     []string `json:"names,omitempty"`
+	Analyze   bool     `json:"analyze"`
+	Fix       bool     `json:"fix"`
+	MaxItems  int      `json:"maxItems,omitempty"`
+}
+
+// ExplainRequest is the body of POST /v1/explain
+type ExplainRequest struct {
+	Content string `json:"content"`
+}
+
+// ExplainResponse is the response of POST /v1/explain
+type ExplainResponse struct {
+	Explanation string        `json:"explanation"`
+	Diagnosis   *ai.Diagnosis `json:"diagnosis"`
+}
+
+// ReportRequest holds the query parameters of GET /v1/report
+type ReportRequest struct {
+	Namespace string
+}
+
+// supportedKinds are the values accepted in DiagnoseRequest.Kinds
+var supportedKinds = map[string]bool{
+	"pod":        true,
+	"event":      true,
+	"deployment": true,
+	"service":    true,
+}
+
+// Backend runs the actual diagnostics for the HTTP API. Errors returned by a
+// backend are fatal for the request; partial failures belong in the returned
+// error list instead.
+type Backend interface {
+	Diagnose(ctx context.Context, req DiagnoseRequest) (output.DiagnosticResults, []error, error)
+	Explain(ctx context.Context, req ExplainRequest) (*ai.Diagnosis, error)
+	Report(ctx context.Context, req ReportRequest) (output.DiagnosticResults, []error, error)
+	// Alert diagnoses the objects named by an Alertmanager alert and posts
+	// the analysis to the configured outputs
+	Alert(ctx context.Context, req AlertRequest) (output.DiagnosticResults, []error, error)
+}
+
+// Config configures the HTTP API
+type Config struct {
+	// Token is the bearer token clients must present. Authentication is
+	// disabled when it is empty.
+	Token string
+	// Timeout bounds the time spent on a single request
+	Timeout time.Duration
+	// MaxConcurrent is the number of requests processed at the same time.
+	// Requests beyond it are rejected with 429 Too Many Requests.
+	MaxConcurrent int
+	// Metrics, if set, is served at /metrics. Like /healthz it is not
+	// authenticated, so that it can be scraped like any exporter.
+	Metrics http.Handler
+}
+
+// Server serves the KubeGPT HTTP API
+type Server struct {
+	config  Config
+	backend Backend
+	slots   chan struct{}
+	mux     *http.ServeMux
+}
+
+// New creates a new API server
+func New(config Config, backend Backend) *Server {
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = 1
+	}
+
+	s := &Server{
+		config:  config,
+		backend: backend,
+		slots:   make(chan struct{}, config.MaxConcurrent),
+		mux:     http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	if config.Metrics != nil {
+		s.mux.Handle("/metrics", config.Metrics)
+	}
+	s.mux.Handle("/v1/diagnose", s.protect(http.MethodPost, s.handleDiagnose))
+	s.mux.Handle("/v1/explain", s.protect(http.MethodPost, s.handleExplain))
+	s.mux.Handle("/v1/report", s.protect(http.MethodGet, s.handleReport))
+	s.mux.Handle("/v1/alertmanager", s.protect(http.MethodPost, s.handleAlertmanager))
+
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Handle registers an additional handler behind the same method check,
+// authentication, timeout and concurrency limit as the built-in endpoints
+func (s *Server) Handle(pattern, method string, handler http.HandlerFunc) {
+	s.mux.Handle(pattern, s.protect(method, handler))
+}
+
+// protect wraps a handler with method checking, authentication, the
+// concurrency limit and the request timeout
+func (s *Server) protect(method string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="kubegpt"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+
+		select {
+		case s.slots <- struct{}{}:
+			defer func() { <-s.slots }()
+		default:
+			writeError(w, http.StatusTooManyRequests, errors.New("too many concurrent requests"))
+			return
+		}
+
+		if s.config.Timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), s.config.Timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+
+		next(w, r)
+	})
+}
+
+// authorized checks the bearer token of a request
+func (s *Server) authorized(r *http.Request) bool {
+	if s.config.Token == "" {
+		return true
+	}
+
+	header := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.config.Token)) == 1
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleDiagnose(w http.ResponseWriter, r *http.Request) {
+	var req DiagnoseRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	for _, kind := range req.Kinds {
+		if !supportedKinds[strings.ToLower(kind)] {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported kind %q", kind))
+			return
+		}
+	}
+
+	results, errs, err := s.backend.Diagnose(r.Context(), req)
+	if err != nil {
+		writeBackendError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, output.NewReport(results, errs))
+}
+
+func (s *Server) handleExplain(w http.ResponseWriter, r *http.Request) {
+	var req ExplainRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if strings.TrimSpace(req.Content) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("content is required"))
+		return
+	}
+
+	diagnosis, err := s.backend.Explain(r.Context(), req)
+	if err != nil {
+		writeBackendError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ExplainResponse{Explanation: diagnosis.Markdown(), Diagnosis: diagnosis})
+}
+
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	req := ReportRequest{Namespace: r.URL.Query().Get("namespace")}
+
+	results, errs, err := s.backend.Report(r.Context(), req)
+	if err != nil {
+		writeBackendError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, output.NewReport(results, errs))
+}
+
+// decodeJSON decodes a JSON request body, treating an empty body as an empty object
+func decodeJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// writeBackendError maps a backend error to a response, reporting timeouts as 504
+func writeBackendError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		writeError(w, http.StatusGatewayTimeout, fmt.Errorf("request timed out: %w", err))
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
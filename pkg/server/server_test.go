@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/ai"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// fakeBackend answers every request with empty results. Diagnose blocks until
+// release is closed, if set, after signalling started.
+type fakeBackend struct {
+	mu       sync.Mutex
+	diagnose []DiagnoseRequest
+	alerts   []AlertRequest
+	alertErr map[string]error
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func (b *fakeBackend) Diagnose(ctx context.Context, req DiagnoseRequest) (output.DiagnosticResults, []error, error) {
+	b.mu.Lock()
+	b.diagnose = append(b.diagnose, req)
+	b.mu.Unlock()
+
+	if b.started != nil {
+		b.started <- struct{}{}
+	}
+	if b.release != nil {
+		select {
+		case <-b.release:
+		case <-ctx.Done():
+			return output.DiagnosticResults{}, nil, ctx.Err()
+		}
+	}
+	return output.DiagnosticResults{Namespace: req.Namespace}, nil, nil
+}
+
+func (b *fakeBackend) Explain(ctx context.Context, req ExplainRequest) (*ai.Diagnosis, error) {
+	return &ai.Diagnosis{Summary: "explained"}, nil
+}
+
+func (b *fakeBackend) Report(ctx context.Context, req ReportRequest) (output.DiagnosticResults, []error, error) {
+	return output.DiagnosticResults{Namespace: req.Namespace}, nil, nil
+}
+
+func (b *fakeBackend) Alert(ctx context.Context, req AlertRequest) (output.DiagnosticResults, []error, error) {
+	b.mu.Lock()
+	b.alerts = append(b.alerts, req)
+	b.mu.Unlock()
+	return output.DiagnosticResults{Namespace: req.Namespace}, nil, b.alertErr[req.AlertName]
+}
+
+// do sends a request to the server and returns the status and the decoded
+// error message, if any
+func do(t *testing.T, handler http.Handler, method, path, token, body string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var response struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &response)
+	return rec.Code, response.Error
+}
+
+func TestServerRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{name: "health is not authenticated", method: "GET", path: "/healthz", wantStatus: http.StatusOK},
+		{name: "missing token", method: "POST", path: "/v1/diagnose", body: `{}`, wantStatus: http.StatusUnauthorized, wantError: "bearer token"},
+		{name: "wrong token", method: "POST", path: "/v1/diagnose", token: "guess", body: `{}`, wantStatus: http.StatusUnauthorized},
+		{name: "diagnose", method: "POST", path: "/v1/diagnose", token: "secret", body: `{"namespace":"default","kinds":["Pod"]}`, wantStatus: http.StatusOK},
+		{name: "empty body", method: "POST", path: "/v1/diagnose", token: "secret", wantStatus: http.StatusOK},
+		{name: "wrong method", method: "GET", path: "/v1/diagnose", token: "secret", wantStatus: http.StatusMethodNotAllowed, wantError: "method GET not allowed"},
+		{name: "malformed JSON", method: "POST", path: "/v1/diagnose", token: "secret", body: `{"namespace":`, wantStatus: http.StatusBadRequest, wantError: "invalid request body"},
+		{name: "unknown field", method: "POST", path: "/v1/diagnose", token: "secret", body: `{"namespaces":"default"}`, wantStatus: http.StatusBadRequest, wantError: "unknown field"},
+		{name: "unsupported kind", method: "POST", path: "/v1/diagnose", token: "secret", body: `{"kinds":["ingress"]}`, wantStatus: http.StatusBadRequest, wantError: `unsupported kind "ingress"`},
+		{name: "explain", method: "POST", path: "/v1/explain", token: "secret", body: `{"content":"CrashLoopBackOff"}`, wantStatus: http.StatusOK},
+		{name: "explain without content", method: "POST", path: "/v1/explain", token: "secret", body: `{"content":" "}`, wantStatus: http.StatusBadRequest, wantError: "content is required"},
+		{name: "report", method: "GET", path: "/v1/report?namespace=default", token: "secret", wantStatus: http.StatusOK},
+		{name: "report by POST", method: "POST", path: "/v1/report", token: "secret", wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	server := New(Config{Token: "secret"}, &fakeBackend{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, message := do(t, server, tt.method, tt.path, tt.token, tt.body)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d (%s), want %d", status, message, tt.wantStatus)
+			}
+			if !strings.Contains(message, tt.wantError) {
+				t.Errorf("error = %q, want it to mention %q", message, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestServerWithoutToken(t *testing.T) {
+	server := New(Config{}, &fakeBackend{})
+	if status, message := do(t, server, "POST", "/v1/diagnose", "", `{}`); status != http.StatusOK {
+		t.Errorf("status = %d (%s), want 200 without authentication", status, message)
+	}
+}
+
+func TestServerConcurrencyLimit(t *testing.T) {
+	backend := &fakeBackend{started: make(chan struct{}), release: make(chan struct{})}
+	server := New(Config{MaxConcurrent: 1}, backend)
+
+	done := make(chan int)
+	go func() {
+		status, _ := do(t, server, "POST", "/v1/diagnose", "", `{}`)
+		done <- status
+	}()
+	<-backend.started
+
+	if status, message := do(t, server, "POST", "/v1/diagnose", "", `{}`); status != http.StatusTooManyRequests {
+		t.Errorf("concurrent request status = %d (%s), want 429", status, message)
+	}
+	if status, _ := do(t, server, "GET", "/healthz", "", ""); status != http.StatusOK {
+		t.Errorf("health status = %d while busy, want 200", status)
+	}
+
+	close(backend.release)
+	if status := <-done; status != http.StatusOK {
+		t.Errorf("first request status = %d, want 200", status)
+	}
+
+	backend.started = nil
+	if status, message := do(t, server, "POST", "/v1/diagnose", "", `{}`); status != http.StatusOK {
+		t.Errorf("status after the first request = %d (%s), want its slot freed", status, message)
+	}
+}
+
+func TestServerTimeout(t *testing.T) {
+	backend := &fakeBackend{release: make(chan struct{})}
+	server := New(Config{Timeout: 20 * time.Millisecond}, backend)
+
+	status, message := do(t, server, "POST", "/v1/diagnose", "", `{}`)
+	if status != http.StatusGatewayTimeout || !strings.Contains(message, "request timed out") {
+		t.Errorf("status = %d (%s), want 504", status, message)
+	}
+}
+
+func TestServerBackendError(t *testing.T) {
+	server := New(Config{}, &fakeBackend{})
+	server.Handle("/v1/fail", http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+		writeBackendError(w, r, errors.New("cluster unreachable"))
+	})
+	status, message := do(t, server, "POST", "/v1/fail", "", "")
+	if status != http.StatusInternalServerError || message != "cluster unreachable" {
+		t.Errorf("status = %d (%s), want 500 with the backend error", status, message)
+	}
+}
@@ -0,0 +1,95 @@
+package ticket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GitHubAPIURL is the base URL of the GitHub REST API. GitHub Enterprise
+// Server uses https://<host>/api/v3.
+const GitHubAPIURL = "https://api.github.com"
+
+// GitHub files tickets as GitHub issues
+type GitHub struct {
+	// Repo is the repository in owner/name form
+	Repo   string
+	Token  string
+	Labels []string
+	// URL overrides GitHubAPIURL, for example with a local stand-in
+	URL        string
+	HTTPClient *http.Client
+}
+
+// githubIssue is the body of issue create and update requests
+type githubIssue struct {
+	Title       string   `json:"title,omitempty"`
+	Body        string   `json:"body,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	State       string   `json:"state,omitempty"`
+	StateReason string   `json:"state_reason,omitempty"`
+}
+
+// Name identifies the provider
+func (g *GitHub) Name() string {
+	return "github"
+}
+
+// Create opens an issue and returns its number
+func (g *GitHub) Create(ctx context.Context, ticket Ticket) (string, error) {
+	var created struct {
+		Number int `json:"number"`
+	}
+	err := doJSON(ctx, g.HTTPClient, http.MethodPost, g.repoURL()+"/issues", g.headers(), githubIssue{
+		Title:  ticket.Title,
+		Body:   MarkdownBody(ticket),
+		Labels: append([]string{"kubegpt"}, g.Labels...),
+	}, &created)
+	if err != nil {
+		return "", err
+	}
+	if created.Number == 0 {
+		return "", fmt.Errorf("response has no issue number")
+	}
+	return strconv.Itoa(created.Number), nil
+}
+
+// Update replaces the title and body of an issue
+func (g *GitHub) Update(ctx context.Context, id string, ticket Ticket) error {
+	return doJSON(ctx, g.HTTPClient, http.MethodPatch, g.repoURL()+"/issues/"+id, g.headers(), githubIssue{
+		Title: ticket.Title,
+		Body:  MarkdownBody(ticket),
+	}, nil)
+}
+
+// Close closes an issue as completed
+func (g *GitHub) Close(ctx context.Context, id string) error {
+	return doJSON(ctx, g.HTTPClient, http.MethodPatch, g.repoURL()+"/issues/"+id, g.headers(), githubIssue{
+		State:       "closed",
+		StateReason: "completed",
+	}, nil)
+}
+
+// Comment adds a comment to an issue
+func (g *GitHub) Comment(ctx context.Context, id string, comment string) error {
+	return doJSON(ctx, g.HTTPClient, http.MethodPost, g.repoURL()+"/issues/"+id+"/comments", g.headers(),
+		map[string]string{"body": comment}, nil)
+}
+
+func (g *GitHub) repoURL() string {
+	base := g.URL
+	if base == "" {
+		base = GitHubAPIURL
+	}
+	return strings.TrimSuffix(base, "/") + "/repos/" + g.Repo
+}
+
+func (g *GitHub) headers() map[string]string {
+	return map[string]string{
+		"Authorization":        "Bearer " + g.Token,
+		"Accept":               "application/vnd.github+json",
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+}
@@ -0,0 +1,150 @@
+package ticket
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// Jira files tickets as Jira issues through the REST API v2, which Jira Cloud
+// and Jira Data Center both serve
+type Jira struct {
+	// URL is the base URL of the Jira site, such as https://example.atlassian.net
+	URL string
+	// Email and Token authenticate with basic auth on Jira Cloud. Without an
+	// email, Token is sent as a bearer personal access token.
+	Email     string
+	Token     string
+	Project   string
+	IssueType string
+	Labels    []string
+	// CloseTransition names the transition that closes an issue. By default
+	// the first transition into the done status category is used.
+	CloseTransition string
+	HTTPClient      *http.Client
+}
+
+// jiraFields are the issue fields kubegpt sets
+type jiraFields struct {
+	Project     *jiraKey  `json:"project,omitempty"`
+	IssueType   *jiraName `json:"issuetype,omitempty"`
+	Summary     string    `json:"summary"`
+	Description string    `json:"description"`
+	Labels      []string  `json:"labels,omitempty"`
+}
+
+type jiraKey struct {
+	Key string `json:"key"`
+}
+
+type jiraName struct {
+	Name string `json:"name"`
+}
+
+// jiraTransition is an entry of the transitions of an issue
+type jiraTransition struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	To   struct {
+		StatusCategory struct {
+			Key string `json:"key"`
+		} `json:"statusCategory"`
+	} `json:"to"`
+}
+
+// Name identifies the provider
+func (j *Jira) Name() string {
+	return "jira"
+}
+
+// Create opens an issue and returns its key
+func (j *Jira) Create(ctx context.Context, ticket Ticket) (string, error) {
+	issueType := j.IssueType
+	if issueType == "" {
+		issueType = "Bug"
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	err := doJSON(ctx, j.HTTPClient, http.MethodPost, j.apiURL("issue"), j.headers(), map[string]jiraFields{
+		"fields": {
+			Project:     &jiraKey{Key: j.Project},
+			IssueType:   &jiraName{Name: issueType},
+			Summary:     output.TruncateRunes(ticket.Title, 255),
+			Description: JiraBody(ticket),
+			Labels:      append([]string{"kubegpt"}, j.Labels...),
+		},
+	}, &created)
+	if err != nil {
+		return "", err
+	}
+	if created.Key == "" {
+		return "", fmt.Errorf("response has no issue key")
+	}
+	return created.Key, nil
+}
+
+// Update replaces the summary and description of an issue
+func (j *Jira) Update(ctx context.Context, id string, ticket Ticket) error {
+	return doJSON(ctx, j.HTTPClient, http.MethodPut, j.apiURL("issue", id), j.headers(), map[string]jiraFields{
+		"fields": {
+			Summary:     output.TruncateRunes(ticket.Title, 255),
+			Description: JiraBody(ticket),
+		},
+	}, nil)
+}
+
+// Close moves an issue to a done status
+func (j *Jira) Close(ctx context.Context, id string) error {
+	var available struct {
+		Transitions []jiraTransition `json:"transitions"`
+	}
+	if err := doJSON(ctx, j.HTTPClient, http.MethodGet, j.apiURL("issue", id, "transitions"), j.headers(), nil, &available); err != nil {
+		return err
+	}
+
+	for _, transition := range available.Transitions {
+		matches := transition.To.StatusCategory.Key == "done"
+		if j.CloseTransition != "" {
+			matches = strings.EqualFold(transition.Name, j.CloseTransition)
+		}
+		if matches {
+			return doJSON(ctx, j.HTTPClient, http.MethodPost, j.apiURL("issue", id, "transitions"), j.headers(),
+				map[string]map[string]string{"transition": {"id": transition.ID}}, nil)
+		}
+	}
+
+	if j.CloseTransition != "" {
+		return fmt.Errorf("issue %s has no transition named %q", id, j.CloseTransition)
+	}
+	return fmt.Errorf("issue %s has no transition to a done status", id)
+}
+
+// Comment adds a comment to an issue
+func (j *Jira) Comment(ctx context.Context, id string, comment string) error {
+	return doJSON(ctx, j.HTTPClient, http.MethodPost, j.apiURL("issue", id, "comment"), j.headers(),
+		map[string]string{"body": comment}, nil)
+}
+
+// apiURL joins path segments onto the REST API v2 base URL
+func (j *Jira) apiURL(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+	return strings.TrimSuffix(j.URL, "/") + "/rest/api/2/" + strings.Join(escaped, "/")
+}
+
+func (j *Jira) headers() map[string]string {
+	if j.Email == "" {
+		return map[string]string{"Authorization": "Bearer " + j.Token}
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte(j.Email + ":" + j.Token))
+	return map[string]string{"Authorization": "Basic " + credentials}
+}
@@ -0,0 +1,218 @@
+package ticket
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// footer closes every ticket body
+const footer = "Filed by kubegpt. This ticket is updated while the issue persists and closed once it resolves."
+
+// MarkdownBody renders a ticket as GitHub flavored markdown
+func MarkdownBody(ticket Ticket) string {
+	finding := ticket.Finding
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "<!-- kubegpt fingerprint: %s -->\n", finding.Fingerprint)
+	fmt.Fprintf(&b, "| | |\n|---|---|\n")
+	for _, row := range summaryRows(ticket) {
+		fmt.Fprintf(&b, "| **%s** | %s |\n", row[0], escapeTableCell(row[1]))
+	}
+
+	if finding.Message != "" {
+		fmt.Fprintf(&b, "\n### Message\n\n```\n%s\n```\n", strings.TrimSpace(finding.Message))
+	}
+
+	if evidence := evidenceRows(finding); len(evidence) > 0 {
+		b.WriteString("\n### Evidence\n\n")
+		for i, row := range evidence {
+			fmt.Fprintf(&b, "| %s |\n", strings.Join(escapeTableCells(row), " | "))
+			if i == 0 {
+				fmt.Fprintf(&b, "|%s\n", strings.Repeat("---|", len(row)))
+			}
+		}
+	}
+
+	if finding.Analysis != "" {
+		fmt.Fprintf(&b, "\n### Analysis\n\n%s\n", strings.TrimSpace(finding.Analysis))
+	}
+
+	if finding.SuggestedFix != "" {
+		fix := strings.TrimSpace(finding.SuggestedFix)
+		if !strings.Contains(fix, "```") {
+			fix = "```yaml\n" + fix + "\n```"
+		}
+		fmt.Fprintf(&b, "\n### Suggested fix\n\n%s\n", fix)
+	}
+
+	fmt.Fprintf(&b, "\n---\n_%s_\n", footer)
+	return b.String()
+}
+
+// JiraBody renders a ticket in Jira wiki markup, the description format of
+// the Jira REST API v2
+func JiraBody(ticket Ticket) string {
+	finding := ticket.Finding
+	var b strings.Builder
+
+	for _, row := range summaryRows(ticket) {
+		fmt.Fprintf(&b, "||%s|%s|\n", row[0], escapeJiraCell(row[1]))
+	}
+
+	if finding.Message != "" {
+		fmt.Fprintf(&b, "\nh3. Message\n{noformat}\n%s\n{noformat}\n", strings.TrimSpace(finding.Message))
+	}
+
+	if evidence := evidenceRows(finding); len(evidence) > 0 {
+		b.WriteString("\nh3. Evidence\n")
+		for i, row := range evidence {
+			cells := make([]string, len(row))
+			for j, cell := range row {
+				cells[j] = escapeJiraCell(cell)
+			}
+			separator := "|"
+			if i == 0 {
+				separator = "||"
+			}
+			fmt.Fprintf(&b, "%s%s%s\n", separator, strings.Join(cells, separator), separator)
+		}
+	}
+
+	if finding.Analysis != "" {
+		fmt.Fprintf(&b, "\nh3. Analysis\n%s\n", JiraMarkup(strings.TrimSpace(finding.Analysis)))
+	}
+
+	if finding.SuggestedFix != "" {
+		fix := strings.TrimSpace(finding.SuggestedFix)
+		if !strings.Contains(fix, "```") {
+			fix = "```yaml\n" + fix + "\n```"
+		}
+		fmt.Fprintf(&b, "\nh3. Suggested fix\n%s\n", JiraMarkup(fix))
+	}
+
+	fmt.Fprintf(&b, "\n----\n_%s_\n", footer)
+	return b.String()
+}
+
+// summaryRows lists the identity of the finding
+func summaryRows(ticket Ticket) [][2]string {
+	finding := ticket.Finding
+	rows := [][2]string{
+		{"Object", finding.Object()},
+		{"Severity", string(finding.Severity)},
+		{"Reason", finding.Reason},
+		{"First seen", ticket.FirstSeen.UTC().Format(time.RFC3339)},
+		{"Fingerprint", finding.Fingerprint},
+	}
+	if len(finding.Labels) > 0 {
+		var labels []string
+		for key, value := range finding.Labels {
+			labels = append(labels, key+"="+value)
+		}
+		sort.Strings(labels)
+		rows = append(rows, [2]string{"Labels", strings.Join(labels, ", ")})
+	}
+	return rows
+}
+
+// evidenceRows tabulates the state kubegpt observed, starting with a header
+// row, or returns nil if the finding has none
+func evidenceRows(finding output.Finding) [][]string {
+	switch {
+	case len(finding.Containers) > 0:
+		rows := [][]string{{"Container", "Ready", "Status", "Restarts", "Reason", "Message"}}
+		for _, container := range finding.Containers {
+			rows = append(rows, []string{
+				container.Name,
+				fmt.Sprint(container.Ready),
+				container.Status,
+				fmt.Sprint(container.Restarts),
+				container.Reason,
+				container.Message,
+			})
+		}
+		return rows
+	case finding.Replicas != nil:
+		replicas := finding.Replicas
+		return [][]string{
+			{"Desired", "Ready", "Updated", "Available"},
+			{fmt.Sprint(replicas.Desired), fmt.Sprint(replicas.Ready), fmt.Sprint(replicas.Updated), fmt.Sprint(replicas.Available)},
+		}
+	case finding.Event != nil:
+		event := finding.Event
+		return [][]string{
+			{"Event type", "Count", "Last seen"},
+			{event.Type, fmt.Sprint(event.Count), event.LastSeen},
+		}
+	}
+	return nil
+}
+
+// escapeTableCells escapes the cells of a markdown table row
+func escapeTableCells(cells []string) []string {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = escapeTableCell(cell)
+	}
+	return escaped
+}
+
+// escapeTableCell keeps a value on one line and from closing a markdown cell
+func escapeTableCell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// escapeJiraCell keeps a value on one line and from closing a Jira cell
+func escapeJiraCell(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return " "
+	}
+	return strings.ReplaceAll(text, "|", "\\|")
+}
+
+var (
+	codeFence      = regexp.MustCompile("(?s)```([\\w-]*)[ \t]*\n(.*?)```")
+	markdownHead   = regexp.MustCompile(`(?m)^(#{1,6})\s+(.+)$`)
+	markdownBold   = regexp.MustCompile(`\*\*([^*\n]+)\*\*`)
+	markdownCode   = regexp.MustCompile("`([^`\n]+)`")
+	markdownBullet = regexp.MustCompile(`(?m)^(\s*)[-*]\s+`)
+)
+
+// JiraMarkup converts the markdown produced by the AI to Jira wiki markup:
+// code fences, headings, bold, inline code and bullet lists
+func JiraMarkup(markdown string) string {
+	// Convert code blocks first and keep them out of the inline conversions
+	var blocks []string
+	text := codeFence.ReplaceAllStringFunc(markdown, func(fence string) string {
+		parts := codeFence.FindStringSubmatch(fence)
+		block := "{code}\n" + strings.TrimRight(parts[2], "\n") + "\n{code}"
+		if parts[1] != "" {
+			block = "{code:" + parts[1] + "}\n" + strings.TrimRight(parts[2], "\n") + "\n{code}"
+		}
+		blocks = append(blocks, block)
+		return fmt.Sprintf("\x00%d\x00", len(blocks)-1)
+	})
+
+	text = markdownHead.ReplaceAllStringFunc(text, func(heading string) string {
+		parts := markdownHead.FindStringSubmatch(heading)
+		return fmt.Sprintf("h%d. %s", len(parts[1]), parts[2])
+	})
+	text = markdownBullet.ReplaceAllStringFunc(text, func(bullet string) string {
+		indent := len(markdownBullet.FindStringSubmatch(bullet)[1])
+		return strings.Repeat("*", indent/2+1) + " "
+	})
+	text = markdownBold.ReplaceAllString(text, "*$1*")
+	text = markdownCode.ReplaceAllString(text, "{{$1}}")
+
+	for i, block := range blocks {
+		text = strings.Replace(text, fmt.Sprintf("\x00%d\x00", i), block, 1)
+	}
+	return text
+}
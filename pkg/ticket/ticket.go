@@ -0,0 +1,356 @@
+// Package ticket turns persistent findings into tracked tickets, one per
+// finding fingerprint, in GitHub Issues or Jira. Tickets are updated when the
+// finding changes on later runs and closed when it resolves.
+package ticket
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// Ticket is a finding as it is filed in an issue tracker
+type Ticket struct {
+	Title string
+	// FirstSeen is when kubegpt first detected the finding
+	FirstSeen time.Time
+	Finding   output.Finding
+}
+
+// Provider creates, updates and closes tickets in an issue tracker
+type Provider interface {
+	// Name identifies the provider in the state file and in error messages
+	Name() string
+	// Create files a ticket and returns its identifier, such as an issue
+	// number or key
+	Create(ctx context.Context, ticket Ticket) (string, error)
+	// Update replaces the title and body of an existing ticket
+	Update(ctx context.Context, id string, ticket Ticket) error
+	// Close closes a ticket
+	Close(ctx context.Context, id string) error
+	// Comment adds a comment to a ticket, which may be closed
+	Comment(ctx context.Context, id string, comment string) error
+}
+
+// NewTicket builds the ticket for a finding
+func NewTicket(finding output.Finding, firstSeen time.Time) Ticket {
+	return Ticket{
+		Title:     fmt.Sprintf("[kubegpt] %s: %s", finding.Object(), finding.Reason),
+		FirstSeen: firstSeen,
+		Finding:   finding,
+	}
+}
+
+// Record is a tracked finding in the state file
+type Record struct {
+	Fingerprint string    `json:"fingerprint"`
+	Kind        string    `json:"kind"`
+	Namespace   string    `json:"namespace"`
+	Object      string    `json:"object"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	// Tickets maps provider names to the ticket filed with them
+	Tickets map[string]string `json:"tickets,omitempty"`
+	// Hash identifies the content the tickets were last written with
+	Hash string `json:"hash,omitempty"`
+}
+
+// state is the content of the state file
+type state struct {
+	Findings map[string]*Record `json:"findings"`
+}
+
+// Scope describes what a run looked at. Tickets are only closed if the run
+// covered their namespace and kind; otherwise their absence says nothing
+// about the cluster.
+type Scope struct {
+	Namespace string
+	Kinds     map[string]bool
+}
+
+// Covers reports whether the scope covers a tracked finding
+func (s Scope) Covers(record *Record) bool {
+	return record.Namespace == s.Namespace && s.Kinds[record.Kind]
+}
+
+// Result summarizes a sync
+type Result struct {
+	Created []string
+	Updated []string
+	Closed  []string
+}
+
+// Options configures a Manager
+type Options struct {
+	// MinSeverity is the lowest severity that gets a ticket, warning by default
+	MinSeverity output.Severity
+	// MinAge is how long a finding must persist before a ticket is filed, so
+	// that transient failures stay out of the tracker
+	MinAge time.Duration
+	// StatePath is the state file, DefaultStatePath by default
+	StatePath string
+}
+
+// Manager files tickets for findings that persist for at least the minimum
+// age, keeps them up to date and closes them once a later run no longer sees
+// the finding. Tracked findings are kept in a state file so that this works
+// across separate runs.
+type Manager struct {
+	providers []Provider
+	options   Options
+	now       func() time.Time
+}
+
+// NewManager creates a manager
+func NewManager(providers []Provider, options Options) *Manager {
+	if options.MinSeverity == "" {
+		options.MinSeverity = output.SeverityWarning
+	}
+	return &Manager{providers: providers, options: options, now: time.Now}
+}
+
+// DefaultStatePath returns the default location of the state file
+func DefaultStatePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kubegpt", "tickets.json"), nil
+}
+
+// Sync files tickets for findings that reached the minimum age, updates the
+// tickets of findings that changed and closes the tickets of findings within
+// scope that are gone. Provider failures do not stop the sync; they are
+// returned and retried next time.
+func (m *Manager) Sync(ctx context.Context, findings []output.Finding, scope Scope) (Result, []error) {
+	var result Result
+	var errs []error
+
+	current, err := m.load()
+	if err != nil {
+		return result, []error{err}
+	}
+
+	now := m.now()
+	seen := make(map[string]bool)
+	for _, finding := range findings {
+		if finding.Severity.Rank() < m.options.MinSeverity.Rank() {
+			continue
+		}
+		seen[finding.Fingerprint] = true
+
+		record, ok := current.Findings[finding.Fingerprint]
+		if !ok {
+			record = &Record{
+				Fingerprint: finding.Fingerprint,
+				Kind:        finding.Kind,
+				Namespace:   finding.Namespace,
+				Object:      finding.Object(),
+				FirstSeen:   now,
+			}
+			current.Findings[finding.Fingerprint] = record
+		}
+		if now.Sub(record.FirstSeen) < m.options.MinAge {
+			continue
+		}
+		if record.Tickets == nil {
+			record.Tickets = make(map[string]string)
+		}
+
+		ticket := NewTicket(finding, record.FirstSeen)
+		hash := contentHash(ticket)
+		changed := record.Hash != hash
+		created, updated, failed := false, false, false
+		for _, provider := range m.providers {
+			id, ok := record.Tickets[provider.Name()]
+			if !ok {
+				id, err := provider.Create(ctx, ticket)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("creating %s ticket for %s: %w", provider.Name(), record.Object, err))
+					continue
+				}
+				record.Tickets[provider.Name()] = id
+				created = true
+				continue
+			}
+			if !changed {
+				continue
+			}
+			if err := provider.Update(ctx, id, ticket); err != nil {
+				errs = append(errs, fmt.Errorf("updating %s ticket %s for %s: %w", provider.Name(), id, record.Object, err))
+				failed = true
+				continue
+			}
+			updated = true
+		}
+		// Keep the old hash after a failed update so that it is retried
+		if !failed {
+			record.Hash = hash
+		}
+
+		if created {
+			result.Created = append(result.Created, record.Object)
+		} else if updated {
+			result.Updated = append(result.Updated, record.Object)
+		}
+	}
+
+	for fingerprint, record := range current.Findings {
+		if seen[fingerprint] || !scope.Covers(record) {
+			continue
+		}
+
+		comment := fmt.Sprintf("Resolved: kubegpt no longer detects this issue on %s (first seen %s).",
+			record.Object, record.FirstSeen.UTC().Format(time.RFC3339))
+		for _, provider := range m.providers {
+			id, ok := record.Tickets[provider.Name()]
+			if !ok {
+				continue
+			}
+			if err := provider.Close(ctx, id); err != nil {
+				errs = append(errs, fmt.Errorf("closing %s ticket %s for %s: %w", provider.Name(), id, record.Object, err))
+				continue
+			}
+			delete(record.Tickets, provider.Name())
+
+			// The comment comes after the close, so that a close that fails
+			// and is retried does not post it twice. A failed comment is not
+			// retried.
+			if err := provider.Comment(ctx, id, comment); err != nil {
+				errs = append(errs, fmt.Errorf("commenting on closed %s ticket %s for %s: %w", provider.Name(), id, record.Object, err))
+			}
+		}
+
+		if len(record.Tickets) == 0 {
+			if record.Hash != "" {
+				result.Closed = append(result.Closed, record.Object)
+			}
+			delete(current.Findings, fingerprint)
+		}
+	}
+
+	sort.Strings(result.Created)
+	sort.Strings(result.Updated)
+	sort.Strings(result.Closed)
+
+	if err := m.save(current); err != nil {
+		errs = append(errs, err)
+	}
+	return result, errs
+}
+
+// contentHash identifies what a ticket says. Only the stable parts of the
+// finding count: messages, restart and event counts change on every run
+// without the finding changing, and would churn the ticket.
+func contentHash(ticket Ticket) string {
+	finding := ticket.Finding
+	data, _ := json.Marshal(struct {
+		Title        string
+		Severity     output.Severity
+		Reason       string
+		Analysis     string
+		SuggestedFix string
+	}{ticket.Title, finding.Severity, finding.Reason, finding.Analysis, finding.SuggestedFix})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// load reads the state file. A missing file is an empty state.
+func (m *Manager) load() (*state, error) {
+	current := &state{Findings: make(map[string]*Record)}
+
+	data, err := os.ReadFile(m.options.StatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return current, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading ticket state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, current); err != nil {
+		return nil, fmt.Errorf("parsing ticket state %s: %w", m.options.StatePath, err)
+	}
+	if current.Findings == nil {
+		current.Findings = make(map[string]*Record)
+	}
+	return current, nil
+}
+
+// save writes the state file atomically
+func (m *Manager) save(current *state) error {
+	data, err := json.MarshalIndent(current, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.options.StatePath), 0700); err != nil {
+		return fmt.Errorf("writing ticket state: %w", err)
+	}
+	tmp := m.options.StatePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing ticket state: %w", err)
+	}
+	if err := os.Rename(tmp, m.options.StatePath); err != nil {
+		return fmt.Errorf("writing ticket state: %w", err)
+	}
+	return nil
+}
+
+// doJSON sends v as JSON, if not nil, and decodes the response into out, if
+// not nil. Non-2xx responses are errors.
+func doJSON(ctx context.Context, client *http.Client, method, url string, headers map[string]string, v, out interface{}) error {
+	var body io.Reader
+	if v != nil {
+		payload, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response %s: %s", resp.Status, output.TruncateRunes(string(bytes.TrimSpace(data)), 200))
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("parsing response: %w", err)
+		}
+	}
+	return nil
+}
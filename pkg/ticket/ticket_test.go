@@ -0,0 +1,415 @@
+package ticket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// fakeProvider records the calls it gets and fails while err is set
+type fakeProvider struct {
+	name  string
+	calls []string
+	err   error
+	next  int
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) Create(ctx context.Context, ticket Ticket) (string, error) {
+	p.calls = append(p.calls, "create "+ticket.Finding.Name)
+	if p.err != nil {
+		return "", p.err
+	}
+	p.next++
+	return fmt.Sprint(p.next), nil
+}
+
+func (p *fakeProvider) Update(ctx context.Context, id string, ticket Ticket) error {
+	p.calls = append(p.calls, "update "+id)
+	return p.err
+}
+
+func (p *fakeProvider) Close(ctx context.Context, id string) error {
+	p.calls = append(p.calls, "close "+id)
+	return p.err
+}
+
+func (p *fakeProvider) Comment(ctx context.Context, id string, comment string) error {
+	p.calls = append(p.calls, "comment "+id)
+	return p.err
+}
+
+func finding(kind, name string) output.Finding {
+	return output.Finding{
+		Fingerprint: strings.ToLower(kind) + "-" + name,
+		Kind:        kind,
+		Namespace:   "default",
+		Name:        name,
+		Severity:    output.SeverityCritical,
+		Reason:      "CrashLoopBackOff",
+		Message:     "back-off 10s restarting failed container",
+		Analysis:    "The container exits with code 1.",
+	}
+}
+
+func TestManagerSync(t *testing.T) {
+	web := finding("Pod", "web")
+	webRestarted := web
+	webRestarted.Message = "back-off 5m0s restarting failed container"
+	webRestarted.Containers = []output.ContainerFinding{{Name: "web", Restarts: 12}}
+	webAnalyzed := web
+	webAnalyzed.Analysis = "The container cannot reach its database."
+	api := finding("Deployment", "api")
+	all := Scope{Namespace: "default", Kinds: map[string]bool{"Pod": true, "Deployment": true}}
+	podsOnly := Scope{Namespace: "default", Kinds: map[string]bool{"Pod": true}}
+
+	// run is one sync, at an offset from the first, and the provider calls
+	// it must make
+	type run struct {
+		after    time.Duration
+		findings []output.Finding
+		scope    Scope
+		fail     bool
+		want     []string
+	}
+
+	tests := []struct {
+		name string
+		runs []run
+	}{
+		{
+			name: "waits for the minimum age",
+			runs: []run{
+				{findings: []output.Finding{web}, scope: all},
+				{after: 5 * time.Minute, findings: []output.Finding{web}, scope: all},
+				{after: 10 * time.Minute, findings: []output.Finding{web}, scope: all, want: []string{"create web"}},
+				{after: 15 * time.Minute, findings: []output.Finding{web}, scope: all},
+			},
+		},
+		{
+			name: "forgets findings that resolve before the minimum age",
+			runs: []run{
+				{findings: []output.Finding{web}, scope: all},
+				{after: 5 * time.Minute, scope: all},
+				{after: 10 * time.Minute, findings: []output.Finding{web}, scope: all},
+			},
+		},
+		{
+			name: "updates only when the analysis changes",
+			runs: []run{
+				{findings: []output.Finding{web}, scope: all},
+				{after: 10 * time.Minute, findings: []output.Finding{web}, scope: all, want: []string{"create web"}},
+				{after: 15 * time.Minute, findings: []output.Finding{webRestarted}, scope: all},
+				{after: 20 * time.Minute, findings: []output.Finding{webAnalyzed}, scope: all, want: []string{"update 1"}},
+				{after: 25 * time.Minute, findings: []output.Finding{webAnalyzed}, scope: all},
+			},
+		},
+		{
+			name: "closes before commenting on resolve",
+			runs: []run{
+				{findings: []output.Finding{web}, scope: all},
+				{after: 10 * time.Minute, findings: []output.Finding{web}, scope: all, want: []string{"create web"}},
+				{after: 15 * time.Minute, scope: all, want: []string{"close 1", "comment 1"}},
+				{after: 20 * time.Minute, scope: all},
+			},
+		},
+		{
+			name: "retries failed closes without commenting",
+			runs: []run{
+				{findings: []output.Finding{web}, scope: all},
+				{after: 10 * time.Minute, findings: []output.Finding{web}, scope: all, want: []string{"create web"}},
+				{after: 15 * time.Minute, scope: all, fail: true, want: []string{"close 1"}},
+				{after: 20 * time.Minute, scope: all, want: []string{"close 1", "comment 1"}},
+			},
+		},
+		{
+			name: "keeps tickets outside the scope open",
+			runs: []run{
+				{findings: []output.Finding{web, api}, scope: all},
+				{after: 10 * time.Minute, findings: []output.Finding{web, api}, scope: all, want: []string{"create web", "create api"}},
+				{after: 15 * time.Minute, scope: podsOnly, want: []string{"close 1", "comment 1"}},
+				{after: 20 * time.Minute, scope: Scope{Namespace: "prod", Kinds: all.Kinds}},
+				{after: 25 * time.Minute, scope: all, want: []string{"close 2", "comment 2"}},
+			},
+		},
+		{
+			name: "retries failed updates",
+			runs: []run{
+				{findings: []output.Finding{web}, scope: all},
+				{after: 10 * time.Minute, findings: []output.Finding{web}, scope: all, want: []string{"create web"}},
+				{after: 15 * time.Minute, findings: []output.Finding{webAnalyzed}, scope: all, fail: true, want: []string{"update 1"}},
+				{after: 20 * time.Minute, findings: []output.Finding{webAnalyzed}, scope: all, want: []string{"update 1"}},
+				{after: 25 * time.Minute, findings: []output.Finding{webAnalyzed}, scope: all},
+			},
+		},
+	}
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{name: "fake"}
+			statePath := filepath.Join(t.TempDir(), "tickets.json")
+
+			for i, r := range tt.runs {
+				provider.calls = nil
+				provider.err = nil
+				if r.fail {
+					provider.err = errors.New("unavailable")
+				}
+
+				// A new manager for every run goes through the state file
+				manager := NewManager([]Provider{provider}, Options{MinAge: 10 * time.Minute, StatePath: statePath})
+				manager.now = func() time.Time { return start.Add(r.after) }
+
+				_, errs := manager.Sync(context.Background(), r.findings, r.scope)
+				if r.fail != (len(errs) > 0) {
+					t.Errorf("run %d: errors = %v, want failures %v", i+1, errs, r.fail)
+				}
+				if !reflect.DeepEqual(provider.calls, r.want) {
+					t.Errorf("run %d: calls = %q, want %q", i+1, provider.calls, r.want)
+				}
+			}
+		})
+	}
+}
+
+func TestManagerSyncFailingProvider(t *testing.T) {
+	web := finding("Pod", "web")
+	webAnalyzed := web
+	webAnalyzed.Analysis = "The container cannot reach its database."
+	scope := Scope{Namespace: "default", Kinds: map[string]bool{"Pod": true}}
+
+	failing := &fakeProvider{name: "failing"}
+	working := &fakeProvider{name: "working"}
+	manager := NewManager([]Provider{failing, working}, Options{StatePath: filepath.Join(t.TempDir(), "tickets.json")})
+
+	if _, errs := manager.Sync(context.Background(), []output.Finding{web}, scope); len(errs) > 0 {
+		t.Fatalf("Sync: %v", errs)
+	}
+
+	failing.err = errors.New("unavailable")
+	failing.calls, working.calls = nil, nil
+	result, errs := manager.Sync(context.Background(), []output.Finding{webAnalyzed}, scope)
+	if len(errs) != 1 {
+		t.Errorf("errors = %v, want the failing provider's", errs)
+	}
+	if want := []string{"update 1"}; !reflect.DeepEqual(working.calls, want) {
+		t.Errorf("working provider calls = %q, want %q", working.calls, want)
+	}
+	if want := []string{"Pod default/web"}; !reflect.DeepEqual(result.Updated, want) {
+		t.Errorf("updated %q, want %q", result.Updated, want)
+	}
+}
+
+func TestManagerSyncStateFile(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state", "tickets.json")
+	provider := &fakeProvider{name: "fake"}
+	scope := Scope{Namespace: "default", Kinds: map[string]bool{"Pod": true}}
+
+	manager := NewManager([]Provider{provider}, Options{StatePath: statePath})
+	result, errs := manager.Sync(context.Background(), []output.Finding{finding("Pod", "web")}, scope)
+	if len(errs) > 0 {
+		t.Fatalf("Sync: %v", errs)
+	}
+	if want := []string{"Pod default/web"}; !reflect.DeepEqual(result.Created, want) {
+		t.Errorf("created %q, want %q", result.Created, want)
+	}
+
+	current, err := manager.load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	record := current.Findings["pod-web"]
+	if record == nil || record.Object != "Pod default/web" || record.Tickets["fake"] != "1" || record.Hash == "" {
+		t.Errorf("record = %+v, want the filed ticket", record)
+	}
+}
+
+// recordedRequest is a request an issue tracker stand-in received
+type recordedRequest struct {
+	Method        string
+	Path          string
+	Authorization string
+	Body          map[string]interface{}
+}
+
+// tracker stands in for an issue tracker, answering paths with the given
+// responses and recording the requests
+func tracker(t *testing.T, responses map[string]string) (*httptest.Server, *[]recordedRequest) {
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := recordedRequest{Method: r.Method, Path: r.URL.Path, Authorization: r.Header.Get("Authorization")}
+		json.NewDecoder(r.Body).Decode(&request.Body)
+		requests = append(requests, request)
+
+		response, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			response = "{}"
+		}
+		fmt.Fprint(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestGitHub(t *testing.T) {
+	server, requests := tracker(t, map[string]string{
+		"POST /repos/acme/shop/issues": `{"number": 42}`,
+	})
+	github := &GitHub{Repo: "acme/shop", Token: "ghp_test", Labels: []string{"prod"}, URL: server.URL + "/"}
+	ticket := NewTicket(finding("Pod", "web"), time.Now())
+	ctx := context.Background()
+
+	id, err := github.Create(ctx, ticket)
+	if err != nil || id != "42" {
+		t.Fatalf("Create() = %q, %v, want issue 42", id, err)
+	}
+	if err := github.Update(ctx, id, ticket); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := github.Close(ctx, id); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := github.Comment(ctx, id, "Resolved"); err != nil {
+		t.Fatalf("Comment: %v", err)
+	}
+
+	want := []recordedRequest{
+		{"POST", "/repos/acme/shop/issues", "Bearer ghp_test", map[string]interface{}{
+			"title":  "[kubegpt] Pod default/web: CrashLoopBackOff",
+			"body":   MarkdownBody(ticket),
+			"labels": []interface{}{"kubegpt", "prod"},
+		}},
+		{"PATCH", "/repos/acme/shop/issues/42", "Bearer ghp_test", map[string]interface{}{
+			"title": "[kubegpt] Pod default/web: CrashLoopBackOff",
+			"body":  MarkdownBody(ticket),
+		}},
+		{"PATCH", "/repos/acme/shop/issues/42", "Bearer ghp_test", map[string]interface{}{
+			"state":        "closed",
+			"state_reason": "completed",
+		}},
+		{"POST", "/repos/acme/shop/issues/42/comments", "Bearer ghp_test", map[string]interface{}{
+			"body": "Resolved",
+		}},
+	}
+	if !reflect.DeepEqual(*requests, want) {
+		t.Errorf("requests =\n%+v\nwant\n%+v", *requests, want)
+	}
+}
+
+func TestJira(t *testing.T) {
+	transitions := `{"transitions": [
+		{"id": "11", "name": "In Progress", "to": {"statusCategory": {"key": "indeterminate"}}},
+		{"id": "31", "name": "Done", "to": {"statusCategory": {"key": "done"}}},
+		{"id": "41", "name": "Won't Fix", "to": {"statusCategory": {"key": "done"}}}
+	]}`
+
+	tests := []struct {
+		name            string
+		email           string
+		closeTransition string
+		wantAuth        string
+		wantTransition  string
+		wantErr         bool
+	}{
+		{
+			name:           "cloud with basic auth",
+			email:          "bot@example.com",
+			wantAuth:       "Basic Ym90QGV4YW1wbGUuY29tOmppcmEtdG9rZW4=",
+			wantTransition: "31",
+		},
+		{
+			name:            "data center with a personal access token",
+			closeTransition: "won't fix",
+			wantAuth:        "Bearer jira-token",
+			wantTransition:  "41",
+		},
+		{
+			name:            "unknown close transition",
+			closeTransition: "Archived",
+			wantAuth:        "Bearer jira-token",
+			wantErr:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := tracker(t, map[string]string{
+				"POST /rest/api/2/issue":                  `{"key": "OPS-7"}`,
+				"GET /rest/api/2/issue/OPS-7/transitions": transitions,
+			})
+			jira := &Jira{URL: server.URL, Email: tt.email, Token: "jira-token", Project: "OPS", CloseTransition: tt.closeTransition}
+			ticket := NewTicket(finding("Pod", "web"), time.Now())
+			ctx := context.Background()
+
+			id, err := jira.Create(ctx, ticket)
+			if err != nil || id != "OPS-7" {
+				t.Fatalf("Create() = %q, %v, want OPS-7", id, err)
+			}
+			if err := jira.Update(ctx, id, ticket); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+			err = jira.Close(ctx, id)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Close() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			if err := jira.Comment(ctx, id, "Resolved"); err != nil {
+				t.Fatalf("Comment: %v", err)
+			}
+
+			want := []recordedRequest{
+				{"POST", "/rest/api/2/issue", tt.wantAuth, map[string]interface{}{"fields": map[string]interface{}{
+					"project":     map[string]interface{}{"key": "OPS"},
+					"issuetype":   map[string]interface{}{"name": "Bug"},
+					"summary":     "[kubegpt] Pod default/web: CrashLoopBackOff",
+					"description": JiraBody(ticket),
+					"labels":      []interface{}{"kubegpt"},
+				}}},
+				{"PUT", "/rest/api/2/issue/OPS-7", tt.wantAuth, map[string]interface{}{"fields": map[string]interface{}{
+					"summary":     "[kubegpt] Pod default/web: CrashLoopBackOff",
+					"description": JiraBody(ticket),
+				}}},
+				{"GET", "/rest/api/2/issue/OPS-7/transitions", tt.wantAuth, nil},
+				{"POST", "/rest/api/2/issue/OPS-7/transitions", tt.wantAuth, map[string]interface{}{
+					"transition": map[string]interface{}{"id": tt.wantTransition},
+				}},
+				{"POST", "/rest/api/2/issue/OPS-7/comment", tt.wantAuth, map[string]interface{}{"body": "Resolved"}},
+			}
+			if !reflect.DeepEqual(*requests, want) {
+				t.Errorf("requests =\n%+v\nwant\n%+v", *requests, want)
+			}
+		})
+	}
+}
+
+func TestDoJSONErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	github := &GitHub{Repo: "acme/shop", Token: "expired", URL: server.URL}
+	_, err := github.Create(context.Background(), NewTicket(finding("Pod", "web"), time.Now()))
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "Bad credentials") {
+		t.Errorf("Create() error = %v, want the status and body", err)
+	}
+}
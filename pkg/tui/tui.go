@@ -0,0 +1,425 @@
+// Package tui is the full-screen interface of diagnose --tui. It lists the
+// findings of a run by severity, shows the details of the selected one and
+// requests analyses and fixes on demand.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// Backend collects results and talks to the AI on behalf of the interface
+type Backend interface {
+	// Collect diagnoses a namespace. Collector errors are returned together
+	// with whatever the remaining collectors found.
+	Collect(ctx context.Context, namespace string) (output.DiagnosticResults, []error)
+	// Details fetches the events and logs of a finding's object
+	Details(ctx context.Context, finding output.Finding) (Details, error)
+	// Analyze asks the AI to analyze a finding
+	Analyze(ctx context.Context, results output.DiagnosticResults, finding output.Finding) (string, error)
+	// Fix asks the AI for a fix of a finding
+	Fix(ctx context.Context, results output.DiagnosticResults, finding output.Finding) (string, error)
+}
+
+// Details are the events and logs of a finding's object, fetched when the
+// finding is first selected
+type Details struct {
+	// Events are formatted one per line, oldest first
+	Events []string
+	// Logs maps container names to their recent logs
+	Logs map[string]string
+}
+
+// Timeouts of the backend calls
+const (
+	collectTimeout = 30 * time.Second
+	detailsTimeout = 15 * time.Second
+	aiTimeout      = 90 * time.Second
+)
+
+// kinds are the values the kind filter cycles through; empty shows all
+var kinds = []string{"", "Pod", "Deployment", "Service", "Event"}
+
+// Messages of the backend commands
+type (
+	collectedMsg struct {
+		results output.DiagnosticResults
+		errs    []error
+	}
+	detailsMsg struct {
+		fingerprint string
+		details     Details
+		err         error
+	}
+	aiMsg struct {
+		fingerprint string
+		fix         bool
+		text        string
+		err         error
+	}
+)
+
+// detailState is the fetch state of the details of a finding
+type detailState struct {
+	loading bool
+	details Details
+	err     error
+}
+
+// model is the bubbletea model of the interface
+type model struct {
+	backend   Backend
+	namespace string
+
+	results  output.DiagnosticResults
+	errs     []error
+	findings []output.Finding
+	visible  []output.Finding
+	cursor   int
+	offset   int
+	kind     int
+
+	details  map[string]*detailState
+	analyses map[string]string
+	fixes    map[string]string
+	aiErrors map[string]error
+	pending  map[string]string
+
+	loading      bool
+	status       string
+	width        int
+	height       int
+	detailFocus  bool
+	showHelp     bool
+	editing      bool
+	namespaceBox textinput.Model
+	detail       viewport.Model
+}
+
+// Run starts the interface on the given namespace and blocks until the user
+// quits
+func Run(backend Backend, namespace string) error {
+	_, err := tea.NewProgram(newModel(backend, namespace), tea.WithAltScreen()).Run()
+	return err
+}
+
+// newModel creates the model of the interface
+func newModel(backend Backend, namespace string) *model {
+	namespaceBox := textinput.New()
+	namespaceBox.Prompt = "Namespace: "
+	namespaceBox.CharLimit = 253
+
+	return &model{
+		backend:      backend,
+		namespace:    namespace,
+		details:      make(map[string]*detailState),
+		analyses:     make(map[string]string),
+		fixes:        make(map[string]string),
+		aiErrors:     make(map[string]error),
+		pending:      make(map[string]string),
+		loading:      true,
+		namespaceBox: namespaceBox,
+		detail:       viewport.New(0, 0),
+	}
+}
+
+// Init starts the first collection
+func (m *model) Init() tea.Cmd {
+	return m.collect()
+}
+
+// collect runs the backend collection for the current namespace
+func (m *model) collect() tea.Cmd {
+	m.loading = true
+	m.status = fmt.Sprintf("Collecting findings in %s...", m.namespaceLabel())
+	backend, namespace := m.backend, m.namespace
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+		defer cancel()
+		results, errs := backend.Collect(ctx, namespace)
+		return collectedMsg{results: results, errs: errs}
+	}
+}
+
+// fetchDetails loads the details of the selected finding once
+func (m *model) fetchDetails() tea.Cmd {
+	finding, ok := m.selected()
+	if !ok {
+		return nil
+	}
+	if _, ok := m.details[finding.Fingerprint]; ok {
+		return nil
+	}
+
+	m.details[finding.Fingerprint] = &detailState{loading: true}
+	backend := m.backend
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), detailsTimeout)
+		defer cancel()
+		details, err := backend.Details(ctx, finding)
+		return detailsMsg{fingerprint: finding.Fingerprint, details: details, err: err}
+	}
+}
+
+// requestAI asks the backend for an analysis or fix of the selected finding
+func (m *model) requestAI(fix bool) tea.Cmd {
+	finding, ok := m.selected()
+	if !ok {
+		return nil
+	}
+	if _, busy := m.pending[finding.Fingerprint]; busy {
+		return nil
+	}
+
+	what := "analysis"
+	if fix {
+		what = "fix"
+	}
+	m.pending[finding.Fingerprint] = what
+	delete(m.aiErrors, finding.Fingerprint)
+	m.status = fmt.Sprintf("Requesting %s for %s...", what, finding.Object())
+
+	backend, results := m.backend, m.results
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), aiTimeout)
+		defer cancel()
+
+		var text string
+		var err error
+		if fix {
+			text, err = backend.Fix(ctx, results, finding)
+		} else {
+			text, err = backend.Analyze(ctx, results, finding)
+		}
+		return aiMsg{fingerprint: finding.Fingerprint, fix: fix, text: text, err: err}
+	}
+}
+
+// Update handles input and backend results
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.refreshDetail(false)
+		return m, nil
+
+	case collectedMsg:
+		m.loading = false
+		m.results, m.errs = msg.results, msg.errs
+		m.setFindings(msg.results.Findings())
+		m.status = fmt.Sprintf("%d findings in %s at %s", len(m.findings), m.namespaceLabel(), msg.results.Timestamp.Format("15:04:05"))
+		if len(msg.errs) > 0 {
+			m.status = fmt.Sprintf("%d collector errors: %v", len(msg.errs), msg.errs[0])
+		}
+		return m, m.fetchDetails()
+
+	case detailsMsg:
+		m.details[msg.fingerprint] = &detailState{details: msg.details, err: msg.err}
+		m.refreshDetail(false)
+		return m, nil
+
+	case aiMsg:
+		delete(m.pending, msg.fingerprint)
+		switch {
+		case msg.err != nil:
+			m.aiErrors[msg.fingerprint] = msg.err
+			m.status = fmt.Sprintf("Error: %v", msg.err)
+		case msg.fix:
+			m.fixes[msg.fingerprint] = msg.text
+			m.status = "Fix ready"
+		default:
+			m.analyses[msg.fingerprint] = msg.text
+			m.status = "Analysis ready"
+		}
+		m.refreshDetail(false)
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.editing {
+			return m.updateNamespaceBox(msg)
+		}
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+// handleKey handles a key press outside of the namespace prompt
+func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "?":
+		m.showHelp = !m.showHelp
+		m.refreshDetail(true)
+	case "tab":
+		m.detailFocus = !m.detailFocus
+	case "up", "k":
+		if m.detailFocus {
+			m.detail.LineUp(1)
+		} else {
+			return m, m.move(-1)
+		}
+	case "down", "j":
+		if m.detailFocus {
+			m.detail.LineDown(1)
+		} else {
+			return m, m.move(1)
+		}
+	case "home", "g":
+		return m, m.move(-len(m.visible))
+	case "end", "G":
+		return m, m.move(len(m.visible))
+	case "pgup":
+		m.detail.HalfViewUp()
+	case "pgdown", " ":
+		m.detail.HalfViewDown()
+	case "a":
+		return m, m.requestAI(false)
+	case "f":
+		return m, m.requestAI(true)
+	case "r":
+		if !m.loading {
+			// Details and AI results of findings that persist stay cached
+			// by fingerprint; only the events and logs are refetched
+			m.details = make(map[string]*detailState)
+			return m, m.collect()
+		}
+	case "t":
+		m.kind = (m.kind + 1) % len(kinds)
+		m.applyFilter()
+		return m, m.fetchDetails()
+	case "n":
+		m.editing = true
+		current := m.namespace
+		if current == "" {
+			current = m.results.Namespace
+		}
+		m.namespaceBox.SetValue(current)
+		m.namespaceBox.CursorEnd()
+		return m, m.namespaceBox.Focus()
+	}
+	return m, nil
+}
+
+// updateNamespaceBox handles a key press in the namespace prompt. Enter
+// switches to the namespace and collects it; escape cancels.
+func (m *model) updateNamespaceBox(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.editing = false
+		m.namespaceBox.Blur()
+		namespace := strings.TrimSpace(m.namespaceBox.Value())
+		if namespace == "" || namespace == m.results.Namespace || m.loading {
+			return m, nil
+		}
+		m.namespace = namespace
+		m.details = make(map[string]*detailState)
+		return m, m.collect()
+	case "esc", "ctrl+c":
+		m.editing = false
+		m.namespaceBox.Blur()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.namespaceBox, cmd = m.namespaceBox.Update(msg)
+	return m, cmd
+}
+
+// move moves the cursor by delta and fetches the details of the new selection
+func (m *model) move(delta int) tea.Cmd {
+	if len(m.visible) == 0 {
+		return nil
+	}
+
+	cursor := m.cursor + delta
+	if cursor < 0 {
+		cursor = 0
+	}
+	if cursor >= len(m.visible) {
+		cursor = len(m.visible) - 1
+	}
+	if cursor == m.cursor {
+		return nil
+	}
+
+	m.cursor = cursor
+	m.refreshDetail(true)
+	return m.fetchDetails()
+}
+
+// setFindings replaces the findings, sorted by severity, keeping the
+// selection on the same finding if it is still there
+func (m *model) setFindings(findings []output.Finding) {
+	selected, _ := m.selected()
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Severity.Rank() != b.Severity.Rank() {
+			return a.Severity.Rank() > b.Severity.Rank()
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Object() < b.Object()
+	})
+
+	// Analyses that came with the results count as already requested
+	for _, finding := range findings {
+		if finding.Analysis != "" {
+			if _, ok := m.analyses[finding.Fingerprint]; !ok {
+				m.analyses[finding.Fingerprint] = finding.Analysis
+			}
+		}
+		if finding.SuggestedFix != "" {
+			if _, ok := m.fixes[finding.Fingerprint]; !ok {
+				m.fixes[finding.Fingerprint] = finding.SuggestedFix
+			}
+		}
+	}
+
+	m.findings = findings
+	m.applyFilter()
+	for i, finding := range m.visible {
+		if finding.Fingerprint == selected.Fingerprint {
+			m.cursor = i
+		}
+	}
+	m.refreshDetail(true)
+}
+
+// applyFilter recomputes the visible findings for the kind filter
+func (m *model) applyFilter() {
+	m.visible = m.visible[:0]
+	for _, finding := range m.findings {
+		if kinds[m.kind] == "" || finding.Kind == kinds[m.kind] {
+			m.visible = append(m.visible, finding)
+		}
+	}
+	m.cursor, m.offset = 0, 0
+	m.refreshDetail(true)
+}
+
+// selected returns the finding under the cursor
+func (m *model) selected() (output.Finding, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.visible) {
+		return output.Finding{}, false
+	}
+	return m.visible[m.cursor], true
+}
+
+// namespaceLabel names the namespace in status messages
+func (m *model) namespaceLabel() string {
+	if m.namespace == "" {
+		return "the current namespace"
+	}
+	return "namespace " + m.namespace
+}
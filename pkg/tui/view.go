@@ -0,0 +1,356 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// Styles of the interface. Severity colors are the ANSI colors of the
+// terminal report: red, yellow and cyan.
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("14"))
+	headingStyle  = lipgloss.NewStyle().Bold(true).Underline(true)
+	mutedStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	paneStyle     = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8"))
+	focusedStyle  = paneStyle.BorderForeground(lipgloss.Color("14"))
+
+	severityStyles = map[output.Severity]lipgloss.Style{
+		output.SeverityCritical: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("9")),
+		output.SeverityWarning:  lipgloss.NewStyle().Foreground(lipgloss.Color("11")),
+		output.SeverityInfo:     lipgloss.NewStyle().Foreground(lipgloss.Color("6")),
+	}
+)
+
+// keyHelp documents the keybindings in the help pane
+var keyHelp = [][2]string{
+	{"↑/k ↓/j", "select a finding, or scroll the detail pane when focused"},
+	{"g G", "jump to the first or last finding"},
+	{"tab", "switch focus between the list and the detail pane"},
+	{"pgup pgdn", "scroll the detail pane"},
+	{"a", "analyze the selected finding with AI"},
+	{"f", "generate a fix for the selected pod or deployment"},
+	{"r", "refresh the findings"},
+	{"t", "filter by kind: all, pods, deployments, services, events"},
+	{"n", "switch namespace"},
+	{"?", "toggle this help"},
+	{"q", "quit"},
+}
+
+// Minimum size of the list pane
+const minListWidth = 32
+
+// View renders the interface
+func (m *model) View() string {
+	if m.width == 0 || m.height == 0 {
+		return "Starting kubegpt..."
+	}
+
+	listWidth, detailWidth, bodyHeight := m.layout()
+	list := m.borderStyle(false).Width(listWidth).Height(bodyHeight).Render(m.renderList(listWidth, bodyHeight))
+	detail := m.borderStyle(true).Width(detailWidth).Height(bodyHeight).Render(m.detail.View())
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		m.renderHeader(),
+		lipgloss.JoinHorizontal(lipgloss.Top, list, detail),
+		m.renderFooter(),
+	)
+}
+
+// layout returns the inner width of both panes and their inner height
+func (m *model) layout() (listWidth, detailWidth, bodyHeight int) {
+	listWidth = m.width * 2 / 5
+	if listWidth < minListWidth {
+		listWidth = minListWidth
+	}
+	// Each pane has a border on both sides
+	detailWidth = m.width - listWidth - 2
+	if detailWidth < 1 {
+		detailWidth = 1
+	}
+	// One line each for the header and footer, two for the borders
+	bodyHeight = m.height - 4
+	if bodyHeight < 1 {
+		bodyHeight = 1
+	}
+	return listWidth - 2, detailWidth, bodyHeight
+}
+
+// borderStyle returns the border style of a pane, highlighting the focused one
+func (m *model) borderStyle(detail bool) lipgloss.Style {
+	if detail == m.detailFocus {
+		return focusedStyle
+	}
+	return paneStyle
+}
+
+// renderHeader renders the title line with the namespace, counts and filter
+func (m *model) renderHeader() string {
+	namespace := m.namespace
+	if namespace == "" {
+		namespace = "(current)"
+	}
+
+	counts := make(map[output.Severity]int)
+	for _, finding := range m.findings {
+		counts[finding.Severity]++
+	}
+
+	parts := []string{
+		titleStyle.Render("kubegpt"),
+		"namespace " + namespace,
+		severityStyles[output.SeverityCritical].Render(fmt.Sprintf("%d critical", counts[output.SeverityCritical])),
+		severityStyles[output.SeverityWarning].Render(fmt.Sprintf("%d warning", counts[output.SeverityWarning])),
+		severityStyles[output.SeverityInfo].Render(fmt.Sprintf("%d info", counts[output.SeverityInfo])),
+	}
+	if kinds[m.kind] != "" {
+		parts = append(parts, fmt.Sprintf("kind %s (%d shown)", kinds[m.kind], len(m.visible)))
+	}
+	return lipgloss.NewStyle().MaxWidth(m.width).Render(strings.Join(parts, mutedStyle.Render(" │ ")))
+}
+
+// renderFooter renders the namespace prompt or the status line
+func (m *model) renderFooter() string {
+	if m.editing {
+		return m.namespaceBox.View()
+	}
+
+	status := m.status
+	if len(m.pending) > 0 {
+		status = fmt.Sprintf("%s [%d AI requests running]", status, len(m.pending))
+	}
+	help := mutedStyle.Render("  a analyze · f fix · r refresh · t kind · n namespace · ? help · q quit")
+	return lipgloss.NewStyle().MaxWidth(m.width).Render(status + help)
+}
+
+// renderList renders the findings list, scrolled to keep the cursor visible
+func (m *model) renderList(width, height int) string {
+	if m.loading && len(m.findings) == 0 {
+		return mutedStyle.Render("Collecting findings...")
+	}
+	if len(m.visible) == 0 {
+		if len(m.findings) == 0 {
+			return severityStyles[output.SeverityInfo].Render("No issues found")
+		}
+		return mutedStyle.Render("No findings of this kind")
+	}
+
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+
+	var lines []string
+	for i := m.offset; i < len(m.visible) && i < m.offset+height; i++ {
+		finding := m.visible[i]
+		marker := " "
+		if _, busy := m.pending[finding.Fingerprint]; busy {
+			marker = "…"
+		} else if m.analyses[finding.Fingerprint] != "" {
+			marker = "✓"
+		}
+
+		// The label is cut before styling, so escape codes are never split
+		label := fmt.Sprintf("%s %s/%s %s", finding.Kind, finding.Namespace, finding.Name, finding.Reason)
+		label = truncate(label, width-7)
+		line := fmt.Sprintf("%s %s %s", marker, severityBadge(finding.Severity), label)
+		if i == m.cursor {
+			line = selectedStyle.Render(fmt.Sprintf("%s %-4s %-*s", marker, badgeText(finding.Severity), width-7, label))
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// refreshDetail re-renders the detail pane for the selected finding. The
+// scroll position is kept unless top is set, such as on a new selection.
+func (m *model) refreshDetail(top bool) {
+	_, width, height := m.layout()
+	m.detail.Width, m.detail.Height = width, height
+	m.detail.SetContent(lipgloss.NewStyle().Width(width).Render(m.renderDetail()))
+	if top {
+		m.detail.GotoTop()
+	}
+}
+
+// renderDetail renders the content of the detail pane
+func (m *model) renderDetail() string {
+	if m.showHelp {
+		return renderHelp()
+	}
+
+	finding, ok := m.selected()
+	if !ok {
+		if len(m.errs) > 0 {
+			return renderErrors(m.errs)
+		}
+		return mutedStyle.Render("Select a finding to see its details. Press ? for help.")
+	}
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(finding.Object()) + "\n")
+	fmt.Fprintf(&b, "%s  %s\n", severityBadge(finding.Severity), finding.Reason)
+	if finding.Message != "" {
+		fmt.Fprintf(&b, "%s\n", finding.Message)
+	}
+	if len(finding.Labels) > 0 {
+		fmt.Fprintf(&b, "%s %s\n", mutedStyle.Render("Labels:"), formatLabels(finding.Labels))
+	}
+
+	if len(finding.Containers) > 0 {
+		section(&b, "Containers")
+		for _, container := range finding.Containers {
+			ready := "not ready"
+			if container.Ready {
+				ready = "ready"
+			}
+			fmt.Fprintf(&b, "• %s: %s, %s, %d restarts\n", container.Name, container.Status, ready, container.Restarts)
+			if container.Message != "" {
+				fmt.Fprintf(&b, "  %s\n", mutedStyle.Render(container.Message))
+			}
+		}
+	}
+	if finding.Replicas != nil {
+		section(&b, "Replicas")
+		fmt.Fprintf(&b, "%d/%d ready, %d updated, %d available\n",
+			finding.Replicas.Ready, finding.Replicas.Desired, finding.Replicas.Updated, finding.Replicas.Available)
+	}
+
+	state := m.details[finding.Fingerprint]
+	switch {
+	case state == nil || state.loading:
+		section(&b, "Events")
+		b.WriteString(mutedStyle.Render("Loading...") + "\n")
+	case state.err != nil:
+		section(&b, "Events")
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error: %v", state.err)) + "\n")
+	default:
+		section(&b, "Events")
+		if len(state.details.Events) == 0 {
+			b.WriteString(mutedStyle.Render("No events") + "\n")
+		}
+		for _, event := range state.details.Events {
+			b.WriteString(event + "\n")
+		}
+
+		containers := make([]string, 0, len(state.details.Logs))
+		for container := range state.details.Logs {
+			containers = append(containers, container)
+		}
+		sort.Strings(containers)
+		for _, container := range containers {
+			section(&b, "Logs: "+container)
+			logs := strings.TrimRight(state.details.Logs[container], "\n")
+			if logs == "" {
+				logs = mutedStyle.Render("No logs")
+			}
+			b.WriteString(logs + "\n")
+		}
+	}
+
+	section(&b, "AI Analysis")
+	switch {
+	case m.pending[finding.Fingerprint] == "analysis":
+		b.WriteString(mutedStyle.Render("Analyzing...") + "\n")
+	case m.analyses[finding.Fingerprint] != "":
+		b.WriteString(strings.TrimSpace(m.analyses[finding.Fingerprint]) + "\n")
+	default:
+		b.WriteString(mutedStyle.Render("Press a to analyze this finding") + "\n")
+	}
+
+	if finding.Kind == "Pod" || finding.Kind == "Deployment" {
+		section(&b, "Suggested Fix")
+		switch {
+		case m.pending[finding.Fingerprint] == "fix":
+			b.WriteString(mutedStyle.Render("Generating fix...") + "\n")
+		case m.fixes[finding.Fingerprint] != "":
+			b.WriteString(strings.TrimSpace(m.fixes[finding.Fingerprint]) + "\n")
+		default:
+			b.WriteString(mutedStyle.Render("Press f to generate a fix") + "\n")
+		}
+	}
+
+	if err := m.aiErrors[finding.Fingerprint]; err != nil {
+		b.WriteString("\n" + errorStyle.Render(fmt.Sprintf("Error: %v", err)) + "\n")
+	}
+	return b.String()
+}
+
+// section writes a section heading
+func section(b *strings.Builder, title string) {
+	b.WriteString("\n" + headingStyle.Render(title) + "\n")
+}
+
+// renderHelp renders the keybindings
+func renderHelp() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Keybindings") + "\n\n")
+	for _, binding := range keyHelp {
+		fmt.Fprintf(&b, "%-10s %s\n", binding[0], binding[1])
+	}
+	return b.String()
+}
+
+// renderErrors renders the collector errors of a run
+func renderErrors(errs []error) string {
+	var b strings.Builder
+	b.WriteString(errorStyle.Render("Errors during diagnosis") + "\n")
+	for _, err := range errs {
+		fmt.Fprintf(&b, "• %v\n", err)
+	}
+	return b.String()
+}
+
+// severityBadge renders the short colored label of a severity
+func severityBadge(severity output.Severity) string {
+	style, ok := severityStyles[severity]
+	if !ok {
+		style = mutedStyle
+	}
+	return style.Render(fmt.Sprintf("%-4s", badgeText(severity)))
+}
+
+// badgeText is the short label of a severity
+func badgeText(severity output.Severity) string {
+	switch severity {
+	case output.SeverityCritical:
+		return "CRIT"
+	case output.SeverityWarning:
+		return "WARN"
+	case output.SeverityInfo:
+		return "INFO"
+	}
+	return "?"
+}
+
+// formatLabels formats labels as sorted key=value pairs
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// truncate shortens text to at most limit characters
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if limit <= 0 {
+		return ""
+	}
+	if len(runes) <= limit {
+		return text
+	}
+	if limit == 1 {
+		return "…"
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,187 @@
+package watch
+
+import (
+	"sort"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+// TransitionType describes how a finding changed between two polls
+type TransitionType string
+
+const (
+	// Opened means a finding has been observed for at least the debounce period
+	Opened TransitionType = "opened"
+	// Changed means an open finding now reports a different reason or
+	// severity. Messages are left out, since they carry restart counts and
+	// back-off durations that change on every poll.
+	Changed TransitionType = "changed"
+	// Resolved means an open finding has been absent for at least the debounce period
+	Resolved TransitionType = "resolved"
+)
+
+// Transition is a state change of a single finding
+type Transition struct {
+	Type     TransitionType
+	Finding  output.Finding
+	Previous output.Finding
+	At       time.Time
+}
+
+// Options configures a Tracker
+type Options struct {
+	// Debounce is how long a finding must be continuously present before it is
+	// reported as opened, and continuously absent before it is reported as
+	// resolved. It keeps flapping resources from producing a stream of
+	// open/resolve pairs.
+	Debounce time.Duration
+}
+
+// tracked is the tracker's view of a single finding
+type tracked struct {
+	finding   output.Finding
+	firstSeen time.Time
+	lastSeen  time.Time
+	open      bool
+}
+
+// Tracker keeps the state of findings across polls and turns successive
+// snapshots into transitions
+type Tracker struct {
+	options  Options
+	findings map[string]*tracked
+	analyzed map[string]bool
+	analyses map[string]string
+}
+
+// NewTracker creates a new tracker
+func NewTracker(options Options) *Tracker {
+	return &Tracker{
+		options:  options,
+		findings: make(map[string]*tracked),
+		analyzed: make(map[string]bool),
+		analyses: make(map[string]string),
+	}
+}
+
+// Update records a new snapshot of findings and returns the transitions it
+// caused, ordered by object. checked holds the finding kinds whose collectors
+// ran, as returned by output.DiagnosticResults.CheckedKinds; tracked findings
+// of other kinds are left as they are, since their absence from the snapshot
+// says nothing about them. A nil checked means every kind was checked.
+func (t *Tracker) Update(findings []output.Finding, checked map[string]bool, now time.Time) []Transition {
+	var transitions []Transition
+
+	seen := make(map[string]bool)
+	for _, finding := range findings {
+		fingerprint := finding.Fingerprint
+		seen[fingerprint] = true
+
+		state, ok := t.findings[fingerprint]
+		if !ok {
+			state = &tracked{finding: finding, firstSeen: now}
+			t.findings[fingerprint] = state
+		}
+		state.lastSeen = now
+
+		if state.open {
+			if state.finding.Reason != finding.Reason || state.finding.Severity != finding.Severity {
+				transitions = append(transitions, Transition{Type: Changed, Finding: finding, Previous: state.finding, At: now})
+			}
+		} else if now.Sub(state.firstSeen) >= t.options.Debounce {
+			state.open = true
+			transitions = append(transitions, Transition{Type: Opened, Finding: finding, At: now})
+		}
+		state.finding = finding
+	}
+
+	for fingerprint, state := range t.findings {
+		if seen[fingerprint] || (checked != nil && !checked[state.finding.Kind]) {
+			continue
+		}
+
+		if !state.open {
+			// Never reported, so it can disappear silently
+			delete(t.findings, fingerprint)
+			continue
+		}
+
+		if now.Sub(state.lastSeen) >= t.options.Debounce {
+			transitions = append(transitions, Transition{Type: Resolved, Finding: state.finding, At: now})
+			delete(t.findings, fingerprint)
+		}
+	}
+
+	sort.SliceStable(transitions, func(i, j int) bool {
+		return transitions[i].Finding.Object() < transitions[j].Finding.Object()
+	})
+
+	return transitions
+}
+
+// PendingAnalysis returns the open findings that have not been analyzed yet.
+// A finding is analyzed at most once for the lifetime of the tracker, even if
+// it resolves and reopens later.
+func (t *Tracker) PendingAnalysis() []output.Finding {
+	var pending []output.Finding
+	for fingerprint, state := range t.findings {
+		if state.open && !t.analyzed[fingerprint] {
+			pending = append(pending, state.finding)
+		}
+	}
+
+	// Oldest incidents first so a backlog drains in a predictable order
+	sort.Slice(pending, func(i, j int) bool {
+		a := t.findings[pending[i].Fingerprint]
+		b := t.findings[pending[j].Fingerprint]
+		if !a.firstSeen.Equal(b.firstSeen) {
+			return a.firstSeen.Before(b.firstSeen)
+		}
+		return pending[i].Object() < pending[j].Object()
+	})
+
+	return pending
+}
+
+// MarkAnalyzed records that a finding has been analyzed
+func (t *Tracker) MarkAnalyzed(finding output.Finding) {
+	t.analyzed[finding.Fingerprint] = true
+}
+
+// SetAnalysis records the analysis of a finding, which Open attaches to it
+// for as long as the tracker lives
+func (t *Tracker) SetAnalysis(finding output.Finding, analysis string) {
+	t.analyses[finding.Fingerprint] = analysis
+}
+
+// Open returns the findings currently reported as open, with their analysis
+// if one was recorded, ordered by object
+func (t *Tracker) Open() []output.Finding {
+	var open []output.Finding
+	for fingerprint, state := range t.findings {
+		if state.open {
+			finding := state.finding
+			if analysis, ok := t.analyses[fingerprint]; ok {
+				finding.Analysis = analysis
+			}
+			open = append(open, finding)
+		}
+	}
+
+	sort.Slice(open, func(i, j int) bool {
+		return open[i].Object() < open[j].Object()
+	})
+	return open
+}
+
+// OpenCount returns the number of findings currently reported as open
+func (t *Tracker) OpenCount() int {
+	count := 0
+	for _, state := range t.findings {
+		if state.open {
+			count++
+		}
+	}
+	return count
+}
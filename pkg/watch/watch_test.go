@@ -0,0 +1,155 @@
+package watch
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/junioroyewunmi/kubegpt/pkg/output"
+)
+
+func finding(kind, name, reason string) output.Finding {
+	return output.Finding{
+		Fingerprint: kind + "/" + name,
+		Kind:        kind,
+		Namespace:   "default",
+		Name:        name,
+		Reason:      reason,
+	}
+}
+
+// poll is one snapshot fed to the tracker, at an offset from the first one
+type poll struct {
+	at       time.Duration
+	findings []output.Finding
+	checked  map[string]bool
+	want     []string
+}
+
+func TestTrackerUpdate(t *testing.T) {
+	crashing := finding("Pod", "web", "CrashLoopBackOff")
+	oom := finding("Pod", "web", "OOMKilled")
+	restarted := crashing
+	restarted.Message = "back-off 5m0s restarting failed container"
+	critical := crashing
+	critical.Severity = output.SeverityCritical
+	deployment := finding("Deployment", "api", "ReplicasUnavailable")
+	podsOnly := map[string]bool{"Pod": true}
+
+	tests := []struct {
+		name  string
+		polls []poll
+	}{
+		{
+			name: "opens once the finding outlasts the debounce",
+			polls: []poll{
+				{at: 0, findings: []output.Finding{crashing}},
+				{at: 30 * time.Second, findings: []output.Finding{crashing}},
+				{at: time.Minute, findings: []output.Finding{crashing}, want: []string{"opened Pod default/web"}},
+				{at: 90 * time.Second, findings: []output.Finding{crashing}},
+			},
+		},
+		{
+			name: "a flapping finding never opens",
+			polls: []poll{
+				{at: 0, findings: []output.Finding{crashing}},
+				{at: 30 * time.Second},
+				{at: time.Minute, findings: []output.Finding{crashing}},
+				{at: 90 * time.Second},
+			},
+		},
+		{
+			name: "reports a changed reason of an open finding",
+			polls: []poll{
+				{at: 0, findings: []output.Finding{crashing}},
+				{at: time.Minute, findings: []output.Finding{crashing}, want: []string{"opened Pod default/web"}},
+				{at: 2 * time.Minute, findings: []output.Finding{oom}, want: []string{"changed Pod default/web"}},
+			},
+		},
+		{
+			name: "reports a changed severity of an open finding",
+			polls: []poll{
+				{at: 0, findings: []output.Finding{crashing}},
+				{at: time.Minute, findings: []output.Finding{crashing}, want: []string{"opened Pod default/web"}},
+				{at: 2 * time.Minute, findings: []output.Finding{critical}, want: []string{"changed Pod default/web"}},
+			},
+		},
+		{
+			name: "ignores a changed message",
+			polls: []poll{
+				{at: 0, findings: []output.Finding{crashing}},
+				{at: time.Minute, findings: []output.Finding{crashing}, want: []string{"opened Pod default/web"}},
+				{at: 2 * time.Minute, findings: []output.Finding{restarted}},
+			},
+		},
+		{
+			name: "resolves once the finding is gone for the debounce",
+			polls: []poll{
+				{at: 0, findings: []output.Finding{crashing}},
+				{at: time.Minute, findings: []output.Finding{crashing}, want: []string{"opened Pod default/web"}},
+				{at: 90 * time.Second},
+				{at: 2 * time.Minute, want: []string{"resolved Pod default/web"}},
+				{at: 3 * time.Minute},
+			},
+		},
+		{
+			name: "keeps findings of kinds that were not checked",
+			polls: []poll{
+				{at: 0, findings: []output.Finding{crashing, deployment}},
+				{at: time.Minute, findings: []output.Finding{crashing, deployment}, want: []string{"opened Deployment default/api", "opened Pod default/web"}},
+				{at: 3 * time.Minute, checked: podsOnly, want: []string{"resolved Pod default/web"}},
+				{at: 4 * time.Minute, checked: podsOnly},
+				{at: 5 * time.Minute, want: []string{"resolved Deployment default/api"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker(Options{Debounce: time.Minute})
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			for i, p := range tt.polls {
+				var got []string
+				for _, transition := range tracker.Update(p.findings, p.checked, start.Add(p.at)) {
+					got = append(got, fmt.Sprintf("%s %s", transition.Type, transition.Finding.Object()))
+				}
+				if !reflect.DeepEqual(got, p.want) {
+					t.Errorf("poll %d: transitions = %q, want %q", i+1, got, p.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTrackerPendingAnalysis(t *testing.T) {
+	tracker := NewTracker(Options{})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	web := finding("Pod", "web", "CrashLoopBackOff")
+	api := finding("Deployment", "api", "ReplicasUnavailable")
+
+	tracker.Update([]output.Finding{web}, nil, start)
+	tracker.Update([]output.Finding{web, api}, nil, start.Add(time.Minute))
+
+	pending := tracker.PendingAnalysis()
+	if len(pending) != 2 || pending[0].Name != "web" || pending[1].Name != "api" {
+		t.Fatalf("PendingAnalysis() = %v, want web then api", pending)
+	}
+
+	// A finding is analyzed once, even if it resolves and reopens
+	tracker.MarkAnalyzed(web)
+	tracker.SetAnalysis(web, "restarts on a missing config map")
+	tracker.Update([]output.Finding{api}, nil, start.Add(2*time.Minute))
+	tracker.Update([]output.Finding{web, api}, nil, start.Add(3*time.Minute))
+
+	pending = tracker.PendingAnalysis()
+	if len(pending) != 1 || pending[0].Name != "api" {
+		t.Errorf("PendingAnalysis() after MarkAnalyzed = %v, want api", pending)
+	}
+	for _, open := range tracker.Open() {
+		if open.Name == "web" && open.Analysis != "restarts on a missing config map" {
+			t.Errorf("Open() analysis of web = %q, want the recorded analysis", open.Analysis)
+		}
+	}
+}